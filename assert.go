@@ -0,0 +1,64 @@
+package errhandling
+
+import (
+	"fmt"
+	"runtime"
+
+	errstack "github.com/the-zucc/errhandling/err-stack"
+)
+
+// CODE_ASSERTION_FAILED is the code of the errors thrown by Assert() and DebugAssert().
+var CODE_ASSERTION_FAILED = errstack.RegisterCode("ASSERTION_FAILED")
+
+/*
+Assert() throws an error up the call stack if the condition is false,
+for the preconditions and invariants that would otherwise panic. The
+error has the CODE_ASSERTION_FAILED code, the message formatted from msg
+and args, and the call site of the assertion as its "caller" field. It
+needs to be paired with a deferred call to Catch(), like Throw().
+
+Example:
+
+	Assert(len(batch) <= maxBatch, "batch of %d items over the limit", len(batch))
+
+errlint:throws
+*/
+func Assert(cond bool, msg string, args ...any) {
+	if !cond {
+		assertionFailed(msg, args)
+	}
+}
+
+/*
+DebugAssert() behaves like Assert() in development, and does nothing in
+the programs built with the errhandling_nodebug build tag, for the
+checks too costly for production:
+
+	go build -tags errhandling_nodebug ./...
+
+Its arguments are still evaluated, so expensive conditions are better
+guarded with DebugAsserts:
+
+	if DebugAsserts {
+		DebugAssert(sort.IsSorted(index), "the index is not sorted")
+	}
+
+errlint:throws
+*/
+func DebugAssert(cond bool, msg string, args ...any) {
+	if DebugAsserts && !cond {
+		assertionFailed(msg, args)
+	}
+}
+
+// this throws the error of a failed assertion, from Assert() or DebugAssert(). errlint:throws
+func assertionFailed(msg string, args []any) {
+	if len(args) > 0 {
+		msg = fmt.Sprintf(msg, args...)
+	}
+	err := errstack.NewCode(CODE_ASSERTION_FAILED, "assertion failed: "+msg)
+	if _, file, line, ok := runtime.Caller(2); ok {
+		err = err.With("caller", fmt.Sprintf("%s:%d", file, line))
+	}
+	Throw_(err)
+}
@@ -0,0 +1,238 @@
+package errhandling
+
+import (
+	"fmt"
+	"sync"
+	"time"
+)
+
+// BreakerState is the state of a circuit Breaker.
+type BreakerState int
+
+const (
+	// Closed lets every call through.
+	Closed BreakerState = iota
+	// Open rejects every call until the cooldown has elapsed.
+	Open
+	// HalfOpen lets a single trial call through to probe recovery.
+	HalfOpen
+)
+
+func (s BreakerState) String() string {
+	switch s {
+	case Closed:
+		return "closed"
+	case Open:
+		return "open"
+	case HalfOpen:
+		return "half-open"
+	}
+	return fmt.Sprintf("BreakerState(%d)", int(s))
+}
+
+/*
+BreakerConfig configures a Breaker. The breaker opens when, among the
+last Window calls (10 if unset), the rate of failures reaches
+FailureRate (0.5 if unset). It stays open for Cooldown (30 seconds if
+unset) before letting a trial call through. OnStateChange, if set, is
+called on every state transition, e.g. to update metrics. It is called
+once the breaker is unlocked, so it may call the breaker's methods.
+*/
+type BreakerConfig struct {
+	Window        int
+	FailureRate   float64
+	Cooldown      time.Duration
+	OnStateChange func(from, to BreakerState)
+}
+
+/*
+ErrCircuitOpen is thrown by Execute() and Execute_() while the breaker
+is open. It carries the last failure seen by the breaker.
+*/
+type ErrCircuitOpen struct {
+	LastCause error
+}
+
+func (e ErrCircuitOpen) Error() string {
+	if e.LastCause == nil {
+		return "circuit breaker is open"
+	}
+	return fmt.Sprintf("circuit breaker is open: %s", e.LastCause)
+}
+
+func (e ErrCircuitOpen) Unwrap() error {
+	return e.LastCause
+}
+
+/*
+Breaker is a circuit breaker for calls that may fail with Throw() or
+Return(). Once too many calls have failed, it rejects further calls
+with an ErrCircuitOpen instead of running them.
+*/
+type Breaker struct {
+	cfg       BreakerConfig
+	mu        sync.Mutex
+	state     BreakerState
+	results   []bool // ring buffer of the last calls, true for failures
+	next      int
+	openedAt  time.Time
+	trial     bool // whether the half-open trial call is running
+	lastCause error
+	changes   [][2]BreakerState // the transitions to report once unlocked
+}
+
+// NewBreaker() returns a closed Breaker with the provided configuration.
+func NewBreaker(cfg BreakerConfig) *Breaker {
+	if cfg.Window < 1 {
+		cfg.Window = 10
+	}
+	if cfg.FailureRate <= 0 {
+		cfg.FailureRate = 0.5
+	}
+	if cfg.Cooldown <= 0 {
+		cfg.Cooldown = 30 * time.Second
+	}
+	return &Breaker{cfg: cfg}
+}
+
+// State() returns the current state of the breaker.
+func (b *Breaker) State() BreakerState {
+	b.mu.Lock()
+	defer b.unlock()
+	b.cool()
+	return b.state
+}
+
+/*
+Execute() runs the provided function through the breaker and returns
+its value. Its failures, and the rejection of the call when the breaker
+is open, are thrown up the call stack, so Execute() needs to be paired
+with a deferred call to Catch().
+
+Example:
+
+	var payments = NewBreaker(BreakerConfig{Cooldown: 10 * time.Second})
+
+	func Charge(order Order) (r Receipt, e error) {
+		defer Catch(&r, &e)
+		return Execute(payments, func() Receipt {
+			return Throw(gateway.Charge(order))
+		}), nil
+	}
+
+errlint:throws
+*/
+func Execute[T any](b *Breaker, f func() T) T {
+	if err := b.allow(); err != nil {
+		var zero T
+		return Throw(zero, err)
+	}
+	val, err := func() (val T, e error) {
+		defer CatchPanic(&val, &e)
+		return f(), nil
+	}()
+	b.record(err)
+	return Throw(val, err)
+}
+
+/*
+Execute_() behaves like Execute(), for functions that do not return a
+value.
+
+errlint:throws
+*/
+func Execute_(b *Breaker, f func()) {
+	Execute(b, func() struct{} {
+		f()
+		return struct{}{}
+	})
+}
+
+// allow() returns an ErrCircuitOpen if the call must be rejected.
+func (b *Breaker) allow() error {
+	b.mu.Lock()
+	defer b.unlock()
+	b.cool()
+	switch b.state {
+	case Open:
+		return ErrCircuitOpen{LastCause: b.lastCause}
+	case HalfOpen:
+		if b.trial {
+			return ErrCircuitOpen{LastCause: b.lastCause}
+		}
+		b.trial = true
+	}
+	return nil
+}
+
+// record() records the outcome of a call, and updates the state.
+func (b *Breaker) record(err error) {
+	b.mu.Lock()
+	defer b.unlock()
+	if err != nil {
+		b.lastCause = err
+	}
+	if b.state == HalfOpen {
+		b.trial = false
+		if err != nil {
+			b.open()
+		} else {
+			b.results = nil
+			b.setState(Closed)
+		}
+		return
+	}
+	if len(b.results) < b.cfg.Window {
+		b.results = append(b.results, err != nil)
+	} else {
+		b.results[b.next] = err != nil
+		b.next = (b.next + 1) % b.cfg.Window
+	}
+	if len(b.results) < b.cfg.Window {
+		return
+	}
+	failures := 0
+	for _, failed := range b.results {
+		if failed {
+			failures++
+		}
+	}
+	if float64(failures)/float64(len(b.results)) >= b.cfg.FailureRate {
+		b.open()
+	}
+}
+
+// open() opens the breaker and resets the window.
+func (b *Breaker) open() {
+	b.results = nil
+	b.next = 0
+	b.openedAt = time.Now()
+	b.setState(Open)
+}
+
+// cool() half-opens the breaker once the cooldown has elapsed.
+func (b *Breaker) cool() {
+	if b.state == Open && time.Since(b.openedAt) >= b.cfg.Cooldown {
+		b.setState(HalfOpen)
+	}
+}
+
+func (b *Breaker) setState(state BreakerState) {
+	if state == b.state {
+		return
+	}
+	if b.cfg.OnStateChange != nil {
+		b.changes = append(b.changes, [2]BreakerState{b.state, state})
+	}
+	b.state = state
+}
+
+// unlock() unlocks the breaker, and then reports its state transitions.
+func (b *Breaker) unlock() {
+	changes := b.changes
+	b.changes = nil
+	b.mu.Unlock()
+	for _, change := range changes {
+		b.cfg.OnStateChange(change[0], change[1])
+	}
+}
@@ -0,0 +1,30 @@
+package errhandling
+
+/*
+CaptureThrow() runs the function, and returns the value and the error
+it throws with Throw(), Return() or the like, sparing tests from
+writing an enclosing function with named returns and a deferred Catch().
+The value is nil if the function only threw an error, and both are nil
+if it did not throw. Other panics are not caught.
+
+Example:
+
+	val, err := CaptureThrow(func() {
+		Return("partial", errors.New("timeout"))
+	})
+	// val == "partial", err.Error() == "timeout"
+*/
+func CaptureThrow(f func()) (val any, err error) {
+	defer func() {
+		if panicInfo := recover(); panicInfo != nil {
+			t, ok := panicInfo.(thrown)
+			if !ok {
+				panic(panicInfo)
+			}
+			val, err = t.thrownVal(), t.thrownErr()
+			fireCatch(err)
+		}
+	}()
+	f()
+	return nil, nil
+}
@@ -0,0 +1,113 @@
+package errhandling
+
+/*
+Handler propagates errors without panicking, for the hot paths where the
+cost of Throw() and Catch() is measurable (see Throw()). Errors are
+passed to Check(), which records the first one and reports whether the
+function should carry on; the function is then expected to return on its
+own. Handlers are created by WithHandler() and WithHandler_(), which return the
+recorded error the way Catch() would. A call to Check() costs a few
+nanoseconds, against hundreds for a Throw() caught by Catch().
+*/
+type Handler struct {
+	err error
+}
+
+/*
+Check() records the error, if it is the first one passed to the handler,
+and returns true if the function can carry on, i.e. if no error was
+passed to the handler so far. The error goes through the OnThrow() hooks,
+as with Throw().
+
+Example:
+
+	for _, line := range lines {
+		if !h.Check(process(line)) {
+			return
+		}
+	}
+*/
+func (h *Handler) Check(err error) bool {
+	if h.err != nil {
+		return false
+	}
+	if err != nil {
+		fireThrow(err)
+		h.err = err
+		return false
+	}
+	return true
+}
+
+// Err() returns the first error passed to the handler, if any.
+func (h *Handler) Err() error {
+	return h.err
+}
+
+/*
+Check() is the value-returning counterpart of Handler.Check(): it takes
+the results of a function call, and returns a function that passes the
+error to the handler, returning the value and whether the function can
+carry on.
+
+Example:
+
+	n, ok := Check(strconv.Atoi(line))(h)
+	if !ok {
+		return 0
+	}
+*/
+func Check[T any](val T, err error) func(h *Handler) (T, bool) {
+	return func(h *Handler) (T, bool) {
+		return val, h.Check(err)
+	}
+}
+
+/*
+WithHandler() and WithHandler_() run the provided function with a Handler, and
+return the first error passed to it with the semantics of Catch(): the
+error goes through the middleware registered with Use() and through the
+OnCatch() hooks. Errors thrown with Throw() inside the function are
+caught as well, so that both styles can be mixed.
+
+Example:
+
+	sum, err := WithHandler(func(h *Handler) int {
+		sum := 0
+		for _, line := range lines {
+			n, ok := Check(strconv.Atoi(line))(h)
+			if !ok {
+				return 0
+			}
+			sum += n
+		}
+		return sum
+	})
+*/
+func WithHandler[T any](f func(h *Handler) T) (val T, err error) {
+	defer Catch(&val, &err)
+	h := &Handler{}
+	val = f(h)
+	return val, handled(h)
+}
+
+/*
+WithHandler() and WithHandler_() run the provided function with a Handler, and
+return the first error passed to it with the semantics of Catch().
+*/
+func WithHandler_(f func(h *Handler)) (err error) {
+	defer Catch_(&err)
+	h := &Handler{}
+	f(h)
+	return handled(h)
+}
+
+// this returns the error recorded by a handler, as Catch() would
+func handled(h *Handler) error {
+	if h.err == nil {
+		return nil
+	}
+	err := transform(h.err, nil)
+	fireCatch(err)
+	return err
+}
@@ -0,0 +1,82 @@
+package errhandling
+
+import (
+	"io"
+
+	errstack "github.com/the-zucc/errhandling/err-stack"
+)
+
+/*
+CloseThrow() closes the closer, and throws the error of Close() up the
+call stack, so it needs to be paired with a deferred call to Catch(),
+deferred before it. If an error is already being thrown, the error of
+Close() is joined to it as a secondary cause instead.
+
+Example:
+
+	func ReadConfig(path string) (c Config, e error) {
+		defer Catch(&c, &e)
+		f := Throw(os.Open(path))
+		defer CloseThrow(f)
+		return Throw(decode(f)), nil
+	}
+
+errlint:throws
+*/
+func CloseThrow(c io.Closer) {
+	panicInfo := recover()
+	err := closeErr(c)
+	if panicInfo != nil {
+		rethrow(panicInfo, err)
+	}
+	Throw_(err)
+}
+
+/*
+CloseAppend() closes the closer, and joins the error of Close() into
+the error pointed to by errAddr as a secondary cause, with AppendInto().
+It is meant to be deferred with the address of the function's returned
+error. If an error is being thrown, the error of Close() is joined to
+it instead, so it is not lost when Catch() sets the returned error.
+
+Example:
+
+	func WriteReport(path string, r Report) (e error) {
+		f, err := os.Create(path)
+		if err != nil {
+			return err
+		}
+		defer CloseAppend(&e, f)
+		return r.Render(f)
+	}
+*/
+func CloseAppend(errAddr *error, c io.Closer) {
+	if errAddr == nil {
+		panic(ERROR_IN_CATCH)
+	}
+	panicInfo := recover()
+	err := closeErr(c)
+	if panicInfo != nil {
+		rethrow(panicInfo, err)
+	}
+	AppendInto(errAddr, err)
+}
+
+// closeErr() closes the closer, and wraps the error of Close() if any.
+func closeErr(c io.Closer) error {
+	if err := c.Close(); err != nil {
+		return errstack.New("close failed", err)
+	}
+	return nil
+}
+
+/*
+rethrow() panics again on a recovered value, joining the error to the
+one being thrown if any. Other panics go on unchanged.
+*/
+func rethrow(panicInfo any, err error) {
+	if t, ok := panicInfo.(thrown); ok && err != nil {
+		panic(t.withErr(errstack.Join(t.thrownErr(), err)))
+	}
+	panic(panicInfo)
+}
@@ -0,0 +1,117 @@
+/*
+errhandling-migrate rewrites Go packages to the Throw/Catch style of the
+errhandling package. In the functions returning an error (alone or after
+a single value), the checks that only pass the error up:
+
+	user, err := db.FindUser(id)
+	if err != nil {
+		return nil, err
+	}
+
+are replaced with Throw():
+
+	user := errhandling.Throw(db.FindUser(id))
+
+and the function gets named return values and a deferred Catch(). The
+checks doing anything else with the error (wrapping it, logging it,
+returning a value along with it) are left untouched.
+
+Usage:
+
+	errhandling-migrate [-w] [-l] path ...
+
+The paths are files or directories; a directory ending with "/..." is
+rewritten recursively. The rewritten files are printed unless -w or -l
+is given. Test files and generated files are left out.
+*/
+package main
+
+import (
+	"bytes"
+	"flag"
+	"fmt"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"strings"
+)
+
+var (
+	write = flag.Bool("w", false, "write the result to the files instead of printing it")
+	list  = flag.Bool("l", false, "list the files that would be rewritten")
+)
+
+func main() {
+	flag.Usage = func() {
+		fmt.Fprintln(os.Stderr, "usage: errhandling-migrate [-w] [-l] path ...")
+		flag.PrintDefaults()
+	}
+	flag.Parse()
+	if flag.NArg() == 0 {
+		flag.Usage()
+		os.Exit(2)
+	}
+	failed := false
+	for _, path := range flag.Args() {
+		if err := walk(path); err != nil {
+			fmt.Fprintln(os.Stderr, err)
+			failed = true
+		}
+	}
+	if failed {
+		os.Exit(1)
+	}
+}
+
+// walk() migrates the file, or the Go files of the directory.
+func walk(path string) error {
+	recursive := strings.HasSuffix(path, "/...")
+	root := strings.TrimSuffix(path, "/...")
+	info, err := os.Stat(root)
+	if err != nil {
+		return err
+	}
+	if !info.IsDir() {
+		return migrateFile(root)
+	}
+	return filepath.WalkDir(root, func(file string, d fs.DirEntry, err error) error {
+		if err != nil {
+			return err
+		}
+		if d.IsDir() {
+			if file != root && (!recursive || d.Name() == "testdata" || strings.HasPrefix(d.Name(), ".")) {
+				return filepath.SkipDir
+			}
+			return nil
+		}
+		if !strings.HasSuffix(file, ".go") || strings.HasSuffix(file, "_test.go") {
+			return nil
+		}
+		return migrateFile(file)
+	})
+}
+
+// migrateFile() migrates a file, and prints, lists or writes the result.
+func migrateFile(file string) error {
+	src, err := os.ReadFile(file)
+	if err != nil {
+		return err
+	}
+	out, err := Migrate(file, src)
+	if err != nil {
+		return err
+	}
+	if bytes.Equal(src, out) {
+		return nil
+	}
+	if *list {
+		fmt.Println(file)
+	}
+	if *write {
+		return os.WriteFile(file, out, 0o644)
+	}
+	if !*list {
+		fmt.Printf("// %s\n%s", file, out)
+	}
+	return nil
+}
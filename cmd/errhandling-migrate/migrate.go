@@ -0,0 +1,652 @@
+package main
+
+import (
+	"bytes"
+	"go/ast"
+	"go/format"
+	"go/parser"
+	"go/token"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+const importPath = "github.com/the-zucc/errhandling"
+
+/*
+Migrate() rewrites the source of a Go file to the Throw/Catch style, and
+returns it formatted. Files left unchanged are returned as they are.
+*/
+func Migrate(filename string, src []byte) ([]byte, error) {
+	fset := token.NewFileSet()
+	file, err := parser.ParseFile(fset, filename, src, parser.ParseComments)
+	if err != nil {
+		return nil, err
+	}
+	if ast.IsGenerated(file) {
+		return src, nil
+	}
+	m := &migration{pkg: importName(file)}
+	if m.pkg == "" {
+		m.pkg = "errhandling"
+	}
+	changed := false
+	for _, decl := range file.Decls {
+		if fd, ok := decl.(*ast.FuncDecl); ok && fd.Body != nil {
+			changed = m.migrateFunc(fd) || changed
+		}
+	}
+	if !changed {
+		return src, nil
+	}
+	if importName(file) == "" {
+		addImport(file)
+	}
+	file.Comments = m.keptComments(file.Comments)
+	m.mergeLines(fset.File(file.Pos()))
+	buf := &bytes.Buffer{}
+	if err := format.Node(buf, fset, file); err != nil {
+		return nil, err
+	}
+	return buf.Bytes(), nil
+}
+
+// migration holds the state of the rewriting of a file.
+type migration struct {
+	// the name the errhandling package is imported as, "." if dot-imported
+	pkg string
+	// the statements removed, whose lines and comments are dropped
+	removed []ast.Node
+}
+
+// scope holds the names declared so far in a block.
+type scope map[string]bool
+
+/*
+migrateFunc() rewrites the propagation-only error checks of a function
+returning an error, alone or after a single value, and names its results
+and defers Catch() if any was rewritten.
+*/
+func (m *migration) migrateFunc(fd *ast.FuncDecl) bool {
+	results := resultNames(fd.Type.Results)
+	if results == nil {
+		return false
+	}
+	for _, name := range results {
+		if name == "_" {
+			return false
+		}
+	}
+	if defersRead(fd.Body, append([]string{"err"}, results...)) {
+		return false
+	}
+	params := scope{}
+	for _, field := range fd.Type.Params.List {
+		for _, name := range field.Names {
+			params[name.Name] = true
+		}
+	}
+	top := scope{}
+	for name := range params {
+		top[name] = true
+	}
+	for _, name := range results {
+		if name != "" {
+			top[name] = true
+		}
+	}
+	f := &function{migration: m, results: results}
+	body := f.rewrite(fd.Body.List, top)
+	if !f.changed {
+		return false
+	}
+	if results[len(results)-1] == "" {
+		results = nameResults(fd.Type.Results, params, identifiers(fd))
+		body = m.redeclare(body, params, results)
+	}
+	body = m.dropUnused(body)
+	if !defersCatch(body, m.pkg) {
+		body = append([]ast.Stmt{m.deferCatch(results)}, body...)
+	}
+	fd.Body.List = body
+	return true
+}
+
+// function holds the state of the rewriting of a function.
+type function struct {
+	*migration
+	// the names of the results, empty if unnamed
+	results []string
+	changed bool
+}
+
+/*
+rewrite() rewrites the propagation-only error checks of a list of
+statements, and of the blocks they contain.
+*/
+func (f *function) rewrite(stmts []ast.Stmt, declared scope) []ast.Stmt {
+	out := make([]ast.Stmt, 0, len(stmts))
+	for i := 0; i < len(stmts); i++ {
+		stmt := stmts[i]
+		if ifStmt, ok := stmt.(*ast.IfStmt); ok && ifStmt.Init != nil {
+			if assign, ok := ifStmt.Init.(*ast.AssignStmt); ok && len(assign.Lhs) == 1 {
+				if call, errName := checked(assign); call != nil && f.propagates(ifStmt, errName) {
+					f.changed = true
+					f.removed = append(f.removed, ifStmt)
+					out = append(out, &ast.ExprStmt{X: f.call("Throw_", call)})
+					continue
+				}
+			}
+		}
+		if assign, ok := stmt.(*ast.AssignStmt); ok && i+1 < len(stmts) {
+			ifStmt, _ := stmts[i+1].(*ast.IfStmt)
+			if call, errName := checked(assign); call != nil && ifStmt != nil && ifStmt.Init == nil && f.propagates(ifStmt, errName) && !usedLater(stmts[i+2:], errName) {
+				f.changed = true
+				f.removed = append(f.removed, ifStmt)
+				out = append(out, f.throw(assign, call, declared))
+				declare(assign, declared)
+				i++
+				continue
+			}
+		}
+		declare(stmt, declared)
+		f.descend(stmt)
+		out = append(out, stmt)
+	}
+	return out
+}
+
+// descend() rewrites the blocks contained by a statement.
+func (f *function) descend(stmt ast.Stmt) {
+	switch s := stmt.(type) {
+	case *ast.BlockStmt:
+		s.List = f.rewrite(s.List, scope{})
+	case *ast.LabeledStmt:
+		f.descend(s.Stmt)
+	case *ast.IfStmt:
+		s.Body.List = f.rewrite(s.Body.List, scope{})
+		if s.Else != nil {
+			f.descend(s.Else)
+		}
+	case *ast.ForStmt:
+		s.Body.List = f.rewrite(s.Body.List, scope{})
+	case *ast.RangeStmt:
+		s.Body.List = f.rewrite(s.Body.List, scope{})
+	case *ast.SwitchStmt:
+		f.descend(s.Body)
+	case *ast.TypeSwitchStmt:
+		f.descend(s.Body)
+	case *ast.SelectStmt:
+		f.descend(s.Body)
+	case *ast.CaseClause:
+		s.Body = f.rewrite(s.Body, scope{})
+	case *ast.CommClause:
+		s.Body = f.rewrite(s.Body, scope{})
+	}
+}
+
+/*
+checked() returns the call of an assignment of the form "err := f()" or
+"v, err := f()", and the name of the error variable.
+*/
+func checked(assign *ast.AssignStmt) (*ast.CallExpr, string) {
+	if len(assign.Rhs) != 1 || len(assign.Lhs) < 1 || len(assign.Lhs) > 2 {
+		return nil, ""
+	}
+	call, ok := assign.Rhs[0].(*ast.CallExpr)
+	if !ok {
+		return nil, ""
+	}
+	errIdent, ok := assign.Lhs[len(assign.Lhs)-1].(*ast.Ident)
+	if !ok || errIdent.Name == "_" {
+		return nil, ""
+	}
+	return call, errIdent.Name
+}
+
+/*
+propagates() reports whether the if statement only returns the error
+when it is not nil, along with zero values.
+*/
+func (f *function) propagates(ifStmt *ast.IfStmt, errName string) bool {
+	if ifStmt.Else != nil || len(ifStmt.Body.List) != 1 {
+		return false
+	}
+	cond, ok := ifStmt.Cond.(*ast.BinaryExpr)
+	if !ok || cond.Op != token.NEQ || !isIdent(cond.X, errName) || !isIdent(cond.Y, "nil") {
+		return false
+	}
+	ret, ok := ifStmt.Body.List[0].(*ast.ReturnStmt)
+	if !ok || len(ret.Results) != len(f.results) || !isIdent(ret.Results[len(ret.Results)-1], errName) {
+		return false
+	}
+	for i, result := range ret.Results[:len(ret.Results)-1] {
+		if !isZero(result) && (f.results[i] == "" || !isIdent(result, f.results[i])) {
+			return false
+		}
+	}
+	return true
+}
+
+/*
+throw() returns the statement replacing a checked assignment, passing
+the call to Throw() (or to Throw_() if only the error is assigned).
+*/
+func (f *function) throw(assign *ast.AssignStmt, call *ast.CallExpr, declared scope) ast.Stmt {
+	if len(assign.Lhs) == 1 {
+		return &ast.ExprStmt{X: f.call("Throw_", call)}
+	}
+	if isIdent(assign.Lhs[0], "_") {
+		return &ast.ExprStmt{X: f.call("Throw", call)}
+	}
+	tok := assign.Tok
+	if ident, ok := assign.Lhs[0].(*ast.Ident); ok && tok == token.DEFINE && declared[ident.Name] {
+		tok = token.ASSIGN
+	}
+	return &ast.AssignStmt{
+		Lhs:    assign.Lhs[:1],
+		TokPos: assign.TokPos,
+		Tok:    tok,
+		Rhs:    []ast.Expr{f.call("Throw", call)},
+	}
+}
+
+// call() returns the call of a function of the errhandling package.
+func (m *migration) call(name string, args ...ast.Expr) *ast.CallExpr {
+	return &ast.CallExpr{Fun: m.qualified(name, args[0].Pos()), Args: args}
+}
+
+// qualified() returns the name of a function of the errhandling package.
+func (m *migration) qualified(name string, pos token.Pos) ast.Expr {
+	if m.pkg == "." {
+		return &ast.Ident{Name: name, NamePos: pos}
+	}
+	return &ast.SelectorExpr{
+		X:   &ast.Ident{Name: m.pkg, NamePos: pos},
+		Sel: &ast.Ident{Name: name, NamePos: pos},
+	}
+}
+
+// deferCatch() returns the deferred Catch() of the named results.
+func (m *migration) deferCatch(results []string) ast.Stmt {
+	args := []ast.Expr{}
+	for _, name := range results {
+		args = append(args, &ast.UnaryExpr{Op: token.AND, X: ast.NewIdent(name)})
+	}
+	name := "Catch"
+	if len(results) == 1 {
+		name = "Catch_"
+	}
+	return &ast.DeferStmt{Call: &ast.CallExpr{Fun: m.qualified(name, token.NoPos), Args: args}}
+}
+
+// keptComments() leaves out the comments of the removed statements.
+func (m *migration) keptComments(comments []*ast.CommentGroup) []*ast.CommentGroup {
+	kept := comments[:0]
+	for _, group := range comments {
+		inside := false
+		for _, node := range m.removed {
+			if group.Pos() >= node.Pos() && group.End() <= node.End() {
+				inside = true
+				break
+			}
+		}
+		if !inside {
+			kept = append(kept, group)
+		}
+	}
+	return kept
+}
+
+/*
+mergeLines() merges the lines of the removed statements into the lines
+preceding them, so that they do not leave blank lines.
+*/
+func (m *migration) mergeLines(file *token.File) {
+	removed := append([]ast.Node(nil), m.removed...)
+	sort.Slice(removed, func(i, j int) bool {
+		return removed[i].Pos() > removed[j].Pos()
+	})
+	for _, node := range removed {
+		start, end := file.Line(node.Pos()), file.Line(node.End())
+		for line := start; line <= end; line++ {
+			file.MergeLine(start - 1)
+		}
+	}
+}
+
+/*
+resultNames() returns the names of the results of a function returning
+an error, alone or after a single value, with empty names if unnamed. It
+returns nil for the other functions.
+*/
+func resultNames(results *ast.FieldList) []string {
+	if results == nil {
+		return nil
+	}
+	names := []string{}
+	for _, field := range results.List {
+		if len(field.Names) == 0 {
+			names = append(names, "")
+		}
+		for _, name := range field.Names {
+			names = append(names, name.Name)
+		}
+	}
+	last := results.List[len(results.List)-1]
+	if len(names) > 2 || !isIdent(last.Type, "error") {
+		return nil
+	}
+	return names
+}
+
+/*
+nameResults() names the unnamed results and returns the names. The error
+is named "err", which the error variables of the body then reuse, unless
+a parameter has this name; the value is named "val", or another name
+that the function does not use.
+*/
+func nameResults(results *ast.FieldList, params scope, used map[string]bool) []string {
+	names := []string{}
+	for i, field := range results.List {
+		var name string
+		switch {
+		case i < len(results.List)-1:
+			name = freeName("val", used)
+		case params["err"]:
+			name = freeName("err", used)
+		default:
+			name = "err"
+		}
+		field.Names = []*ast.Ident{ast.NewIdent(name)}
+		names = append(names, name)
+	}
+	return names
+}
+
+// freeName() returns the base name, or a numbered one, that is unused.
+func freeName(base string, used map[string]bool) string {
+	name := base
+	for i := 1; used[name]; i++ {
+		name = base + strconv.Itoa(i)
+	}
+	used[name] = true
+	return name
+}
+
+/*
+redeclare() fixes the statements of the body of a function whose results
+were just named: the "var" declarations of the results are removed, and
+the ":=" assignments declaring no new variable turn into "=".
+*/
+func (m *migration) redeclare(body []ast.Stmt, params scope, results []string) []ast.Stmt {
+	declared := scope{}
+	for name := range params {
+		declared[name] = true
+	}
+	for _, name := range results {
+		declared[name] = true
+	}
+	out := make([]ast.Stmt, 0, len(body))
+	for _, stmt := range body {
+		switch s := stmt.(type) {
+		case *ast.DeclStmt:
+			if gen, ok := s.Decl.(*ast.GenDecl); ok && gen.Tok == token.VAR && len(gen.Specs) == 1 {
+				spec := gen.Specs[0].(*ast.ValueSpec)
+				if len(spec.Names) == 1 && len(spec.Values) == 0 && declared[spec.Names[0].Name] {
+					m.removed = append(m.removed, s)
+					continue
+				}
+			}
+		case *ast.AssignStmt:
+			if s.Tok == token.DEFINE {
+				fresh := false
+				for _, lhs := range s.Lhs {
+					if ident, ok := lhs.(*ast.Ident); ok && ident.Name != "_" && !declared[ident.Name] {
+						fresh = true
+					}
+				}
+				if !fresh {
+					s.Tok = token.ASSIGN
+				}
+			}
+		}
+		declare(stmt, declared)
+		out = append(out, stmt)
+	}
+	return out
+}
+
+/*
+dropUnused() removes the "var" declarations without values of the
+variables that are no longer used, like the error variables whose checks
+were rewritten.
+*/
+func (m *migration) dropUnused(body []ast.Stmt) []ast.Stmt {
+	uses := map[string]int{}
+	for _, stmt := range body {
+		ast.Inspect(stmt, func(n ast.Node) bool {
+			if ident, ok := n.(*ast.Ident); ok {
+				uses[ident.Name]++
+			}
+			return true
+		})
+	}
+	out := make([]ast.Stmt, 0, len(body))
+	for _, stmt := range body {
+		if decl, ok := stmt.(*ast.DeclStmt); ok {
+			if gen, ok := decl.Decl.(*ast.GenDecl); ok && gen.Tok == token.VAR && len(gen.Specs) == 1 {
+				spec := gen.Specs[0].(*ast.ValueSpec)
+				if len(spec.Names) == 1 && len(spec.Values) == 0 && uses[spec.Names[0].Name] == 1 {
+					m.removed = append(m.removed, stmt)
+					continue
+				}
+			}
+		}
+		out = append(out, stmt)
+	}
+	return out
+}
+
+/*
+defersRead() reports whether the deferred calls of the body read one of
+the variables, whose values would change once the results are named and
+the errors thrown, the deferred Catch() setting them after these calls.
+*/
+func defersRead(body *ast.BlockStmt, names []string) bool {
+	read := false
+	ast.Inspect(body, func(n ast.Node) bool {
+		if deferStmt, ok := n.(*ast.DeferStmt); ok {
+			for _, name := range names {
+				if name != "" && uses(deferStmt, name) {
+					read = true
+				}
+			}
+		}
+		return !read
+	})
+	return read
+}
+
+/*
+usedLater() reports whether the statements following a checked
+assignment use its error variable before declaring it again, in which
+case the assignment must be kept: throwing the error would drop the
+declaration of the variable, or the value it is later read with.
+*/
+func usedLater(stmts []ast.Stmt, name string) bool {
+	for _, stmt := range stmts {
+		switch s := stmt.(type) {
+		case *ast.AssignStmt:
+			if s.Tok == token.DEFINE && declares(s, name) {
+				for _, rhs := range s.Rhs {
+					if uses(rhs, name) {
+						return true
+					}
+				}
+				return false
+			}
+		case *ast.IfStmt:
+			if init, ok := s.Init.(*ast.AssignStmt); ok && init.Tok == token.DEFINE && declares(init, name) {
+				// the if statement has a variable of its own
+				for _, rhs := range init.Rhs {
+					if uses(rhs, name) {
+						return true
+					}
+				}
+				continue
+			}
+		}
+		if uses(stmt, name) {
+			return true
+		}
+	}
+	return false
+}
+
+// declares() reports whether the assignment assigns the variable.
+func declares(assign *ast.AssignStmt, name string) bool {
+	for _, lhs := range assign.Lhs {
+		if isIdent(lhs, name) {
+			return true
+		}
+	}
+	return false
+}
+
+// uses() reports whether the node refers to the name.
+func uses(node ast.Node, name string) bool {
+	used := false
+	ast.Inspect(node, func(n ast.Node) bool {
+		if ident, ok := n.(*ast.Ident); ok && ident.Name == name {
+			used = true
+		}
+		return !used
+	})
+	return used
+}
+
+// declare() adds the names declared by a statement to the scope.
+func declare(stmt ast.Stmt, declared scope) {
+	switch s := stmt.(type) {
+	case *ast.AssignStmt:
+		if s.Tok != token.DEFINE {
+			return
+		}
+		for _, lhs := range s.Lhs {
+			if ident, ok := lhs.(*ast.Ident); ok {
+				declared[ident.Name] = true
+			}
+		}
+	case *ast.DeclStmt:
+		if gen, ok := s.Decl.(*ast.GenDecl); ok {
+			for _, spec := range gen.Specs {
+				if spec, ok := spec.(*ast.ValueSpec); ok {
+					for _, name := range spec.Names {
+						declared[name.Name] = true
+					}
+				}
+			}
+		}
+	}
+}
+
+// defersCatch() reports whether the body already defers a Catch function.
+func defersCatch(body []ast.Stmt, pkg string) bool {
+	for _, stmt := range body {
+		deferStmt, ok := stmt.(*ast.DeferStmt)
+		if !ok {
+			continue
+		}
+		fun := deferStmt.Call.Fun
+		switch index := fun.(type) {
+		case *ast.IndexExpr:
+			fun = index.X
+		case *ast.IndexListExpr:
+			fun = index.X
+		}
+		var name string
+		switch fun := fun.(type) {
+		case *ast.Ident:
+			if pkg == "." {
+				name = fun.Name
+			}
+		case *ast.SelectorExpr:
+			if isIdent(fun.X, pkg) {
+				name = fun.Sel.Name
+			}
+		}
+		if strings.HasPrefix(name, "Catch") || name == "HandleMain" {
+			return true
+		}
+	}
+	return false
+}
+
+// identifiers() returns the set of the identifiers used by a function.
+func identifiers(fd *ast.FuncDecl) map[string]bool {
+	used := map[string]bool{}
+	ast.Inspect(fd, func(n ast.Node) bool {
+		if ident, ok := n.(*ast.Ident); ok {
+			used[ident.Name] = true
+		}
+		return true
+	})
+	return used
+}
+
+// isIdent() reports whether the expression is the identifier.
+func isIdent(expr ast.Expr, name string) bool {
+	ident, ok := expr.(*ast.Ident)
+	return ok && ident.Name == name
+}
+
+// isZero() reports whether the expression is a zero value literal.
+func isZero(expr ast.Expr) bool {
+	switch e := expr.(type) {
+	case *ast.Ident:
+		return e.Name == "nil" || e.Name == "false"
+	case *ast.BasicLit:
+		return e.Value == "0" || e.Value == `""` || e.Value == "``" || e.Value == "0.0"
+	case *ast.CompositeLit:
+		return len(e.Elts) == 0
+	}
+	return false
+}
+
+/*
+importName() returns the name the errhandling package is imported as in
+the file, or "" if it is not imported.
+*/
+func importName(file *ast.File) string {
+	for _, spec := range file.Imports {
+		if path, _ := strconv.Unquote(spec.Path.Value); path == importPath {
+			if spec.Name == nil {
+				return "errhandling"
+			}
+			if spec.Name.Name != "_" {
+				return spec.Name.Name
+			}
+		}
+	}
+	return ""
+}
+
+// addImport() adds the import of the errhandling package to the file.
+func addImport(file *ast.File) {
+	spec := &ast.ImportSpec{Path: &ast.BasicLit{Kind: token.STRING, Value: strconv.Quote(importPath)}}
+	file.Imports = append(file.Imports, spec)
+	for _, decl := range file.Decls {
+		if gen, ok := decl.(*ast.GenDecl); ok && gen.Tok == token.IMPORT {
+			if !gen.Lparen.IsValid() {
+				gen.Lparen = gen.Specs[0].Pos()
+				gen.Rparen = gen.Specs[0].End()
+			}
+			spec.Path.ValuePos = gen.Rparen - 1
+			gen.Specs = append(gen.Specs, spec)
+			return
+		}
+	}
+	gen := &ast.GenDecl{Tok: token.IMPORT, Specs: []ast.Spec{spec}}
+	file.Decls = append([]ast.Decl{gen}, file.Decls...)
+}
@@ -0,0 +1,148 @@
+package main
+
+import (
+	"testing"
+
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+)
+
+func TestMigrate(t *testing.T) {
+	RegisterFailHandler(Fail)
+	RunSpecs(t, "errhandling-migrate tests")
+}
+
+var _ = Describe("errhandling-migrate tests", func() {
+	It("Migrate should replace the propagation-only checks with Throw", func() {
+		out, err := Migrate("load.go", []byte(`package store
+
+import "os"
+
+func Load(path string) ([]byte, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+	if err := check(f); err != nil {
+		return nil, err
+	}
+	buf, err := read(f)
+	if err != nil {
+		return nil, fmt.Errorf("read: %w", err)
+	}
+	return buf, nil
+}
+`))
+		Expect(err).To(BeNil())
+		Expect(string(out)).To(Equal(`package store
+
+import (
+	"github.com/the-zucc/errhandling"
+	"os"
+)
+
+func Load(path string) (val []byte, err error) {
+	defer errhandling.Catch(&val, &err)
+	f := errhandling.Throw(os.Open(path))
+	defer f.Close()
+	errhandling.Throw_(check(f))
+	buf, err := read(f)
+	if err != nil {
+		return nil, fmt.Errorf("read: %w", err)
+	}
+	return buf, nil
+}
+`))
+	})
+	It("Migrate should reuse the named results and the existing import", func() {
+		out, err := Migrate("save.go", []byte(`package store
+
+import (
+	"os"
+
+	eh "github.com/the-zucc/errhandling"
+)
+
+func Save(path string, data []byte) (e error) {
+	var err error
+	err = os.WriteFile(path, data, 0o644)
+	if err != nil {
+		return err
+	}
+	return nil
+}
+`))
+		Expect(err).To(BeNil())
+		Expect(string(out)).To(Equal(`package store
+
+import (
+	"os"
+
+	eh "github.com/the-zucc/errhandling"
+)
+
+func Save(path string, data []byte) (e error) {
+	defer eh.Catch_(&e)
+	eh.Throw_(os.WriteFile(path, data, 0o644))
+	return nil
+}
+`))
+	})
+	It("Migrate should leave the functions handling their errors unchanged", func() {
+		src := []byte(`package store
+
+func Count(path string) (int, error) {
+	n, err := count(path)
+	if err != nil {
+		log.Print(err)
+		return 0, err
+	}
+	return n, nil
+}
+`)
+		out, err := Migrate("count.go", src)
+		Expect(err).To(BeNil())
+		Expect(out).To(Equal(src))
+	})
+	It("Migrate should keep the checks of the error variables used afterwards", func() {
+		src := []byte(`package store
+
+func Sync(path string) error {
+	err := flush(path)
+	if err != nil {
+		return err
+	}
+	err = fsync(path)
+	if err != nil {
+		os.Exit(1)
+	}
+	return nil
+}
+`)
+		out, err := Migrate("sync.go", src)
+		Expect(err).To(BeNil())
+		Expect(out).To(Equal(src))
+	})
+	It("Migrate should leave the functions whose deferred calls read the error unchanged", func() {
+		src := []byte(`package store
+
+func Store(path string) error {
+	var err error
+	defer func() {
+		if err != nil {
+			os.Remove(path)
+		}
+	}()
+	err = write(path)
+	if err != nil {
+		return err
+	}
+	return nil
+}
+`)
+		out, err := Migrate("store.go", src)
+		Expect(err).To(BeNil())
+		Expect(out).To(Equal(src))
+	})
+})
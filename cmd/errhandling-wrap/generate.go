@@ -0,0 +1,186 @@
+package main
+
+import (
+	"bytes"
+	"fmt"
+	"go/format"
+	"go/types"
+	"sort"
+	"strings"
+)
+
+const importPath = "github.com/the-zucc/errhandling"
+
+/*
+Generator generates the source of the wrappers of a package. Package is
+the name of the package generated, and Local the package it completes,
+if any, whose types are referred to unqualified.
+*/
+type Generator struct {
+	Package string
+	Local   *types.Package
+
+	imports map[string]string
+	body    bytes.Buffer
+}
+
+/*
+Wrapper() returns the source of a type embedding the named type (or a
+pointer to it if it is not an interface), whose methods returning an
+error Throw() it instead.
+*/
+func (g *Generator) Wrapper(named *types.Named, wrapper string) ([]byte, error) {
+	g.reset()
+	field := named.Obj().Name()
+	embedded := g.typeString(named)
+	var mset *types.MethodSet
+	if types.IsInterface(named) {
+		mset = types.NewMethodSet(named)
+	} else {
+		embedded = "*" + embedded
+		mset = types.NewMethodSet(types.NewPointer(named))
+	}
+	fmt.Fprintf(&g.body, "// %s wraps a %s, throwing the errors of its methods.\n", wrapper, field)
+	fmt.Fprintf(&g.body, "type %s struct {\n\t%s\n}\n", wrapper, embedded)
+	for i := 0; i < mset.Len(); i++ {
+		method := mset.At(i).Obj().(*types.Func)
+		if !g.accessible(method) {
+			continue
+		}
+		sig := method.Type().(*types.Signature)
+		if throwing(sig) == "" {
+			continue
+		}
+		fmt.Fprintf(&g.body, "\n// %s() calls %s.%s(), throwing its error.\n", method.Name(), field, method.Name())
+		g.function(fmt.Sprintf("(w %s) %s", wrapper, method.Name()), "w."+field+"."+method.Name(), sig)
+	}
+	return g.source()
+}
+
+/*
+Functions() returns the source of the wrappers of the exported functions
+of the package returning an error, which Throw() it instead.
+*/
+func (g *Generator) Functions(pkg *types.Package) ([]byte, error) {
+	g.reset()
+	g.imports[pkg.Path()] = pkg.Name()
+	for _, name := range pkg.Scope().Names() {
+		fn, ok := pkg.Scope().Lookup(name).(*types.Func)
+		if !ok || !fn.Exported() {
+			continue
+		}
+		sig := fn.Type().(*types.Signature)
+		if sig.TypeParams().Len() > 0 || throwing(sig) == "" {
+			continue
+		}
+		fmt.Fprintf(&g.body, "\n// %s() calls %s.%s(), throwing its error.\n", name, pkg.Name(), name)
+		g.function(name, pkg.Name()+"."+name, sig)
+	}
+	return g.source()
+}
+
+// reset() clears the state of a previous generation.
+func (g *Generator) reset() {
+	g.imports = map[string]string{importPath: "errhandling"}
+	g.body.Reset()
+}
+
+/*
+function() writes a function (or method) with the signature, returning
+the value of the call to target and throwing its error.
+*/
+func (g *Generator) function(decl, target string, sig *types.Signature) {
+	typeStrings := make([]string, sig.Params().Len())
+	for i := range typeStrings {
+		param := sig.Params().At(i)
+		if sig.Variadic() && i == len(typeStrings)-1 {
+			typeStrings[i] = "..." + g.typeString(param.Type().(*types.Slice).Elem())
+		} else {
+			typeStrings[i] = g.typeString(param.Type())
+		}
+	}
+	taken := map[string]bool{"w": true}
+	for _, name := range g.imports {
+		taken[name] = true
+	}
+	params, args := []string{}, []string{}
+	for i := range typeStrings {
+		name := sig.Params().At(i).Name()
+		if name == "" || name == "_" {
+			name = fmt.Sprintf("p%d", i)
+		}
+		for taken[name] {
+			name += "_"
+		}
+		taken[name] = true
+		params = append(params, name+" "+typeStrings[i])
+		if sig.Variadic() && i == len(typeStrings)-1 {
+			name += "..."
+		}
+		args = append(args, name)
+	}
+	call := fmt.Sprintf("%s(%s)", target, strings.Join(args, ", "))
+	if throwing(sig) == "Throw_" {
+		fmt.Fprintf(&g.body, "func %s(%s) {\n\terrhandling.Throw_(%s)\n}\n", decl, strings.Join(params, ", "), call)
+		return
+	}
+	result := g.typeString(sig.Results().At(0).Type())
+	fmt.Fprintf(&g.body, "func %s(%s) %s {\n\treturn errhandling.Throw(%s)\n}\n", decl, strings.Join(params, ", "), result, call)
+}
+
+// source() returns the formatted source of the generated file.
+func (g *Generator) source() ([]byte, error) {
+	buf := &bytes.Buffer{}
+	fmt.Fprintf(buf, "// Code generated by errhandling-wrap. DO NOT EDIT.\n\npackage %s\n\nimport (\n", g.Package)
+	paths := make([]string, 0, len(g.imports))
+	for path := range g.imports {
+		paths = append(paths, path)
+	}
+	sort.Strings(paths)
+	for _, path := range paths {
+		fmt.Fprintf(buf, "\t%q\n", path)
+	}
+	buf.WriteString(")\n\n")
+	buf.Write(g.body.Bytes())
+	return format.Source(buf.Bytes())
+}
+
+/*
+typeString() returns the type as written in the generated file, and
+records the packages to import.
+*/
+func (g *Generator) typeString(t types.Type) string {
+	return types.TypeString(t, func(pkg *types.Package) string {
+		if g.Local != nil && pkg.Path() == g.Local.Path() {
+			return ""
+		}
+		g.imports[pkg.Path()] = pkg.Name()
+		return pkg.Name()
+	})
+}
+
+// accessible() reports whether the generated file may call the method.
+func (g *Generator) accessible(method *types.Func) bool {
+	return method.Exported() || g.Local != nil && method.Pkg() == g.Local
+}
+
+/*
+throwing() returns the function throwing the results of the signature:
+Throw() for a value and an error, Throw_() for an error alone, or "" if
+it returns anything else.
+*/
+func throwing(sig *types.Signature) string {
+	results := sig.Results()
+	if results.Len() == 0 || results.Len() > 2 || !isError(results.At(results.Len()-1).Type()) {
+		return ""
+	}
+	if results.Len() == 1 {
+		return "Throw_"
+	}
+	return "Throw"
+}
+
+// isError() reports whether the type is the error interface.
+func isError(t types.Type) bool {
+	return types.Identical(t, types.Universe.Lookup("error").Type())
+}
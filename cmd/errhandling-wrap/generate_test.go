@@ -0,0 +1,72 @@
+package main
+
+import (
+	"os"
+	"testing"
+
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+)
+
+func TestWrap(t *testing.T) {
+	RegisterFailHandler(Fail)
+	RunSpecs(t, "errhandling-wrap tests")
+}
+
+var _ = Describe("errhandling-wrap tests", func() {
+	var l *loader
+	BeforeEach(func() {
+		dir, err := os.Getwd()
+		Expect(err).To(BeNil())
+		l = newLoader(dir)
+	})
+	It("Wrapper should throw the errors of the methods of an interface", func() {
+		named, err := l.lookup("io.ReadCloser")
+		Expect(err).To(BeNil())
+		g := &Generator{Package: "files"}
+		src, err := g.Wrapper(named, "Reader")
+		Expect(err).To(BeNil())
+		Expect(string(src)).To(Equal(`// Code generated by errhandling-wrap. DO NOT EDIT.
+
+package files
+
+import (
+	"github.com/the-zucc/errhandling"
+	"io"
+)
+
+// Reader wraps a ReadCloser, throwing the errors of its methods.
+type Reader struct {
+	io.ReadCloser
+}
+
+// Close() calls ReadCloser.Close(), throwing its error.
+func (w Reader) Close() {
+	errhandling.Throw_(w.ReadCloser.Close())
+}
+
+// Read() calls ReadCloser.Read(), throwing its error.
+func (w Reader) Read(p []byte) int {
+	return errhandling.Throw(w.ReadCloser.Read(p))
+}
+`))
+	})
+	It("Functions should throw the errors of the functions of a package", func() {
+		pkg, err := l.load("strconv")
+		Expect(err).To(BeNil())
+		g := &Generator{Package: "conv"}
+		src, err := g.Functions(pkg)
+		Expect(err).To(BeNil())
+		Expect(string(src)).To(ContainSubstring(`
+// Atoi() calls strconv.Atoi(), throwing its error.
+func Atoi(s string) int {
+	return errhandling.Throw(strconv.Atoi(s))
+}
+`))
+		Expect(string(src)).NotTo(ContainSubstring("func Itoa("))
+	})
+	It("lookup should refuse the names that are not types", func() {
+		_, err := l.lookup("strconv.Atoi")
+		Expect(err).NotTo(BeNil())
+	})
+})
@@ -0,0 +1,84 @@
+package main
+
+import (
+	"fmt"
+	"go/ast"
+	"go/build"
+	"go/importer"
+	"go/parser"
+	"go/token"
+	"go/types"
+)
+
+/*
+loader type-checks packages from their source, resolving the imports
+from the directory of the package being generated.
+*/
+type loader struct {
+	dir      string
+	fset     *token.FileSet
+	importer types.ImporterFrom
+}
+
+func newLoader(dir string) *loader {
+	fset := token.NewFileSet()
+	return &loader{
+		dir:      dir,
+		fset:     fset,
+		importer: importer.ForCompiler(fset, "source", nil).(types.ImporterFrom),
+	}
+}
+
+// load() type-checks the package with the import path.
+func (l *loader) load(importPath string) (*types.Package, error) {
+	return l.importer.ImportFrom(importPath, l.dir, 0)
+}
+
+/*
+local() type-checks the package of the directory. The type errors are
+ignored, as the package may use the wrappers that are not generated yet.
+*/
+func (l *loader) local() (*types.Package, error) {
+	bp, err := build.ImportDir(l.dir, 0)
+	if err != nil {
+		return nil, err
+	}
+	files := []*ast.File{}
+	for _, name := range bp.GoFiles {
+		file, err := parser.ParseFile(l.fset, bp.Dir+"/"+name, nil, 0)
+		if err != nil {
+			return nil, err
+		}
+		files = append(files, file)
+	}
+	conf := types.Config{Importer: l.importer, Error: func(error) {}}
+	pkg, _ := conf.Check(bp.ImportPath, l.fset, files, nil)
+	return pkg, nil
+}
+
+/*
+lookup() returns the named type, qualified by its import path if it
+belongs to another package than the one of the directory.
+*/
+func (l *loader) lookup(qualified string) (*types.Named, error) {
+	importPath, name := splitType(qualified)
+	var pkg *types.Package
+	var err error
+	if importPath == "" {
+		pkg, err = l.local()
+	} else {
+		pkg, err = l.load(importPath)
+	}
+	if err != nil {
+		return nil, err
+	}
+	obj, ok := pkg.Scope().Lookup(name).(*types.TypeName)
+	if !ok {
+		return nil, fmt.Errorf("no type %s in package %s", name, pkg.Path())
+	}
+	named, ok := obj.Type().(*types.Named)
+	if !ok || named.TypeParams().Len() > 0 {
+		return nil, fmt.Errorf("%s is not a non-generic named type", qualified)
+	}
+	return named, nil
+}
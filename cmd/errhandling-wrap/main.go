@@ -0,0 +1,119 @@
+/*
+errhandling-wrap generates wrappers throwing the errors of existing types
+or packages, so that their call sites read linearly. It is meant to be
+run with go generate:
+
+	//go:generate errhandling-wrap -type Store
+
+generates, in store_throw.go, a ThrowingStore type embedding a Store,
+whose methods returning a value and an error (or only an error) return
+the value and Throw() the error instead:
+
+	func (w ThrowingStore) Get(ctx context.Context, key string) []byte {
+		return errhandling.Throw(w.Store.Get(ctx, key))
+	}
+
+The type may be an interface or a concrete type, in which case a pointer
+to it is embedded, and may belong to another package:
+
+	//go:generate errhandling-wrap -type github.com/redis/go-redis/v9.Client
+
+The other methods are promoted from the embedded value unchanged. With
+-pkg instead of -type, the exported functions of a package are wrapped:
+
+	//go:generate errhandling-wrap -pkg os
+
+Usage:
+
+	errhandling-wrap (-type [importpath.]Name | -pkg importpath) [-name Wrapper] [-o file]
+*/
+package main
+
+import (
+	"flag"
+	"fmt"
+	"go/build"
+	"os"
+	"strings"
+)
+
+var (
+	typeName   = flag.String("type", "", "the type to wrap, qualified by its import path if in another package")
+	pkgPath    = flag.String("pkg", "", "the import path of the package whose functions are wrapped")
+	name       = flag.String("name", "", "the name of the wrapper type (default Throwing<Type>)")
+	outputFile = flag.String("o", "", "the output file (default <type>_throw.go)")
+)
+
+func main() {
+	flag.Usage = func() {
+		fmt.Fprintln(os.Stderr, "usage: errhandling-wrap (-type [importpath.]Name | -pkg importpath) [-name Wrapper] [-o file]")
+		flag.PrintDefaults()
+	}
+	flag.Parse()
+	if (*typeName == "") == (*pkgPath == "") {
+		flag.Usage()
+		os.Exit(2)
+	}
+	if err := run(); err != nil {
+		fmt.Fprintln(os.Stderr, "errhandling-wrap:", err)
+		os.Exit(1)
+	}
+}
+
+func run() error {
+	dir, err := os.Getwd()
+	if err != nil {
+		return err
+	}
+	l := newLoader(dir)
+	g := &Generator{Package: os.Getenv("GOPACKAGE")}
+	if g.Package == "" {
+		bp, err := build.ImportDir(dir, 0)
+		if err != nil {
+			return err
+		}
+		g.Package = bp.Name
+	}
+	g.Local, _ = l.local()
+	var src []byte
+	output := *outputFile
+	if *pkgPath != "" {
+		pkg, err := l.load(*pkgPath)
+		if err != nil {
+			return err
+		}
+		src, err = g.Functions(pkg)
+		if err != nil {
+			return err
+		}
+		if output == "" {
+			output = pkg.Name() + "_throw.go"
+		}
+	} else {
+		typ, err := l.lookup(*typeName)
+		if err != nil {
+			return err
+		}
+		wrapper := *name
+		if wrapper == "" {
+			wrapper = "Throwing" + typ.Obj().Name()
+		}
+		src, err = g.Wrapper(typ, wrapper)
+		if err != nil {
+			return err
+		}
+		if output == "" {
+			output = strings.ToLower(typ.Obj().Name()) + "_throw.go"
+		}
+	}
+	return os.WriteFile(output, src, 0o644)
+}
+
+// splitType() splits a type name qualified by its import path.
+func splitType(qualified string) (importPath, typeName string) {
+	dot := strings.LastIndex(qualified, ".")
+	if dot < 0 || dot < strings.LastIndex(qualified, "/") {
+		return "", qualified
+	}
+	return qualified[:dot], qualified[dot+1:]
+}
@@ -0,0 +1,98 @@
+package errhandling
+
+import (
+	"sync"
+
+	errstack "github.com/the-zucc/errhandling/err-stack"
+)
+
+/*
+Collector accumulates non-fatal errors, for call trees that should not
+stop at the first failure. It is safe for concurrent use, and its zero
+value is ready to use.
+*/
+type Collector struct {
+	mu   sync.Mutex
+	errs []error
+}
+
+// Collect() adds the error to the collector, unless it is nil.
+func (c *Collector) Collect(err error) {
+	if err == nil {
+		return
+	}
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.errs = append(c.errs, err)
+}
+
+// Errors() returns the errors collected so far.
+func (c *Collector) Errors() []error {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return append([]error{}, c.errs...)
+}
+
+/*
+Err() returns nil if no error was collected, the collected error if
+there is only one, and the collected errors joined with errstack.Join()
+otherwise.
+*/
+func (c *Collector) Err() error {
+	var err error
+	for _, collected := range c.Errors() {
+		AppendInto(&err, collected)
+	}
+	return err
+}
+
+/*
+Flush() appends the collected errors to the error pointed to by errAddr,
+and is meant to be deferred with the address of the function's returned
+error. When the function also uses Catch(), Flush() should be deferred
+first, so that it runs after Catch() has set the returned error.
+
+Example:
+
+	func CloseAll(files []*os.File) (e error) {
+		var errs Collector
+		defer errs.Flush(&e)
+		for _, f := range files {
+			errs.Collect(f.Close())
+		}
+		return nil
+	}
+*/
+func (c *Collector) Flush(errAddr *error) {
+	if errAddr == nil {
+		panic(ERROR_IN_CATCH)
+	}
+	for _, err := range c.Errors() {
+		AppendInto(errAddr, err)
+	}
+}
+
+/*
+AppendInto() appends the error to the one pointed to by errAddr: if the
+latter is nil, it is replaced, and otherwise both are joined with
+errstack.Join(). It reports whether the error was non-nil.
+
+Example:
+
+	func Shutdown() (e error) {
+		AppendInto(&e, server.Close())
+		AppendInto(&e, db.Close())
+		return e
+	}
+*/
+func AppendInto(errAddr *error, err error) bool {
+	if err == nil {
+		return false
+	}
+	if *errAddr == nil {
+		*errAddr = err
+		return true
+	}
+	*errAddr = errstack.Join(*errAddr, err)
+	return true
+}
@@ -0,0 +1,93 @@
+package errhandling
+
+import (
+	"context"
+
+	errstack "github.com/the-zucc/errhandling/err-stack"
+)
+
+/*
+ThrowIfDone() throws the error of the context (and the cause of its
+cancellation, see doneCauses()) up the call stack if it is cancelled or
+expired, so it needs to be paired with a deferred call
+to Catch(). It is useful to check for cancellation between steps of a
+long operation.
+
+Example:
+
+	func Import(ctx context.Context, rows []Row) (e error) {
+		defer Catch_(&e)
+		for _, row := range rows {
+			ThrowIfDone(ctx)
+			Throw_(insert(ctx, row))
+		}
+		return nil
+	}
+
+errlint:throws
+*/
+func ThrowIfDone(ctx context.Context) {
+	err := ctx.Err()
+	if err == nil {
+		return
+	}
+	if err == context.DeadlineExceeded {
+		Throw_(errstack.New("context deadline exceeded", doneCauses(ctx)...).TimedOut())
+	}
+	Throw_(errstack.New("context cancelled", doneCauses(ctx)...))
+}
+
+/*
+doneCauses() returns the causes of the errors of a done context: its
+error, so that errors.Is(err, context.Canceled) holds, and the cause of
+its cancellation, if one was given with context.WithCancelCause() and
+the like.
+*/
+func doneCauses(ctx context.Context) []error {
+	err := ctx.Err()
+	if cause := context.Cause(ctx); cause != nil && cause != err {
+		return []error{err, cause}
+	}
+	return []error{err}
+}
+
+type collectorKey struct{}
+
+/*
+WithCollector() returns a copy of the context carrying a new Collector,
+so that deep call trees can accumulate non-fatal errors per request
+with CollectInto().
+
+Example:
+
+	ctx, errs := WithCollector(r.Context())
+	render(ctx, page) // calls CollectInto(ctx, err) for missing widgets
+	log.Println(errs.Err())
+*/
+func WithCollector(ctx context.Context) (context.Context, *Collector) {
+	c := &Collector{}
+	return context.WithValue(ctx, collectorKey{}, c), c
+}
+
+/*
+CollectorFrom() returns the Collector carried by the context, or nil if
+there is none.
+*/
+func CollectorFrom(ctx context.Context) *Collector {
+	c, _ := ctx.Value(collectorKey{}).(*Collector)
+	return c
+}
+
+/*
+CollectInto() adds the error to the Collector carried by the context.
+It does nothing if the error is nil or if there is no Collector, and
+reports whether the error was collected.
+*/
+func CollectInto(ctx context.Context, err error) bool {
+	c := CollectorFrom(ctx)
+	if err == nil || c == nil {
+		return false
+	}
+	c.Collect(err)
+	return true
+}
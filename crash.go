@@ -0,0 +1,141 @@
+package errhandling
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"regexp"
+	"runtime"
+	"runtime/debug"
+	"strings"
+	"time"
+
+	errstack "github.com/the-zucc/errhandling/err-stack"
+)
+
+/*
+CrashReportDir is the directory HandleMain() writes its crash reports
+to, before exiting on a failure (see WriteCrashReport()). No report is
+written if it is empty, which is the default unless the
+ERRHANDLING_CRASH_DIR environment variable is set.
+*/
+var CrashReportDir = os.Getenv("ERRHANDLING_CRASH_DIR")
+
+// the names of the environment variables and flags whose values are left out of the crash reports
+var secretEnvPattern = regexp.MustCompile(`(?i)pass|secret|token|key|credential|auth|dsn|cookie|session`)
+
+/*
+CrashReport is the structured report of a failure written by
+WriteCrashReport(), for post-mortem analysis.
+*/
+type CrashReport struct {
+	Time       time.Time         `json:"time"`
+	PID        int               `json:"pid"`
+	Args       []string          `json:"args"`
+	Error      json.RawMessage   `json:"error,omitempty"` // the JSON document of the error, if it is stacked
+	Trace      string            `json:"trace"`
+	Goroutines string            `json:"goroutines"`
+	GoVersion  string            `json:"go_version"`
+	Metadata   errstack.Metadata `json:"metadata"` // as set with errstack.SetMetadata()
+	Build      map[string]string `json:"build,omitempty"`
+	Env        map[string]string `json:"env"`
+}
+
+/*
+WriteCrashReport() writes a crash report of the error to a new file of
+the directory, and returns its path. The report holds the whole trace of
+the error and its JSON document, the stacks of all the goroutines, the
+metadata and build information of the program, and its arguments and
+environment, redacted: the values of the variables and flags whose name
+suggests a secret (such as DB_PASSWORD or --api-token) are left out, and
+the others are redacted as registered with errstack.RedactPattern().
+*/
+func WriteCrashReport(dir string, err error) (string, error) {
+	report := newCrashReport(err)
+	data, jsonErr := json.MarshalIndent(report, "", "  ")
+	if jsonErr != nil {
+		return "", errstack.New("could not encode the crash report", jsonErr)
+	}
+	if mkdirErr := os.MkdirAll(dir, 0o755); mkdirErr != nil {
+		return "", errstack.New("could not create the crash report directory", mkdirErr).With("dir", dir)
+	}
+	path := filepath.Join(dir, fmt.Sprintf("crash-%s-%d.json", report.Time.Format("20060102T150405.000000000"), report.PID))
+	if writeErr := os.WriteFile(path, data, 0o600); writeErr != nil {
+		return "", errstack.New("could not write the crash report", writeErr).With("path", path)
+	}
+	return path, nil
+}
+
+// this gathers the crash report of the error
+func newCrashReport(err error) CrashReport {
+	report := CrashReport{
+		Time:       time.Now().UTC(),
+		PID:        os.Getpid(),
+		Args:       redactArgs(os.Args),
+		Trace:      fmt.Sprintf("%+v", err),
+		Goroutines: string(goroutines()),
+		GoVersion:  runtime.Version(),
+		Metadata:   errstack.CurrentMetadata(),
+		Env:        map[string]string{},
+	}
+	if se, ok := err.(errstack.Error); ok {
+		if doc, jsonErr := se.MarshalJSON(); jsonErr == nil {
+			report.Error = doc
+		}
+	}
+	if info, ok := debug.ReadBuildInfo(); ok {
+		report.Build = map[string]string{"path": info.Path, "version": info.Main.Version}
+		for _, setting := range info.Settings {
+			report.Build[setting.Key] = setting.Value
+		}
+	}
+	for _, variable := range os.Environ() {
+		name, value, _ := strings.Cut(variable, "=")
+		if secretEnvPattern.MatchString(name) {
+			value = errstack.Redacted
+		}
+		report.Env[name] = errstack.Redact(value)
+	}
+	return report
+}
+
+/*
+this redacts the arguments of the program like its environment: the
+values of the flags whose name suggests a secret ("--token=...", or
+"--token ..."), and the patterns registered with errstack.RedactPattern()
+*/
+func redactArgs(args []string) []string {
+	redacted := make([]string, len(args))
+	secret := false // whether the argument is the value of a secret flag
+	for i, arg := range args {
+		if secret {
+			arg = errstack.Redacted
+		}
+		secret = false
+		if strings.HasPrefix(arg, "-") {
+			name, _, hasValue := strings.Cut(arg, "=")
+			if secretEnvPattern.MatchString(name) {
+				if hasValue {
+					arg = name + "=" + errstack.Redacted
+				} else {
+					secret = true
+				}
+			}
+		}
+		redacted[i] = errstack.Redact(arg)
+	}
+	return redacted
+}
+
+// this returns the stacks of all the goroutines
+func goroutines() []byte {
+	buf := make([]byte, 64<<10)
+	for {
+		n := runtime.Stack(buf, true)
+		if n < len(buf) {
+			return buf[:n]
+		}
+		buf = make([]byte, 2*len(buf))
+	}
+}
@@ -0,0 +1,10 @@
+//go:build !errhandling_nodebug
+
+package errhandling
+
+/*
+DebugAsserts reports whether DebugAssert() checks its condition, which
+it does unless the program is built with the errhandling_nodebug build
+tag.
+*/
+const DebugAsserts = true
@@ -0,0 +1,152 @@
+package errcatalog
+
+import (
+	"fmt"
+	"os"
+	"sort"
+	"strings"
+
+	errstack "github.com/the-zucc/errhandling/err-stack"
+	"gopkg.in/yaml.v3"
+)
+
+/*
+Entry describes an error code of the catalog: the default message of the
+errors built with it, the message that is safe to show to users, the
+HTTP status to respond with, and a hint on how to remediate the error.
+*/
+type Entry struct {
+	Code          string `yaml:"-" json:"-"`
+	Message       string `yaml:"message" json:"message"`
+	PublicMessage string `yaml:"public_message" json:"public_message"`
+	Status        int    `yaml:"status" json:"status"`
+	Remediation   string `yaml:"remediation" json:"remediation"`
+}
+
+/*
+Catalog maps error codes to their Entry. It is meant to be loaded at
+startup from a YAML or JSON file, so that codes, messages and statuses
+are defined in one place.
+*/
+type Catalog struct {
+	entries map[string]Entry
+}
+
+/*
+LoadFile() loads a catalog from a YAML or JSON file, of the following
+format:
+
+	ORDER_409:
+	  message: order already exists
+	  public_message: This order was already placed.
+	  status: 409
+	  remediation: Check the order ID before retrying.
+*/
+func LoadFile(path string) (*Catalog, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, errstack.New(fmt.Sprintf("could not read error catalog %s", path), err)
+	}
+	c, err := Parse(data)
+	if err != nil {
+		return nil, errstack.New(fmt.Sprintf("could not load error catalog %s", path), err)
+	}
+	return c, nil
+}
+
+/*
+Parse() parses a catalog from YAML or JSON data (JSON being a subset of
+YAML), and checks that every entry has a message and a valid status.
+*/
+func Parse(data []byte) (*Catalog, error) {
+	entries := map[string]Entry{}
+	if err := yaml.Unmarshal(data, &entries); err != nil {
+		return nil, errstack.New("invalid error catalog", err)
+	}
+	errs := []error{}
+	for code, entry := range entries {
+		if entry.Message == "" {
+			errs = append(errs, errstack.New(fmt.Sprintf("code %s has no message", code)))
+		}
+		if entry.Status != 0 && (entry.Status < 100 || entry.Status > 599) {
+			errs = append(errs, errstack.New(fmt.Sprintf("code %s has an invalid status %d", code, entry.Status)))
+		}
+		entry.Code = code
+		entries[code] = entry
+	}
+	if err := errstack.Join(errs...); err != nil {
+		return nil, errstack.New("invalid error catalog", err)
+	}
+	return &Catalog{entries: entries}, nil
+}
+
+// Lookup() returns the entry of the provided code, if it exists.
+func (c *Catalog) Lookup(code string) (Entry, bool) {
+	entry, ok := c.entries[code]
+	return entry, ok
+}
+
+// Codes() returns the codes of the catalog, sorted.
+func (c *Catalog) Codes() []string {
+	codes := make([]string, 0, len(c.entries))
+	for code := range c.entries {
+		codes = append(codes, code)
+	}
+	sort.Strings(codes)
+	return codes
+}
+
+/*
+New() instanciates an errstack.Error with the provided code, along with
+the message, public message, status and remediation of its entry. The
+status and remediation are attached as the "http_status" and
+"remediation" fields. If the code is not in the catalog, the error still
+carries the code, with a message saying so.
+
+Example:
+
+	return catalog.New("ORDER_409", err)
+*/
+func (c *Catalog) New(code string, cause ...error) errstack.Error {
+	entry, ok := c.entries[code]
+	if !ok {
+		return errstack.NewCode(code, fmt.Sprintf("unknown error code %s", code), cause...)
+	}
+	e := errstack.NewCode(code, entry.Message, cause...)
+	if entry.PublicMessage != "" {
+		e = e.WithPublicMessage(entry.PublicMessage)
+	}
+	if entry.Status != 0 {
+		e = e.With("http_status", entry.Status)
+	}
+	if entry.Remediation != "" {
+		e = e.With("remediation", entry.Remediation)
+	}
+	return e
+}
+
+/*
+Validate() returns an error listing the provided codes that are missing
+from the catalog, or nil if they all exist.
+*/
+func (c *Catalog) Validate(codes ...string) error {
+	missing := []string{}
+	for _, code := range codes {
+		if _, ok := c.entries[code]; !ok {
+			missing = append(missing, code)
+		}
+	}
+	if len(missing) == 0 {
+		return nil
+	}
+	return errstack.New(fmt.Sprintf("codes missing from the error catalog: %s", strings.Join(missing, ", ")))
+}
+
+/*
+ValidateRegistered() checks that every code registered with
+errstack.RegisterCode() exists in the catalog. It is meant to be called
+at startup, once the catalog is loaded.
+*/
+func (c *Catalog) ValidateRegistered() error {
+	return c.Validate(errstack.RegisteredCodes()...)
+}
@@ -0,0 +1,43 @@
+package errcatalog_test
+
+import (
+	"errors"
+	"testing"
+
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+
+	errcatalog "github.com/the-zucc/errhandling/err-catalog"
+	errstack "github.com/the-zucc/errhandling/err-stack"
+)
+
+const SAMPLE_CATALOG = `
+ORDER_409:
+  message: order already exists
+  public_message: This order was already placed.
+  status: 409
+`
+
+func TestErrCatalog(t *testing.T) {
+	RegisterFailHandler(Fail)
+	RunSpecs(t, "errcatalog tests")
+}
+
+var _ = Describe("errcatalog tests", func() {
+	It("New() should build errors from the catalog entries", func() {
+		catalog, err := errcatalog.Parse([]byte(SAMPLE_CATALOG))
+		Expect(err).To(BeNil())
+		e := catalog.New("ORDER_409", errors.New("duplicate key"))
+		Expect(errstack.Code(e)).To(Equal("ORDER_409"))
+		Expect(errstack.PublicMessage(e)).To(Equal("This order was already placed."))
+		status, _ := errstack.Field(e, "http_status")
+		Expect(status).To(Equal(409))
+	})
+	It("Parse() and Validate() should report invalid and missing codes", func() {
+		_, err := errcatalog.Parse([]byte(`{"BAD": {"status": 42}}`))
+		Expect(err).NotTo(BeNil())
+		catalog, _ := errcatalog.Parse([]byte(SAMPLE_CATALOG))
+		Expect(catalog.Validate("ORDER_409")).To(BeNil())
+		Expect(catalog.Validate("ORDER_404")).NotTo(BeNil())
+	})
+})
@@ -0,0 +1,91 @@
+/*
+Package errcli integrates the errhandling package with Cobra: commands
+may Throw, their errors are rendered for humans, and the process exits
+with the code mapped to them.
+*/
+package errcli
+
+import (
+	"context"
+	"os"
+
+	"github.com/spf13/cobra"
+	"github.com/the-zucc/errhandling"
+)
+
+/*
+VerboseFlag is the name of the boolean flag that, like
+errhandling.Verbose, makes the errors be printed with their whole trace.
+The flag is not defined by this package.
+*/
+var VerboseFlag = "verbose"
+
+/*
+Wrap() adapts a RunE function so that it may Throw. The errors are
+printed to the error output of the command with
+errhandling.PrintError(), and still returned for Execute() to exit with
+the mapped code.
+
+Example:
+
+	cmd := &cobra.Command{
+		Use: "import FILE",
+		RunE: errcli.Wrap(func(cmd *cobra.Command, args []string) error {
+			rows := Throw(readRows(args[0]))
+			Throw_(store(cmd.Context(), rows))
+			return nil
+		}),
+	}
+*/
+func Wrap(f func(cmd *cobra.Command, args []string) error) func(cmd *cobra.Command, args []string) error {
+	return func(cmd *cobra.Command, args []string) error {
+		err := run(f, cmd, args)
+		if err != nil {
+			errhandling.PrintError(cmd.ErrOrStderr(), err, verbose(cmd))
+			return rendered{err}
+		}
+		return nil
+	}
+}
+
+// rendered marks the errors that Wrap() already rendered.
+type rendered struct {
+	error
+}
+
+func (r rendered) Unwrap() error {
+	return r.error
+}
+
+func run(f func(cmd *cobra.Command, args []string) error, cmd *cobra.Command, args []string) (err error) {
+	defer errhandling.CatchPanic_(&err)
+	return f(cmd, args)
+}
+
+// verbose() reports whether the errors of the command are printed with their whole trace.
+func verbose(cmd *cobra.Command) bool {
+	flag, _ := cmd.Flags().GetBool(VerboseFlag)
+	return flag || errhandling.Verbose
+}
+
+/*
+Execute() runs the command, and exits the process with the code that
+errhandling.ExitCode() maps to its error, if any. The errors that Wrap()
+did not print, like Cobra's own usage errors, are printed as well.
+
+Example:
+
+	func main() {
+		errcli.Execute(context.Background(), rootCmd)
+	}
+*/
+func Execute(ctx context.Context, cmd *cobra.Command) {
+	cmd.SilenceErrors = true
+	cmd.SilenceUsage = true
+	if err := cmd.ExecuteContext(ctx); err != nil {
+		if _, ok := err.(rendered); !ok {
+			errhandling.PrintError(cmd.ErrOrStderr(), err, verbose(cmd))
+		}
+		os.Exit(errhandling.ExitCode(err))
+	}
+}
@@ -0,0 +1,65 @@
+package errcli_test
+
+import (
+	"bytes"
+	"errors"
+	"testing"
+
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+	"github.com/spf13/cobra"
+
+	. "github.com/the-zucc/errhandling"
+	errcli "github.com/the-zucc/errhandling/err-cli"
+	errstack "github.com/the-zucc/errhandling/err-stack"
+)
+
+func TestErrCli(t *testing.T) {
+	RegisterFailHandler(Fail)
+	RunSpecs(t, "errcli tests")
+}
+
+var _ = Describe("errcli tests", func() {
+	It("Wrap() should render what the command throws and keep its exit code", func() {
+		MapExitCode("CONFIG_INVALID", 78)
+		var stderr bytes.Buffer
+		cmd := &cobra.Command{
+			Use: "import",
+			RunE: errcli.Wrap(func(cmd *cobra.Command, args []string) error {
+				Throw_(errstack.NewCode("CONFIG_INVALID", "load config", errors.New("missing key")))
+				return nil
+			}),
+			SilenceErrors: true,
+			SilenceUsage:  true,
+		}
+		cmd.SetErr(&stderr)
+		cmd.SetArgs([]string{})
+		err := cmd.Execute()
+		Expect(stderr.String()).To(Equal("Error: load config: missing key\n"))
+		Expect(ExitCode(err)).To(Equal(78))
+	})
+	It("Wrap() should print the whole trace with the verbose flag or errhandling.Verbose", func() {
+		run := func(args ...string) string {
+			var stderr bytes.Buffer
+			cmd := &cobra.Command{
+				Use: "import",
+				RunE: errcli.Wrap(func(cmd *cobra.Command, args []string) error {
+					Throw_(errstack.New("load config", errors.New("missing key")).WithPublicMessage("The configuration is invalid."))
+					return nil
+				}),
+				SilenceErrors: true,
+				SilenceUsage:  true,
+			}
+			cmd.Flags().Bool(errcli.VerboseFlag, false, "print the whole trace of the errors")
+			cmd.SetErr(&stderr)
+			cmd.SetArgs(args)
+			_ = cmd.Execute()
+			return stderr.String()
+		}
+		Expect(run()).To(Equal("Error: The configuration is invalid.\n"))
+		Expect(run("--verbose")).To(ContainSubstring("Full error trace:"))
+		defer func(verbose bool) { Verbose = verbose }(Verbose)
+		Verbose = true
+		Expect(run()).To(ContainSubstring("Full error trace:"))
+	})
+})
@@ -0,0 +1,117 @@
+/*
+Package errconsumer wraps the handlers of message consumers (Kafka, NATS,
+SQS, ...) so that they may Throw, and classifies their failures into
+redeliveries and dead letters.
+*/
+package errconsumer
+
+import (
+	"context"
+	"encoding/json"
+
+	"github.com/the-zucc/errhandling"
+	errstack "github.com/the-zucc/errhandling/err-stack"
+)
+
+// Decision is what the consumer should do with a handled message.
+type Decision int
+
+const (
+	Ack          Decision = iota // the message was handled
+	Redeliver                    // the message should be delivered again
+	DeadLettered                 // the message was passed to the dead-letter callback
+)
+
+func (d Decision) String() string {
+	switch d {
+	case Ack:
+		return "ack"
+	case Redeliver:
+		return "redeliver"
+	case DeadLettered:
+		return "dead_lettered"
+	}
+	return "unknown"
+}
+
+/*
+Config configures Wrap(). DeadLetter is called with the messages whose
+handling failed with a non-retryable error, and the chain of the error
+encoded as JSON by errstack.Error's MarshalJSON(); if it is nil or
+fails, the message is redelivered. Observe, if set, is called for every
+message with the code of the error ("" on success) and the decision,
+e.g. to count them per code.
+*/
+type Config[M any] struct {
+	DeadLetter func(ctx context.Context, msg M, chain []byte) error
+	Observe    func(code string, decision Decision)
+}
+
+/*
+Wrap() adapts a handler that may Throw to a function returning the
+Decision for each message: Ack on success, Redeliver on retryable
+errors (see errstack.IsRetryable()), and DeadLettered once the message
+was passed to DeadLetter for the other errors. Both Ack and DeadLettered
+mean that the message should be acknowledged. The failures of
+DeadLetter are passed to errhandling.Report().
+
+Example:
+
+	handle := errconsumer.Wrap(func(ctx context.Context, msg *sarama.ConsumerMessage) {
+		order := Throw(decodeOrder(msg.Value))
+		Throw_(ship(ctx, order))
+	}, errconsumer.Config[*sarama.ConsumerMessage]{
+		DeadLetter: func(ctx context.Context, msg *sarama.ConsumerMessage, chain []byte) error {
+			return produceDLQ(ctx, msg, chain)
+		},
+		Observe: func(code string, decision errconsumer.Decision) {
+			handled.WithLabelValues(code, decision.String()).Inc()
+		},
+	})
+	for msg := range claim.Messages() {
+		if handle(session.Context(), msg) != errconsumer.Redeliver {
+			session.MarkMessage(msg, "")
+		}
+	}
+*/
+func Wrap[M any](handler func(ctx context.Context, msg M), cfg Config[M]) func(ctx context.Context, msg M) Decision {
+	return func(ctx context.Context, msg M) Decision {
+		err := handle(ctx, msg, handler)
+		decision := decide(ctx, msg, err, cfg)
+		if cfg.Observe != nil {
+			cfg.Observe(errstack.Code(err), decision)
+		}
+		return decision
+	}
+}
+
+// handle() runs the handler, converting what it throws into an error.
+func handle[M any](ctx context.Context, msg M, handler func(ctx context.Context, msg M)) (e error) {
+	defer errhandling.CatchPanic_(&e)
+	handler(ctx, msg)
+	return nil
+}
+
+func decide[M any](ctx context.Context, msg M, err error, cfg Config[M]) Decision {
+	if err == nil {
+		return Ack
+	}
+	if errstack.IsRetryable(err) || cfg.DeadLetter == nil {
+		return Redeliver
+	}
+	chain, jsonErr := json.Marshal(errstack.New("message handling failed", err))
+	if jsonErr != nil {
+		chain, _ = json.Marshal(map[string]string{"message": err.Error()})
+	}
+	if dlqErr := handleDeadLetter(ctx, msg, chain, cfg.DeadLetter); dlqErr != nil {
+		errhandling.Report(errstack.New("could not dead-letter message", err, dlqErr))
+		return Redeliver
+	}
+	return DeadLettered
+}
+
+// handleDeadLetter() calls the callback, which may Throw as well.
+func handleDeadLetter[M any](ctx context.Context, msg M, chain []byte, deadLetter func(ctx context.Context, msg M, chain []byte) error) (e error) {
+	defer errhandling.CatchPanic_(&e)
+	return deadLetter(ctx, msg, chain)
+}
@@ -0,0 +1,49 @@
+package errconsumer_test
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+
+	. "github.com/the-zucc/errhandling"
+	errconsumer "github.com/the-zucc/errhandling/err-consumer"
+	errstack "github.com/the-zucc/errhandling/err-stack"
+)
+
+func TestErrConsumer(t *testing.T) {
+	RegisterFailHandler(Fail)
+	RunSpecs(t, "errconsumer tests")
+}
+
+var _ = Describe("errconsumer tests", func() {
+	It("Wrap() should redeliver retryable failures and dead-letter the others", func() {
+		deadLetters := map[string][]byte{}
+		observed := []string{}
+		handle := errconsumer.Wrap(func(ctx context.Context, msg string) {
+			switch msg {
+			case "flaky":
+				Throw_(errstack.New("broker unavailable").Retryable())
+			case "poison":
+				Throw_(errstack.NewCode("INVALID_ORDER", "decode order", errors.New("unexpected EOF")))
+			}
+		}, errconsumer.Config[string]{
+			DeadLetter: func(ctx context.Context, msg string, chain []byte) error {
+				deadLetters[msg] = chain
+				return nil
+			},
+			Observe: func(code string, decision errconsumer.Decision) {
+				observed = append(observed, code+":"+decision.String())
+			},
+		})
+		Expect(handle(context.Background(), "ok")).To(Equal(errconsumer.Ack))
+		Expect(handle(context.Background(), "flaky")).To(Equal(errconsumer.Redeliver))
+		Expect(handle(context.Background(), "poison")).To(Equal(errconsumer.DeadLettered))
+		Expect(observed).To(Equal([]string{":ack", ":redeliver", "INVALID_ORDER:dead_lettered"}))
+		err, decodeErr := errstack.Decode(deadLetters["poison"])
+		Expect(decodeErr).To(BeNil())
+		Expect(errstack.RootCode(err)).To(Equal("INVALID_ORDER"))
+	})
+})
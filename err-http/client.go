@@ -0,0 +1,68 @@
+package errhttp
+
+import (
+	"fmt"
+	"io"
+	"net/http"
+
+	"github.com/the-zucc/errhandling"
+	errstack "github.com/the-zucc/errhandling/err-stack"
+)
+
+// MaxBodyRead is how much of the body StatusError keeps, in bytes.
+var MaxBodyRead int64 = 4096
+
+/*
+StatusError is the error of a response whose status is not 2xx. It
+holds at most MaxBodyRead bytes of the body, and unwraps to the error
+the server propagated with ChainFrom() if there is one.
+*/
+type StatusError struct {
+	Method   string
+	URL      string
+	Status   int
+	Body     []byte
+	Upstream error
+}
+
+func (e *StatusError) Error() string {
+	return fmt.Sprintf("%s %s: %d %s", e.Method, e.URL, e.Status, http.StatusText(e.Status))
+}
+
+func (e *StatusError) Unwrap() error {
+	return e.Upstream
+}
+
+/*
+ThrowStatus() throws the error of an HTTP call, or a *StatusError if
+the status of the response is not 2xx, in which case it reads and
+closes the body. Otherwise it returns the response.
+
+Example:
+
+	resp := errhttp.ThrowStatus(client.Get(url))
+	defer resp.Body.Close()
+
+errlint:throws
+*/
+func ThrowStatus(resp *http.Response, err error) *http.Response {
+	errhandling.Throw_(err)
+	if resp.StatusCode >= 200 && resp.StatusCode < 300 {
+		return resp
+	}
+	defer resp.Body.Close()
+	statusErr := &StatusError{Status: resp.StatusCode}
+	if resp.Request != nil {
+		statusErr.Method = resp.Request.Method
+		statusErr.URL = resp.Request.URL.Redacted()
+	}
+	statusErr.Body, _ = io.ReadAll(io.LimitReader(resp.Body, MaxBodyRead))
+	if upstream, ok := ChainFrom(resp); ok {
+		statusErr.Upstream = upstream
+	}
+	errhandling.Throw_(errstack.New("unexpected response status", statusErr).
+		With("method", statusErr.Method).
+		With("url", statusErr.URL).
+		With("status", statusErr.Status))
+	return nil
+}
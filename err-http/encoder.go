@@ -0,0 +1,97 @@
+package errhttp
+
+import (
+	"encoding/json"
+	"html/template"
+	"net/http"
+
+	errstack "github.com/the-zucc/errhandling/err-stack"
+)
+
+/*
+ErrorEncoder writes the response for an error. Encoders only expose the
+public message of the error (or the status text if it has none), so that
+internal details are not leaked to clients.
+*/
+type ErrorEncoder func(w http.ResponseWriter, r *http.Request, err error)
+
+/*
+Encoder is the ErrorEncoder used by WriteError(), Recover() and
+Handler(). It defaults to Text.
+*/
+var Encoder ErrorEncoder = Text
+
+/*
+Text() responds with the public message of the error, as plain text.
+*/
+func Text(w http.ResponseWriter, r *http.Request, err error) {
+	status, msg := Status(err), message(err)
+	http.Error(w, msg, status)
+}
+
+/*
+JSON() responds with a JSON document holding the public message of the
+error, its code and correlation ID (if any) and its status:
+
+	{"error": "This order was already placed.", "code": "ORDER_EXISTS", "correlation_id": "9f86d081884c7d65", "status": 409}
+*/
+func JSON(w http.ResponseWriter, r *http.Request, err error) {
+	status := Status(err)
+	writeJSON(w, "application/json", status, struct {
+		Error         string `json:"error"`
+		Code          string `json:"code,omitempty"`
+		CorrelationID string `json:"correlation_id,omitempty"`
+		Status        int    `json:"status"`
+	}{message(err), errstack.Code(err), errstack.ID(err), status})
+}
+
+/*
+ProblemJSON() responds with the RFC 7807 application/problem+json
+document built by NewProblem(), with the request URI as its instance.
+*/
+func ProblemJSON(w http.ResponseWriter, r *http.Request, err error) {
+	problem := NewProblem(err)
+	problem.Instance = r.URL.RequestURI()
+	writeJSON(w, "application/problem+json", problem.Status, problem)
+}
+
+var page = template.Must(template.New("error").Parse(`<!DOCTYPE html>
+<html>
+<head><title>{{.Status}} {{.Title}}</title></head>
+<body>
+<h1>{{.Title}}</h1>
+<p>{{.Message}}</p>
+{{if .ID}}<p>Error ID: <code>{{.ID}}</code></p>
+{{end}}</body>
+</html>
+`))
+
+/*
+HTML() responds with a minimal HTML page holding the status, the public
+message and the correlation ID of the error.
+*/
+func HTML(w http.ResponseWriter, r *http.Request, err error) {
+	status := Status(err)
+	w.Header().Set("Content-Type", "text/html; charset=utf-8")
+	w.Header().Set("X-Content-Type-Options", "nosniff")
+	w.WriteHeader(status)
+	page.Execute(w, struct {
+		Status             int
+		Title, Message, ID string
+	}{status, http.StatusText(status), message(err), errstack.ID(err)})
+}
+
+// message() returns the public message of the error, or the status text.
+func message(err error) string {
+	if msg := errstack.PublicMessage(err); msg != "" {
+		return msg
+	}
+	return http.StatusText(Status(err))
+}
+
+func writeJSON(w http.ResponseWriter, contentType string, status int, body any) {
+	w.Header().Set("Content-Type", contentType)
+	w.Header().Set("X-Content-Type-Options", "nosniff")
+	w.WriteHeader(status)
+	json.NewEncoder(w).Encode(body)
+}
@@ -0,0 +1,180 @@
+/*
+Package errhttp integrates the errhandling package with net/http:
+handlers may Throw, and the errors are turned into HTTP responses.
+*/
+package errhttp
+
+import (
+	"bufio"
+	"errors"
+	"io"
+	"log/slog"
+	"net"
+	"net/http"
+
+	"github.com/the-zucc/errhandling"
+	errstack "github.com/the-zucc/errhandling/err-stack"
+)
+
+/*
+Logger is used to log the full chain of the errors turned into
+responses. It defaults to slog.Default().
+*/
+var Logger *slog.Logger
+
+/*
+CorrelationHeader is the header of the responses holding the correlation
+ID of the error (see errstack.ID()), which is also logged with it.
+*/
+const CorrelationHeader = "X-Correlation-ID"
+
+/*
+WriteError() logs the full chain of the error, and responds with the
+package's Encoder. The correlation ID of the error, given to it by
+errstack.Identify() if it has none, is set as the CorrelationHeader of
+the response.
+*/
+func WriteError(w http.ResponseWriter, r *http.Request, err error) {
+	writeError(w, r, err, Encoder)
+}
+
+func writeError(w http.ResponseWriter, r *http.Request, err error, encoder ErrorEncoder) {
+	err = errstack.Identify(err)
+	logError(r, err)
+	if id := errstack.ID(err); id != "" {
+		w.Header().Set(CorrelationHeader, id)
+	}
+	if Propagate {
+		SetChain(w.Header(), err)
+	}
+	if encoder == nil {
+		encoder = Text
+	}
+	encoder(w, r, err)
+}
+
+// logError() logs the full chain of the error.
+func logError(r *http.Request, err error) {
+	logger := Logger
+	if logger == nil {
+		logger = slog.Default()
+	}
+	logger.ErrorContext(r.Context(), "request failed",
+		"method", r.Method,
+		"path", r.URL.Path,
+		"err", err,
+	)
+}
+
+/*
+Recover() returns a middleware that recovers the errors thrown by the
+handler (and any other panic), logs them, and responds with WriteError().
+If the handler already started writing the response, the error is only
+logged.
+
+Example:
+
+	mux.Handle("/orders/", errhttp.Recover(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		order := Throw(loadOrder(r.Context(), r.URL.Path))
+		json.NewEncoder(w).Encode(order)
+	})))
+*/
+func Recover(next http.Handler) http.Handler {
+	return HandlerWith(func(w http.ResponseWriter, r *http.Request) error {
+		next.ServeHTTP(w, r)
+		return nil
+	}, nil)
+}
+
+/*
+Handler() adapts a handler returning an error to an http.Handler. The
+handler may also Throw; either way, the error is logged and the response
+is written by the package's Encoder, unless the handler already started
+writing it.
+
+Example:
+
+	mux.Handle("/orders/", errhttp.Handler(func(w http.ResponseWriter, r *http.Request) error {
+		order, err := loadOrder(r.Context(), r.URL.Path)
+		if err != nil {
+			return err
+		}
+		return json.NewEncoder(w).Encode(order)
+	}))
+*/
+func Handler(f func(w http.ResponseWriter, r *http.Request) error) http.Handler {
+	return HandlerWith(f, nil)
+}
+
+/*
+HandlerWith() is like Handler(), but writes the error responses with the
+given ErrorEncoder instead of the package's Encoder (if it is not nil).
+*/
+func HandlerWith(f func(w http.ResponseWriter, r *http.Request) error, encoder ErrorEncoder) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		rw := &responseWriter{ResponseWriter: w}
+		err := serve(rw, r, f)
+		if err == nil {
+			return
+		}
+		if errors.Is(err, http.ErrAbortHandler) {
+			panic(http.ErrAbortHandler)
+		}
+		if rw.written {
+			logError(r, err)
+			return
+		}
+		if encoder == nil {
+			encoder = Encoder
+		}
+		writeError(w, r, err, encoder)
+	})
+}
+
+// serve() runs the handler, turning what it throws into an error.
+func serve(w http.ResponseWriter, r *http.Request, f func(w http.ResponseWriter, r *http.Request) error) (err error) {
+	defer errhandling.CatchPanic_(&err)
+	return f(w, r)
+}
+
+// responseWriter records whether the response was started.
+type responseWriter struct {
+	http.ResponseWriter
+	written bool
+}
+
+func (w *responseWriter) WriteHeader(status int) {
+	w.written = true
+	w.ResponseWriter.WriteHeader(status)
+}
+
+func (w *responseWriter) Write(b []byte) (int, error) {
+	w.written = true
+	return w.ResponseWriter.Write(b)
+}
+
+// Unwrap() lets http.ResponseController reach the underlying writer.
+func (w *responseWriter) Unwrap() http.ResponseWriter {
+	return w.ResponseWriter
+}
+
+// Flush() implements http.Flusher, for the handlers streaming their responses.
+func (w *responseWriter) Flush() {
+	w.written = true
+	_ = http.NewResponseController(w.ResponseWriter).Flush()
+}
+
+// Hijack() implements http.Hijacker, for the handlers taking the connection over.
+func (w *responseWriter) Hijack() (net.Conn, *bufio.ReadWriter, error) {
+	conn, rw, err := http.NewResponseController(w.ResponseWriter).Hijack()
+	if err == nil {
+		w.written = true
+	}
+	return conn, rw, err
+}
+
+// ReadFrom() implements io.ReaderFrom, so that http.ServeContent() and the like may use sendfile.
+func (w *responseWriter) ReadFrom(r io.Reader) (int64, error) {
+	w.written = true
+	return io.Copy(w.ResponseWriter, r)
+}
@@ -0,0 +1,173 @@
+package errhttp_test
+
+import (
+	"encoding/json"
+	"errors"
+	"io"
+	"log/slog"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+
+	. "github.com/the-zucc/errhandling"
+	errhttp "github.com/the-zucc/errhandling/err-http"
+	errstack "github.com/the-zucc/errhandling/err-stack"
+)
+
+func TestErrHttp(t *testing.T) {
+	RegisterFailHandler(Fail)
+	RunSpecs(t, "errhttp tests")
+}
+
+var _ = BeforeSuite(func() {
+	errhttp.Logger = slog.New(slog.NewTextHandler(io.Discard, nil))
+})
+
+var _ = Describe("errhttp tests", func() {
+	It("Recover() should turn a Throw into a response", func() {
+		handler := errhttp.Recover(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			Throw_(errstack.New("order exists", errors.New("duplicate key")).
+				With("http_status", http.StatusConflict).
+				WithPublicMessage("This order was already placed."))
+		}))
+		rec := httptest.NewRecorder()
+		handler.ServeHTTP(rec, httptest.NewRequest(http.MethodPost, "/orders", nil))
+		Expect(rec.Code).To(Equal(http.StatusConflict))
+		Expect(rec.Body.String()).To(Equal("This order was already placed.\n"))
+	})
+
+	It("Handler() should identify every occurrence of a sentinel error", func() {
+		errOrderMissing := errstack.NewCode("ORDER_MISSING", "order not found")
+		handler := errhttp.Handler(func(w http.ResponseWriter, r *http.Request) error {
+			return errOrderMissing
+		})
+		ids := []string{}
+		for i := 0; i < 2; i++ {
+			rec := httptest.NewRecorder()
+			handler.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/orders/42", nil))
+			ids = append(ids, rec.Header().Get(errhttp.CorrelationHeader))
+		}
+		Expect(ids[0]).To(MatchRegexp("^[0-9a-f]{16}$"))
+		Expect(ids[1]).NotTo(Equal(ids[0]))
+		Expect(errOrderMissing.ID()).To(Equal(""))
+	})
+
+	It("HandlerWith() should encode returned errors with the given encoder", func() {
+		handler := errhttp.HandlerWith(func(w http.ResponseWriter, r *http.Request) error {
+			return errstack.NewCode("ORDER_MISSING", "order not found").
+				With("http_status", http.StatusNotFound).
+				WithID("req-42")
+		}, errhttp.JSON)
+		rec := httptest.NewRecorder()
+		handler.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/orders/42", nil))
+		Expect(rec.Code).To(Equal(http.StatusNotFound))
+		Expect(rec.Header().Get("Content-Type")).To(Equal("application/json"))
+		Expect(rec.Header().Get(errhttp.CorrelationHeader)).To(Equal("req-42"))
+		Expect(rec.Body.String()).To(MatchJSON(`{"error": "Not Found", "code": "ORDER_MISSING", "correlation_id": "req-42", "status": 404}`))
+	})
+
+	It("NewProblem() should convert an error to Problem Details", func() {
+		err := errstack.NewCode("ORDER_EXISTS", "INSERT INTO orders failed", errors.New("duplicate key")).
+			WithPublicField("order_id", 42).
+			With("query", "INSERT INTO orders VALUES (42)").
+			With("http_status", http.StatusConflict).
+			WithPublicMessage("This order was already placed.").
+			WithID("req-42")
+		doc, jsonErr := json.Marshal(errhttp.NewProblem(err))
+		Expect(jsonErr).To(BeNil())
+		Expect(doc).To(MatchJSON(`{
+			"type": "about:blank",
+			"title": "Conflict",
+			"status": 409,
+			"detail": "This order was already placed.",
+			"code": "ORDER_EXISTS",
+			"correlation_id": "req-42",
+			"order_id": 42
+		}`))
+	})
+
+	It("Status() should consult the status mapping registry", func() {
+		errNotFound := errors.New("not found")
+		errhttp.MapStatus(errNotFound, http.StatusNotFound)
+		errhttp.MapCode("AUTH_*", http.StatusUnauthorized)
+		errhttp.MapCode("AUTH_FORBIDDEN", http.StatusForbidden)
+		Expect(errhttp.Status(errstack.New("load order", errNotFound))).To(Equal(http.StatusNotFound))
+		Expect(errhttp.Status(errstack.NewCode("AUTH_EXPIRED", "token expired"))).To(Equal(http.StatusUnauthorized))
+		Expect(errhttp.Status(errstack.NewCode("AUTH_FORBIDDEN", "not an admin"))).To(Equal(http.StatusForbidden))
+		Expect(errhttp.Status(errstack.New("oopsie"))).To(Equal(http.StatusInternalServerError))
+	})
+
+	It("ChainFrom() should reconstruct the chain propagated by the handler", func() {
+		errhttp.Propagate = true
+		defer func() { errhttp.Propagate = false }()
+		handler := errhttp.Handler(func(w http.ResponseWriter, r *http.Request) error {
+			return errstack.New("load stock", errstack.NewCode("DB_DOWN", "connect", errors.New("connection refused")))
+		})
+		rec := httptest.NewRecorder()
+		handler.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/stock", nil))
+		err, ok := errhttp.ChainFrom(rec.Result())
+		Expect(ok).To(BeTrue())
+		Expect(err.Error()).To(Equal("connection refused -> connect -> load stock"))
+		Expect(errstack.RootCode(err)).To(Equal("DB_DOWN"))
+	})
+
+	It("ThrowStatus() should throw a StatusError on non-2xx responses", func() {
+		server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			http.Error(w, "no such order", http.StatusNotFound)
+		}))
+		defer server.Close()
+		err := func() (e error) {
+			defer Catch_(&e)
+			errhttp.ThrowStatus(http.Get(server.URL + "/orders/42"))
+			return nil
+		}()
+		var statusErr *errhttp.StatusError
+		Expect(errors.As(err, &statusErr)).To(BeTrue())
+		Expect(statusErr.Method).To(Equal(http.MethodGet))
+		Expect(statusErr.Status).To(Equal(http.StatusNotFound))
+		Expect(string(statusErr.Body)).To(Equal("no such order\n"))
+	})
+
+	It("Transport should annotate and retry failed idempotent requests", func() {
+		failures := 0
+		client := &http.Client{Transport: &errhttp.Transport{
+			Base: roundTripperFunc(func(req *http.Request) (*http.Response, error) {
+				failures++
+				return nil, errors.New("connection refused")
+			}),
+			Retry: &RetryPolicy{MaxAttempts: 3},
+		}}
+		_, err := client.Get("http://inventory.internal/stock")
+		Expect(failures).To(Equal(3))
+		Expect(err.Error()).To(ContainSubstring("gave up after 3 attempts"))
+		host, _ := errstack.Field(err, "host")
+		Expect(host).To(Equal("inventory.internal"))
+		_, err = client.Post("http://inventory.internal/stock", "text/plain", nil)
+		Expect(failures).To(Equal(4))
+		attempt, _ := errstack.Field(err, "attempt")
+		Expect(attempt).To(Equal(1))
+	})
+	It("Recover() should let the handlers flush and hijack the response", func() {
+		hijackable := false
+		handler := errhttp.Recover(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			w.Write([]byte("chunk"))
+			w.(http.Flusher).Flush()
+			_, hijackable = w.(http.Hijacker)
+			_, _, err := http.NewResponseController(w).Hijack()
+			Expect(err).To(MatchError(http.ErrNotSupported))
+		}))
+		rec := httptest.NewRecorder()
+		handler.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/events", nil))
+		Expect(rec.Flushed).To(BeTrue())
+		Expect(hijackable).To(BeTrue())
+	})
+})
+
+type roundTripperFunc func(req *http.Request) (*http.Response, error)
+
+func (f roundTripperFunc) RoundTrip(req *http.Request) (*http.Response, error) {
+	return f(req)
+}
@@ -0,0 +1,115 @@
+package errhttp
+
+import (
+	"encoding/json"
+	"errors"
+	"net/http"
+
+	errstack "github.com/the-zucc/errhandling/err-stack"
+)
+
+/*
+Problem is an RFC 7807 Problem Details document. The extension members
+are encoded alongside the standard members.
+*/
+type Problem struct {
+	Type       string
+	Title      string
+	Status     int
+	Detail     string
+	Instance   string
+	Extensions map[string]any
+}
+
+/*
+ProblemTypeBase is the URI prefix of the "type" member of the problems
+built from coded errors: an error with the code "ORDER_EXISTS" gets the
+type ProblemTypeBase + "ORDER_EXISTS". Errors without a code, or all
+errors if ProblemTypeBase is empty, get the type "about:blank".
+*/
+var ProblemTypeBase string
+
+/*
+Debug makes NewProblem() add the whole cause chain, as encoded by
+errstack.Error's MarshalJSON(), in the "chain" extension member. It
+must never be enabled for public APIs.
+*/
+var Debug bool
+
+/*
+NewProblem() converts an error to a Problem: its status is Status(err),
+its detail is the public message of the error, and its code, correlation
+ID and public fields (see errstack.WithPublicField()) become extension
+members ("code", "correlation_id", and one member per field). The other
+fields, which may hold queries, hosts and the like, are left out, as is
+the "http_status" field, which is already the status.
+
+Example:
+
+	errstack.NewCode("ORDER_EXISTS", "INSERT INTO orders failed", err).
+		WithPublicField("order_id", 42).
+		With("query", query).
+		WithPublicMessage("This order was already placed.")
+
+becomes:
+
+	{
+	  "type": "about:blank",
+	  "title": "Conflict",
+	  "status": 409,
+	  "detail": "This order was already placed.",
+	  "code": "ORDER_EXISTS",
+	  "correlation_id": "9f86d081884c7d65",
+	  "order_id": 42
+	}
+*/
+func NewProblem(err error) Problem {
+	status := Status(err)
+	problem := Problem{
+		Type:       "about:blank",
+		Title:      http.StatusText(status),
+		Status:     status,
+		Detail:     errstack.PublicMessage(err),
+		Extensions: map[string]any{},
+	}
+	if id := errstack.ID(err); id != "" {
+		problem.Extensions["correlation_id"] = id
+	}
+	for _, field := range errstack.PublicFields(err) {
+		if field.Key != "http_status" {
+			problem.Extensions[field.Key] = field.Value
+		}
+	}
+	if code := errstack.Code(err); code != "" {
+		problem.Extensions["code"] = code
+		if ProblemTypeBase != "" {
+			problem.Type = ProblemTypeBase + code
+		}
+	}
+	var se errstack.Error
+	if Debug && errors.As(err, &se) {
+		problem.Extensions["chain"] = se
+	}
+	return problem
+}
+
+/*
+MarshalJSON() encodes the problem, with its extension members at the top
+level. Extension members cannot override the standard members.
+*/
+func (p Problem) MarshalJSON() ([]byte, error) {
+	doc := make(map[string]any, len(p.Extensions)+5)
+	for key, value := range p.Extensions {
+		doc[key] = value
+	}
+	doc["type"] = p.Type
+	doc["title"] = p.Title
+	doc["status"] = p.Status
+	if p.Detail != "" {
+		doc["detail"] = p.Detail
+	}
+	if p.Instance != "" {
+		doc["instance"] = p.Instance
+	}
+	return json.Marshal(doc)
+}
@@ -0,0 +1,70 @@
+package errhttp
+
+import (
+	"net/http"
+
+	errstack "github.com/the-zucc/errhandling/err-stack"
+)
+
+/*
+ChainHeader is the name of the header, or trailer, holding the chain of
+an error encoded with errstack.Serialize().
+*/
+var ChainHeader = "Errstack-Chain"
+
+/*
+Propagate makes WriteError(), Recover() and Handler() set the chain of
+the errors in the ChainHeader of the responses, so that the calling
+services can reconstruct it with ChainFrom(). It must only be enabled
+between trusted services, as the chain exposes internal details.
+*/
+var Propagate bool
+
+/*
+SetChain() sets the chain of the error in the ChainHeader of the header.
+*/
+func SetChain(header http.Header, err error) {
+	if err != nil {
+		header.Set(ChainHeader, string(errstack.Serialize(err)))
+	}
+}
+
+/*
+SetChainTrailer() sets the chain of the error in the ChainHeader trailer
+of the response, which works even after the response was started, e.g.
+when a streaming handler fails halfway through.
+*/
+func SetChainTrailer(w http.ResponseWriter, err error) {
+	if err != nil {
+		w.Header().Set(http.TrailerPrefix+ChainHeader, string(errstack.Serialize(err)))
+	}
+}
+
+/*
+ChainFrom() reconstructs the error whose chain is in the ChainHeader of
+the response's header or trailer, and returns false if there is none or
+it cannot be parsed. Trailers are only available once the body has been
+read entirely.
+
+Example:
+
+	resp, err := http.Get(url)
+	...
+	if upstream, ok := errhttp.ChainFrom(resp); ok {
+		Throw_(errstack.New("fetch inventory", upstream))
+	}
+*/
+func ChainFrom(resp *http.Response) (error, bool) {
+	chain := resp.Header.Get(ChainHeader)
+	if chain == "" {
+		chain = resp.Trailer.Get(ChainHeader)
+	}
+	if chain == "" {
+		return nil, false
+	}
+	err, parseErr := errstack.Parse([]byte(chain))
+	if parseErr != nil {
+		return nil, false
+	}
+	return err, true
+}
@@ -0,0 +1,125 @@
+package errhttp
+
+import (
+	"errors"
+	"net/http"
+	"strings"
+	"sync"
+
+	errstack "github.com/the-zucc/errhandling/err-stack"
+)
+
+/*
+DefaultStatus is the status of the errors that Status() cannot map.
+*/
+var DefaultStatus = http.StatusInternalServerError
+
+var (
+	statusMu sync.RWMutex
+	// codes maps exact codes, and code prefixes ending with "*"
+	codes = map[string]int{}
+	// rules holds the sentinel and type rules, in registration order
+	rules []statusRule
+)
+
+type statusRule struct {
+	match  func(err error) bool
+	status int
+}
+
+/*
+MapStatus() maps the errors matching the sentinel error (as per
+errors.Is()) to the HTTP status.
+
+Example:
+
+	errhttp.MapStatus(sql.ErrNoRows, http.StatusNotFound)
+*/
+func MapStatus(target error, status int) {
+	statusMu.Lock()
+	defer statusMu.Unlock()
+	rules = append(rules, statusRule{
+		match:  func(err error) bool { return errors.Is(err, target) },
+		status: status,
+	})
+}
+
+/*
+MapType() maps the errors whose chain holds an error of type T (as per
+errors.As()) to the HTTP status.
+
+Example:
+
+	errhttp.MapType[*json.SyntaxError](http.StatusBadRequest)
+*/
+func MapType[T error](status int) {
+	statusMu.Lock()
+	defer statusMu.Unlock()
+	rules = append(rules, statusRule{
+		match: func(err error) bool {
+			var target T
+			return errors.As(err, &target)
+		},
+		status: status,
+	})
+}
+
+/*
+MapCode() maps the errors with the code to the HTTP status. A code
+ending with "*" is a wildcard matching all the codes with that prefix;
+exact codes win over wildcards, and longer prefixes over shorter ones.
+
+Example:
+
+	errhttp.MapCode("AUTH_*", http.StatusUnauthorized)
+	errhttp.MapCode("AUTH_FORBIDDEN", http.StatusForbidden)
+*/
+func MapCode(code string, status int) {
+	statusMu.Lock()
+	defer statusMu.Unlock()
+	codes[code] = status
+}
+
+/*
+Status() returns the HTTP status for the error. It is, in this order:
+the "http_status" field of its chain if it has one (errors built from an
+errcatalog.Catalog do), the status mapped to its code with MapCode(), the
+status of the first matching MapStatus() or MapType() rule, and finally
+DefaultStatus.
+*/
+func Status(err error) int {
+	if status, ok := errstack.Field(err, "http_status"); ok {
+		if status, ok := status.(int); ok {
+			return status
+		}
+	}
+	statusMu.RLock()
+	defer statusMu.RUnlock()
+	if status, ok := codeStatus(errstack.Code(err)); ok {
+		return status
+	}
+	for _, rule := range rules {
+		if rule.match(err) {
+			return rule.status
+		}
+	}
+	return DefaultStatus
+}
+
+// codeStatus() looks the code up, then its longest matching wildcard.
+func codeStatus(code string) (int, bool) {
+	if code == "" {
+		return 0, false
+	}
+	if status, ok := codes[code]; ok {
+		return status, true
+	}
+	status, longest := 0, -1
+	for pattern, s := range codes {
+		prefix, ok := strings.CutSuffix(pattern, "*")
+		if ok && len(prefix) > longest && strings.HasPrefix(code, prefix) {
+			status, longest = s, len(prefix)
+		}
+	}
+	return status, longest >= 0
+}
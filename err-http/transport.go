@@ -0,0 +1,87 @@
+package errhttp
+
+import (
+	"net/http"
+	"time"
+
+	"github.com/the-zucc/errhandling"
+	errstack "github.com/the-zucc/errhandling/err-stack"
+)
+
+/*
+Transport is an http.RoundTripper wrapping the failures of its Base
+transport (http.DefaultTransport if nil) into stacked errors with the
+"method", "host", "attempt" and "elapsed" fields. If Retry is set, the
+idempotent requests are retried as per the policy; the other requests
+are never retried.
+
+Example:
+
+	client := &http.Client{Transport: &errhttp.Transport{
+		Retry: &errhandling.RetryPolicy{
+			MaxAttempts: 3,
+			Backoff:     errhandling.ExponentialBackoff{Initial: 100 * time.Millisecond},
+		},
+	}}
+*/
+type Transport struct {
+	Base  http.RoundTripper
+	Retry *errhandling.RetryPolicy
+}
+
+func (t *Transport) RoundTrip(req *http.Request) (*http.Response, error) {
+	if t.Retry == nil || !replayable(req) {
+		return t.attempt(req, 1)
+	}
+	attempt := 0
+	return errhandling.Retry(req.Context(), *t.Retry, func() (*http.Response, error) {
+		attempt++
+		if attempt == 1 {
+			return t.attempt(req, attempt)
+		}
+		retry := req.Clone(req.Context())
+		if req.Body != nil && req.Body != http.NoBody {
+			body, err := req.GetBody()
+			if err != nil {
+				return nil, errstack.New("could not replay the request body", err)
+			}
+			retry.Body = body
+		}
+		return t.attempt(retry, attempt)
+	})
+}
+
+// attempt() sends the request once, wrapping the failure if any.
+func (t *Transport) attempt(req *http.Request, attempt int) (*http.Response, error) {
+	base := t.Base
+	if base == nil {
+		base = http.DefaultTransport
+	}
+	start := time.Now()
+	resp, err := base.RoundTrip(req)
+	if err != nil {
+		return nil, errstack.New("round trip failed", err).
+			With("method", req.Method).
+			With("host", req.URL.Host).
+			With("attempt", attempt).
+			With("elapsed", time.Since(start))
+	}
+	return resp, nil
+}
+
+/*
+replayable() tells whether the request is idempotent, the same way
+net/http does, and whether its body can be sent again.
+*/
+func replayable(req *http.Request) bool {
+	switch req.Method {
+	case "", http.MethodGet, http.MethodHead, http.MethodOptions, http.MethodTrace, http.MethodPut, http.MethodDelete:
+	default:
+		if _, ok := req.Header["Idempotency-Key"]; !ok {
+			if _, ok := req.Header["X-Idempotency-Key"]; !ok {
+				return false
+			}
+		}
+	}
+	return req.Body == nil || req.Body == http.NoBody || req.GetBody != nil
+}
@@ -0,0 +1,143 @@
+package errlint
+
+import (
+	"go/ast"
+	"go/token"
+	"go/types"
+
+	"golang.org/x/tools/go/analysis"
+	"golang.org/x/tools/go/analysis/passes/inspect"
+)
+
+/*
+CatchReturns reports the deferred calls to Catch() (or Catch_(),
+CatchPanic(), ...) that are passed the addresses of the local variables
+of a function returning results, rather than of its named return
+values, as what is caught is then silently dropped:
+
+	func load() (string, error) {
+		var err error
+		defer errhandling.Catch_(&err) // the caller gets a nil error
+		...
+	}
+
+The addresses of the variables captured from an enclosing function, of
+struct fields and of the results of an enclosing function are accepted,
+as the code around the deferring function may read them afterwards.
+
+It also reports the functions returning a value along with their error
+that defer the error-only Catch_() (or CatchPanic_(), ...), which leave
+the value returned unset.
+*/
+var CatchReturns = &analysis.Analyzer{
+	Name:     "catchreturns",
+	Doc:      "report deferred Catch calls not given the addresses of the named return values",
+	Requires: []*analysis.Analyzer{inspect.Analyzer},
+	Run:      runCatchReturns,
+}
+
+func runCatchReturns(pass *analysis.Pass) (any, error) {
+	for _, file := range pass.Files {
+		// the stack of the enclosing functions
+		var stack []enclosing
+		var nodes []ast.Node
+		ast.Inspect(file, func(n ast.Node) bool {
+			if n == nil {
+				last := nodes[len(nodes)-1]
+				nodes = nodes[:len(nodes)-1]
+				switch last.(type) {
+				case *ast.FuncDecl, *ast.FuncLit:
+					stack = stack[:len(stack)-1]
+				}
+				return true
+			}
+			nodes = append(nodes, n)
+			switch n := n.(type) {
+			case *ast.FuncDecl:
+				sig, _ := pass.TypesInfo.Defs[n.Name].Type().(*types.Signature)
+				stack = append(stack, enclosing{n, sig})
+			case *ast.FuncLit:
+				sig, _ := pass.TypesInfo.TypeOf(n).(*types.Signature)
+				stack = append(stack, enclosing{n, sig})
+			case *ast.DeferStmt:
+				if len(stack) > 0 && stack[len(stack)-1].sig != nil && isCatch(pass.TypesInfo, n.Call) {
+					checkCatch(pass, stack[len(stack)-1], n.Call)
+				}
+			}
+			return true
+		})
+	}
+	return nil, nil
+}
+
+// enclosing is a function declaration or literal, with its signature.
+type enclosing struct {
+	node ast.Node
+	sig  *types.Signature
+}
+
+// checkCatch() reports the misuses of a Catch function deferred by fn.
+func checkCatch(pass *analysis.Pass, fn enclosing, call *ast.CallExpr) {
+	sig := fn.sig
+	catch := callee(pass.TypesInfo, call)
+	params := catch.Type().(*types.Signature).Params()
+	hasVal := false
+	for i := 0; i < params.Len() && i < len(call.Args); i++ {
+		switch params.At(i).Name() {
+		case "valAddr":
+			hasVal = true
+		case "errAddr":
+		default:
+			continue
+		}
+		if isLocalAddr(pass.TypesInfo, fn, call.Args[i]) {
+			pass.Reportf(call.Args[i].Pos(), "%s() is not passed the address of a named return value, what it catches is dropped", catch.Name())
+		}
+	}
+	if !hasVal && returnsValue(sig) {
+		pass.Reportf(call.Pos(), "%s() leaves the value returned unset, use the Catch function taking its address", catch.Name())
+	}
+}
+
+/*
+isLocalAddr() reports whether the expression is the address of a
+variable declared by the function, other than its results, when the
+function has results: nothing reads such a variable once the deferred
+calls have run. The variables of the enclosing functions, and anything
+else than a variable, may still be read afterwards.
+*/
+func isLocalAddr(info *types.Info, fn enclosing, expr ast.Expr) bool {
+	results := fn.sig.Results()
+	if results.Len() == 0 {
+		return false
+	}
+	unary, ok := ast.Unparen(expr).(*ast.UnaryExpr)
+	if !ok || unary.Op != token.AND {
+		return false
+	}
+	ident, ok := ast.Unparen(unary.X).(*ast.Ident)
+	if !ok {
+		return false
+	}
+	obj, ok := info.Uses[ident].(*types.Var)
+	if !ok || obj.Pos() < fn.node.Pos() || obj.Pos() >= fn.node.End() {
+		return false
+	}
+	for i := 0; i < results.Len(); i++ {
+		if obj == results.At(i) {
+			return false
+		}
+	}
+	return true
+}
+
+// returnsValue() reports whether the function returns more than an error.
+func returnsValue(sig *types.Signature) bool {
+	results := sig.Results()
+	for i := 0; i < results.Len(); i++ {
+		if !types.Identical(results.At(i).Type(), types.Universe.Lookup("error").Type()) {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,15 @@
+/*
+errlint runs the analyzers of the errlint package:
+
+	go vet -vettool=$(which errlint) ./...
+*/
+package main
+
+import (
+	errlint "github.com/the-zucc/errhandling/err-lint"
+	"golang.org/x/tools/go/analysis/multichecker"
+)
+
+func main() {
+	multichecker.Main(errlint.ThrowCatch, errlint.CatchReturns)
+}
@@ -0,0 +1,27 @@
+package errlint_test
+
+import (
+	"testing"
+
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+	"golang.org/x/tools/go/analysis/analysistest"
+
+	errlint "github.com/the-zucc/errhandling/err-lint"
+)
+
+func TestErrLint(t *testing.T) {
+	RegisterFailHandler(Fail)
+	RunSpecs(t, "errlint tests")
+}
+
+var _ = Describe("errlint tests", func() {
+	It("ThrowCatch should report throws without a deferred Catch", func() {
+		results := analysistest.Run(GinkgoT(), analysistest.TestData(), errlint.ThrowCatch, "throwcatch")
+		Expect(results).To(HaveLen(1))
+	})
+	It("CatchReturns should report Catch calls not given the named return values", func() {
+		results := analysistest.Run(GinkgoT(), analysistest.TestData(), errlint.CatchReturns, "catchreturns")
+		Expect(results).To(HaveLen(1))
+	})
+})
@@ -0,0 +1,77 @@
+package catchreturns
+
+import (
+	"errors"
+
+	. "github.com/the-zucc/errhandling"
+)
+
+func load() (string, error) { return "", errors.New("oopsie") }
+
+func named() (s string, e error) {
+	defer Catch(&s, &e)
+	return Throw(load()), nil
+}
+
+func errorOnly() (e error) {
+	defer Catch_(&e)
+	Throw_(errors.New("oopsie"))
+	return nil
+}
+
+func local() error {
+	var err error
+	defer Catch_(&err) // want `Catch_\(\) is not passed the address of a named return value, what it catches is dropped`
+	Throw_(errors.New("oopsie"))
+	return err
+}
+
+func swapped() (s string, e error) {
+	var other string
+	defer Catch(&other, &e) // want `Catch\(\) is not passed the address of a named return value, what it catches is dropped`
+	return Throw(load()), nil
+}
+
+func dropsValue() (s string, e error) {
+	defer Catch_(&e) // want `Catch_\(\) leaves the value returned unset, use the Catch function taking its address`
+	return Throw(load()), nil
+}
+
+func scoped() (e error) {
+	s := &Scope{}
+	defer s.Catch_(&e)
+	func() (inner error) {
+		defer CatchPanic_(&e)
+		return nil
+	}()
+	return nil
+}
+
+type handle struct {
+	err error
+}
+
+func captured() error {
+	var err error
+	h := &handle{}
+	func() {
+		defer CatchPanic_(&err)
+		Throw_(errors.New("oopsie"))
+	}()
+	func() (inner error) {
+		defer CatchPanic_(&h.err)
+		return nil
+	}()
+	if h.err != nil {
+		return h.err
+	}
+	return err
+}
+
+func localInLiteral() {
+	func() error {
+		var err error
+		defer CatchPanic_(&err) // want `CatchPanic_\(\) is not passed the address of a named return value, what it catches is dropped`
+		return err
+	}()
+}
@@ -0,0 +1,16 @@
+// Package errhttp is a stub of the errhttp package for the tests.
+package errhttp
+
+import (
+	"net/http"
+
+	. "github.com/the-zucc/errhandling"
+)
+
+// ThrowStatus() throws the error of the response. errlint:throws
+func ThrowStatus(status int) {
+	ThrowIf(status >= 400, nil)
+}
+
+// Recover() catches what the handler throws.
+func Recover(next http.Handler) http.Handler { return next }
@@ -0,0 +1,21 @@
+// Package errhandling is a stub of the errhandling package for the tests.
+package errhandling
+
+func Catch[T any](valAddr *T, errAddr *error) {}
+func Catch_(errAddr *error)                   {}
+func HandleMain()                             {}
+func Throw[T any](val T, err error) T         { return val }
+func Throw_(err error)                        {}
+func ThrowIf(cond bool, err error)            {}
+func Assert(cond bool, msg string)            {}
+func Return[T any](val T, err error)          {}
+func Must[T any](val T, err error) T          { return val }
+func Go(f func())                             {}
+func OnReport(f func(Event)) (remove func())  { return nil }
+func CatchPanic_(errAddr *error)              {}
+
+type Scope struct{}
+
+func (s *Scope) Catch_(errAddr *error) {}
+
+type Event struct{ Err error }
@@ -0,0 +1,61 @@
+package throwcatch
+
+import (
+	"errors"
+	"net/http"
+
+	. "github.com/the-zucc/errhandling"
+	errhttp "github.com/the-zucc/errhandling/err-http"
+)
+
+func load() (string, error) { return "", errors.New("oopsie") }
+
+func caught() (s string, e error) {
+	defer Catch(&s, &e)
+	s = Throw(load())
+	func() {
+		Return(load()) // runs inline, caught by the enclosing function
+	}()
+	return s, nil
+}
+
+func uncaught() string {
+	return Throw(load()) // want `Throw\(\) is called in a function without a deferred Catch\(\)`
+}
+
+func goroutine() (e error) {
+	defer Catch_(&e)
+	go func() {
+		Throw_(errors.New("oopsie")) // want `Throw_\(\) is called in a function without a deferred Catch\(\)`
+	}()
+	Go(func() {
+		Throw_(errors.New("oopsie")) // caught by Go()
+	})
+	return nil
+}
+
+func main() {
+	defer HandleMain()
+	Must(load())
+}
+
+// mustLoad() throws to its callers. errlint:throws
+func mustLoad() string { // want mustLoad:"throws"
+	return Throw(load())
+}
+
+func helpers(status int) {
+	ThrowIf(status >= 500, errors.New("oopsie")) // want `ThrowIf\(\) is called in a function without a deferred Catch\(\)`
+	Assert(status > 0, "no status")              // want `Assert\(\) is called in a function without a deferred Catch\(\)`
+	errhttp.ThrowStatus(status)                  // want `ThrowStatus\(\) is called in a function without a deferred Catch\(\)`
+	mustLoad()                                   // want `mustLoad\(\) is called in a function without a deferred Catch\(\)`
+}
+
+func handlers() http.Handler {
+	OnReport(func(ev Event) {
+		Throw_(ev.Err) // want `Throw_\(\) is called in a function without a deferred Catch\(\)`
+	})
+	return errhttp.Recover(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		Throw(load()) // caught by errhttp.Recover()
+	}))
+}
@@ -0,0 +1,288 @@
+/*
+Package errlint provides go/analysis analyzers catching the misuses of
+the errhandling package that turn into crashes or silently dropped
+errors.
+*/
+package errlint
+
+import (
+	"go/ast"
+	"go/types"
+	"strings"
+
+	"golang.org/x/tools/go/analysis"
+	"golang.org/x/tools/go/analysis/passes/inspect"
+	"golang.org/x/tools/go/types/typeutil"
+)
+
+const (
+	rootPkg   = "github.com/the-zucc/errhandling"
+	directive = "errlint:throws"
+)
+
+/*
+these functions of the root package throw up the call stack; the other
+throwing functions, in its subpackages or elsewhere, are marked with an
+"errlint:throws" comment (see throwsFact)
+*/
+var throwing = map[string]bool{
+	"Throw":       true,
+	"Throw_":      true,
+	"ThrowIf":     true,
+	"ThrowUnless": true,
+	"Return":      true,
+	"Return_":     true,
+	"Must":        true,
+	"Must_":       true,
+	"RetryThrow":  true,
+	"ThrowIfDone": true,
+	"CloseThrow":  true,
+	"Assert":      true,
+	"DebugAssert": true,
+}
+
+/*
+throwsFact marks the functions meant to throw to their callers, those
+with an "errlint:throws" comment in their documentation, so that their
+calls are checked like the calls to Throw() in the packages importing
+them.
+*/
+type throwsFact struct{}
+
+func (*throwsFact) AFact() {}
+
+func (*throwsFact) String() string { return "throws" }
+
+// these functions of the root package intercept what is thrown
+var catching = map[string]bool{
+	"Catch":             true,
+	"Catch_":            true,
+	"CatchPanic":        true,
+	"CatchPanic_":       true,
+	"CatchWith":         true,
+	"CatchWith_":        true,
+	"CatchWithFinally":  true,
+	"CatchWithFinally_": true,
+	"CatchScope":        true,
+	"HandleMain":        true,
+}
+
+/*
+these functions and methods (by their full name) run the anonymous
+functions passed to them under a Catch function, in the module and in
+its integrations
+*/
+var catchingCalls = map[string]bool{
+	rootPkg + ".Try":                    true,
+	rootPkg + ".Go":                     true,
+	rootPkg + ".Async":                  true,
+	rootPkg + ".All":                    true,
+	rootPkg + ".Any":                    true,
+	rootPkg + ".Race":                   true,
+	rootPkg + ".Retry":                  true,
+	rootPkg + ".Retry_":                 true,
+	rootPkg + ".RetryThrow":             true,
+	rootPkg + ".Execute":                true,
+	rootPkg + ".Execute_":               true,
+	rootPkg + ".WithTimeout":            true,
+	rootPkg + ".WithTimeout_":           true,
+	rootPkg + ".CaptureThrow":           true,
+	rootPkg + ".WithHandler":            true,
+	rootPkg + ".WithHandler_":           true,
+	rootPkg + ".OnShutdown":             true,
+	"(*" + rootPkg + ".Pool).Submit":    true,
+	"(*" + rootPkg + ".Group).Go":       true,
+	"(*" + rootPkg + ".Scope).Go":       true,
+	"(*" + rootPkg + ".Supervisor).Add": true,
+	rootPkg + "/err-http.Handler":       true,
+	rootPkg + "/err-http.HandlerWith":   true,
+	rootPkg + "/err-http.Recover":       true,
+	rootPkg + "/err-cli.Wrap":           true,
+	rootPkg + "/err-sql.WithTx":         true,
+	rootPkg + "/err-sql.WithTxOptions":  true,
+	rootPkg + "/err-consumer.Wrap":      true,
+	rootPkg + "/err-test.AssertThrows":  true,
+}
+
+/*
+ThrowCatch reports the calls to Throw(), Return(), Must() and the like
+in functions without a deferred Catch() (or Catch_(), CatchPanic(),
+HandleMain(), ...), which crash the process instead of returning the
+error.
+
+Anonymous functions are covered by the Catch() of the functions they
+are written in, as they usually run inline, unless they are started as
+goroutines. Anonymous functions passed to the functions of this module
+that catch what they throw (Try(), Go(), Retry(), errhttp.Handler(),
+...), possibly converted first (as with http.HandlerFunc()), are
+covered; those passed to the other ones, such as OnReport(), are not. Functions meant to throw to their callers can be
+marked with an "errlint:throws" comment in their documentation: their
+calls are then reported like those to Throw(), in their package and in
+the packages importing it (such as errhttp.ThrowStatus()).
+*/
+var ThrowCatch = &analysis.Analyzer{
+	Name:      "throwcatch",
+	Doc:       "report Throw, Return and Must calls without an enclosing deferred Catch",
+	Requires:  []*analysis.Analyzer{inspect.Analyzer},
+	Run:       runThrowCatch,
+	FactTypes: []analysis.Fact{(*throwsFact)(nil)},
+}
+
+func runThrowCatch(pass *analysis.Pass) (any, error) {
+	for _, file := range pass.Files {
+		for _, decl := range file.Decls {
+			if fd, ok := decl.(*ast.FuncDecl); ok && hasDirective(fd.Doc) {
+				if fn, ok := pass.TypesInfo.Defs[fd.Name].(*types.Func); ok {
+					pass.ExportObjectFact(fn, &throwsFact{})
+				}
+			}
+		}
+	}
+	for _, file := range pass.Files {
+		// isolated holds the anonymous functions that do not run inline
+		isolated := map[*ast.FuncLit]bool{}
+		// covered holds the anonymous functions whose throws are caught
+		covered := map[*ast.FuncLit]bool{}
+		ast.Inspect(file, func(n ast.Node) bool {
+			switch n := n.(type) {
+			case *ast.GoStmt:
+				if lit, ok := n.Call.Fun.(*ast.FuncLit); ok {
+					isolated[lit] = true
+				}
+			case *ast.CallExpr:
+				if fn := callee(pass.TypesInfo, n); fn != nil && catchingCalls[fn.FullName()] {
+					for _, arg := range n.Args {
+						if lit, ok := funcLit(pass.TypesInfo, arg); ok {
+							covered[lit] = true
+						}
+					}
+				}
+			}
+			return true
+		})
+		// the stack of the enclosing functions, and whether they catch
+		var stack []bool
+		var nodes []ast.Node
+		ast.Inspect(file, func(n ast.Node) bool {
+			if n == nil {
+				last := nodes[len(nodes)-1]
+				nodes = nodes[:len(nodes)-1]
+				if _, ok := last.(*ast.FuncDecl); ok {
+					stack = stack[:len(stack)-1]
+				} else if _, ok := last.(*ast.FuncLit); ok {
+					stack = stack[:len(stack)-1]
+				}
+				return true
+			}
+			nodes = append(nodes, n)
+			switch n := n.(type) {
+			case *ast.FuncDecl:
+				stack = append(stack, hasDirective(n.Doc) || defersCatch(pass.TypesInfo, n.Body))
+			case *ast.FuncLit:
+				caught := covered[n] || defersCatch(pass.TypesInfo, n.Body)
+				if !isolated[n] && len(stack) > 0 {
+					caught = caught || stack[len(stack)-1]
+				}
+				stack = append(stack, caught)
+			case *ast.CallExpr:
+				fn := callee(pass.TypesInfo, n)
+				if !throws(pass, fn) {
+					return true
+				}
+				if len(stack) > 0 && !stack[len(stack)-1] {
+					pass.Reportf(n.Pos(), "%s() is called in a function without a deferred Catch()", fn.Name())
+				}
+			}
+			return true
+		})
+	}
+	return nil, nil
+}
+
+// callee() returns the function called, or nil if it is not static.
+func callee(info *types.Info, call *ast.CallExpr) *types.Func {
+	fn, _ := typeutil.Callee(info, call).(*types.Func)
+	if fn == nil {
+		return nil
+	}
+	if fn.Origin() != nil {
+		fn = fn.Origin()
+	}
+	return fn
+}
+
+/*
+funcLit() returns the anonymous function of the expression, seeing
+through the parentheses and the type conversions around it.
+*/
+func funcLit(info *types.Info, expr ast.Expr) (*ast.FuncLit, bool) {
+	for {
+		switch e := ast.Unparen(expr).(type) {
+		case *ast.FuncLit:
+			return e, true
+		case *ast.CallExpr:
+			if tv, ok := info.Types[e.Fun]; !ok || !tv.IsType() || len(e.Args) != 1 {
+				return nil, false
+			}
+			expr = e.Args[0]
+		default:
+			return nil, false
+		}
+	}
+}
+
+// throws() reports whether the function throws to its callers.
+func throws(pass *analysis.Pass, fn *types.Func) bool {
+	if fn == nil || fn.Pkg() == nil {
+		return false
+	}
+	if fn.Pkg().Path() == rootPkg && throwing[fn.Name()] {
+		return true
+	}
+	return pass.ImportObjectFact(fn, &throwsFact{})
+}
+
+/*
+isCatch() reports whether the call is to a Catch function, or to the
+Catch_() method of Scope.
+*/
+func isCatch(info *types.Info, call *ast.CallExpr) bool {
+	fn := callee(info, call)
+	return fn != nil && fn.Pkg() != nil && fn.Pkg().Path() == rootPkg && catching[fn.Name()]
+}
+
+/*
+defersCatch() reports whether the body defers a Catch function, leaving
+out the anonymous functions it contains.
+*/
+func defersCatch(info *types.Info, body *ast.BlockStmt) bool {
+	if body == nil {
+		return false
+	}
+	found := false
+	ast.Inspect(body, func(n ast.Node) bool {
+		switch n := n.(type) {
+		case *ast.FuncLit:
+			return false
+		case *ast.DeferStmt:
+			if isCatch(info, n.Call) {
+				found = true
+			}
+		}
+		return !found
+	})
+	return found
+}
+
+// hasDirective() reports whether the doc comment marks a throwing helper.
+func hasDirective(doc *ast.CommentGroup) bool {
+	if doc == nil {
+		return false
+	}
+	for _, comment := range doc.List {
+		if strings.Contains(comment.Text, directive) {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,191 @@
+/*
+Package errproto defines the errhandling.v1.Error protobuf message (see
+error.proto), which carries an error and its whole cause chain with full
+fidelity, for gRPC details and event payloads, and converts errors to
+and from it.
+*/
+package errproto
+
+// error.pb.go is generated from the root of the repository, its "source" path being relative to it
+//go:generate protoc --proto_path=.. --go_out=.. --go_opt=paths=source_relative err-proto/error.proto
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"sort"
+	"strings"
+
+	errstack "github.com/the-zucc/errhandling/err-stack"
+	"google.golang.org/protobuf/types/known/structpb"
+)
+
+/*
+This is the JSON document of an error encoded by errstack, through which
+the errors are converted, so that the messages keep the same content.
+*/
+type document struct {
+	Message       string           `json:"message"`
+	ID            string           `json:"id,omitempty"`
+	External      bool             `json:"external,omitempty"`
+	Code          string           `json:"code,omitempty"`
+	Severity      string           `json:"severity,omitempty"`
+	PublicMessage string           `json:"public_message,omitempty"`
+	Retryable     bool             `json:"retryable,omitempty"`
+	Timeout       bool             `json:"timeout,omitempty"`
+	Fields        map[string]any   `json:"fields,omitempty"`
+	Frames        []errstack.Frame `json:"frames,omitempty"`
+	Cause         *document        `json:"cause,omitempty"`
+	Causes        []*document      `json:"causes,omitempty"`
+}
+
+/*
+ToProto() converts any error and its whole cause chain to an Error
+message, or returns nil if the error is nil. Outside errors of the chain
+only carry their message, and the values of the fields are converted as
+they are encoded in JSON.
+
+Example:
+
+	payload, err := proto.Marshal(errproto.ToProto(failure))
+*/
+func ToProto(err error) *Error {
+	if err == nil {
+		return nil
+	}
+	se, ok := err.(errstack.Error)
+	if !ok {
+		// an outside error, possibly wrapping a stacked one
+		msg := &Error{Message: errstack.Redact(err.Error()), External: true}
+		if cause := errors.Unwrap(err); cause != nil {
+			msg.Causes = []*Error{ToProto(cause)}
+		}
+		return msg
+	}
+	data, jsonErr := se.MarshalJSON()
+	doc := &document{}
+	if jsonErr != nil || json.Unmarshal(data, doc) != nil {
+		// some field value cannot be encoded, fall back to the message
+		return &Error{Message: se.Error(), External: true}
+	}
+	return fromDocument(doc)
+}
+
+// this converts the JSON document of an error of the chain to a message
+func fromDocument(doc *document) *Error {
+	msg := &Error{
+		Message:       doc.Message,
+		External:      doc.External,
+		Code:          doc.Code,
+		Id:            doc.ID,
+		Severity:      Severity(Severity_value["SEVERITY_"+strings.ToUpper(doc.Severity)]),
+		PublicMessage: doc.PublicMessage,
+		Retryable:     doc.Retryable,
+		Timeout:       doc.Timeout,
+	}
+	keys := make([]string, 0, len(doc.Fields))
+	for key := range doc.Fields {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+	for _, key := range keys {
+		value, err := structpb.NewValue(doc.Fields[key])
+		if err != nil {
+			value = structpb.NewStringValue(fmt.Sprint(doc.Fields[key]))
+		}
+		msg.Fields = append(msg.Fields, &Field{Key: key, Value: value})
+	}
+	for _, frame := range doc.Frames {
+		msg.Frames = append(msg.Frames, &Frame{Function: frame.Function, File: frame.File, Line: int64(frame.Line)})
+	}
+	if doc.Cause != nil {
+		msg.Causes = []*Error{fromDocument(doc.Cause)}
+	}
+	for _, cause := range doc.Causes {
+		msg.Causes = append(msg.Causes, fromDocument(cause))
+	}
+	return msg
+}
+
+/*
+FromProto() converts an Error message back to an error, or returns nil
+if the message is nil. The errors of the chain get back their messages,
+codes, severities, public messages, classification flags, fields and
+call stacks; the external ones are plain errors carrying their message.
+*/
+func FromProto(msg *Error) error {
+	if msg == nil {
+		return nil
+	}
+	if msg.GetExternal() {
+		// an outside error keeps its own message, whatever its causes
+		return external{msg: msg.GetMessage(), cause: FromProto(firstCause(msg))}
+	}
+	data, err := json.Marshal(toDocument(msg))
+	if err != nil {
+		return errors.New(msg.GetMessage())
+	}
+	decoded, err := errstack.Decode(data)
+	if err != nil {
+		return errors.New(msg.GetMessage())
+	}
+	return decoded
+}
+
+// this converts a message of the chain to its JSON document
+func toDocument(msg *Error) *document {
+	doc := &document{
+		Message:       msg.GetMessage(),
+		ID:            msg.GetId(),
+		Code:          msg.GetCode(),
+		PublicMessage: msg.GetPublicMessage(),
+		Retryable:     msg.GetRetryable(),
+		Timeout:       msg.GetTimeout(),
+	}
+	if msg.GetSeverity() != Severity_SEVERITY_UNSPECIFIED {
+		doc.Severity = strings.ToLower(strings.TrimPrefix(msg.GetSeverity().String(), "SEVERITY_"))
+	}
+	for _, field := range msg.GetFields() {
+		if doc.Fields == nil {
+			doc.Fields = map[string]any{}
+		}
+		doc.Fields[field.GetKey()] = field.GetValue().AsInterface()
+	}
+	for _, frame := range msg.GetFrames() {
+		doc.Frames = append(doc.Frames, errstack.Frame{Function: frame.GetFunction(), File: frame.GetFile(), Line: int(frame.GetLine())})
+	}
+	if msg.GetExternal() {
+		doc.External = true
+		return doc
+	}
+	if causes := msg.GetCauses(); len(causes) == 1 {
+		doc.Cause = toDocument(causes[0])
+	} else {
+		for _, cause := range causes {
+			doc.Causes = append(doc.Causes, toDocument(cause))
+		}
+	}
+	return doc
+}
+
+// this returns the first cause of the message, or nil
+func firstCause(msg *Error) *Error {
+	if len(msg.GetCauses()) == 0 {
+		return nil
+	}
+	return msg.GetCauses()[0]
+}
+
+// external is an outside error decoded from a message, with its cause.
+type external struct {
+	msg   string
+	cause error
+}
+
+func (e external) Error() string {
+	return e.msg
+}
+
+func (e external) Unwrap() error {
+	return e.cause
+}
@@ -0,0 +1,56 @@
+package errproto_test
+
+import (
+	"errors"
+	"fmt"
+	"testing"
+
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+	errproto "github.com/the-zucc/errhandling/err-proto"
+	errstack "github.com/the-zucc/errhandling/err-stack"
+	"google.golang.org/protobuf/proto"
+)
+
+func TestErrProto(t *testing.T) {
+	RegisterFailHandler(Fail)
+	RunSpecs(t, "errproto tests")
+}
+
+var _ = Describe("errproto tests", func() {
+	It("ToProto() and FromProto() should round-trip the whole chain", func() {
+		err := errstack.NewCode("ORDER_MISSING", "load order", errstack.Join(errors.New("no rows"), errstack.New("timeout").TimedOut())).
+			With("order_id", 42).
+			WithSeverity(errstack.SeverityWarn).
+			WithPublicMessage("This order does not exist.")
+		msg := errproto.ToProto(err)
+		Expect(msg.GetCode()).To(Equal("ORDER_MISSING"))
+		Expect(msg.GetSeverity()).To(Equal(errproto.Severity_SEVERITY_WARN))
+		Expect(msg.GetCauses()).To(HaveLen(1))
+		Expect(msg.GetCauses()[0].GetCauses()).To(HaveLen(2))
+		Expect(msg.GetFields()[0].GetValue().GetNumberValue()).To(Equal(42.0))
+		data, marshalErr := proto.Marshal(msg)
+		Expect(marshalErr).To(BeNil())
+		received := &errproto.Error{}
+		Expect(proto.Unmarshal(data, received)).To(Succeed())
+		decoded := errproto.FromProto(received)
+		Expect(decoded.(errstack.Error).PrintableError()).To(Equal(err.PrintableError()))
+		Expect(errstack.Code(decoded)).To(Equal("ORDER_MISSING"))
+		Expect(errstack.ID(decoded)).To(Equal(err.ID()))
+		Expect(errstack.Severity(decoded)).To(Equal(errstack.SeverityWarn))
+		Expect(errstack.PublicMessage(decoded)).To(Equal("This order does not exist."))
+		Expect(errstack.IsTimeout(decoded)).To(BeTrue())
+		Expect(errstack.Frames(decoded)).To(Equal(errstack.Frames(err)))
+	})
+
+	It("ToProto() should keep the outside errors wrapping stacked ones", func() {
+		err := fmt.Errorf("request failed: %w", errstack.NewCode("DB_DOWN", "connect", errors.New("connection refused")))
+		msg := errproto.ToProto(err)
+		Expect(msg.GetExternal()).To(BeTrue())
+		decoded := errproto.FromProto(msg)
+		Expect(decoded.Error()).To(Equal(err.Error()))
+		Expect(errstack.RootCode(decoded)).To(Equal("DB_DOWN"))
+		Expect(errproto.ToProto(nil)).To(BeNil())
+		Expect(errproto.FromProto(nil)).To(BeNil())
+	})
+})
@@ -0,0 +1,174 @@
+package errsql
+
+import (
+	"context"
+	"database/sql"
+	"database/sql/driver"
+	"errors"
+	"reflect"
+	"strings"
+
+	errstack "github.com/the-zucc/errhandling/err-stack"
+)
+
+// These are the codes of the errors translated by Translate().
+var (
+	CODE_NO_ROWS               = errstack.RegisterCode("SQL_NO_ROWS")
+	CODE_UNIQUE_VIOLATION      = errstack.RegisterCode("SQL_UNIQUE_VIOLATION")
+	CODE_FOREIGN_KEY_VIOLATION = errstack.RegisterCode("SQL_FOREIGN_KEY_VIOLATION")
+	CODE_NOT_NULL_VIOLATION    = errstack.RegisterCode("SQL_NOT_NULL_VIOLATION")
+	CODE_CHECK_VIOLATION       = errstack.RegisterCode("SQL_CHECK_VIOLATION")
+	CODE_SERIALIZATION_FAILURE = errstack.RegisterCode("SQL_SERIALIZATION_FAILURE")
+	CODE_DEADLOCK              = errstack.RegisterCode("SQL_DEADLOCK")
+	CODE_CONNECTION_LOST       = errstack.RegisterCode("SQL_CONNECTION_LOST")
+	CODE_QUERY_CANCELED        = errstack.RegisterCode("SQL_QUERY_CANCELED")
+)
+
+// these codes may succeed if the transaction or query is run again
+var retryable = map[string]bool{
+	CODE_SERIALIZATION_FAILURE: true,
+	CODE_DEADLOCK:              true,
+	CODE_CONNECTION_LOST:       true,
+}
+
+// sqlStates maps the SQLSTATE codes of PostgreSQL
+var sqlStates = map[string]string{
+	"23505": CODE_UNIQUE_VIOLATION,
+	"23503": CODE_FOREIGN_KEY_VIOLATION,
+	"23502": CODE_NOT_NULL_VIOLATION,
+	"23514": CODE_CHECK_VIOLATION,
+	"40001": CODE_SERIALIZATION_FAILURE,
+	"40P01": CODE_DEADLOCK,
+	"57014": CODE_QUERY_CANCELED,
+	"57P01": CODE_CONNECTION_LOST,
+}
+
+// mysqlNumbers maps the error numbers of MySQL
+var mysqlNumbers = map[uint16]string{
+	1062: CODE_UNIQUE_VIOLATION,
+	1451: CODE_FOREIGN_KEY_VIOLATION,
+	1452: CODE_FOREIGN_KEY_VIOLATION,
+	1048: CODE_NOT_NULL_VIOLATION,
+	3819: CODE_CHECK_VIOLATION,
+	1213: CODE_DEADLOCK,
+	1205: CODE_SERIALIZATION_FAILURE, // lock wait timeout
+	1317: CODE_QUERY_CANCELED,
+	2006: CODE_CONNECTION_LOST, // server has gone away
+	2013: CODE_CONNECTION_LOST, // lost connection during query
+}
+
+/*
+Classify() returns the code of the error, regardless of the driver, or
+"" if it is not a known database error. PostgreSQL errors are recognized
+by their SQLState() method, which both lib/pq and pgx implement, and
+MySQL errors by their number. The MySQL driver is not imported, so as
+not to register it in every program: its errors are recognized by their
+structure.
+*/
+func Classify(err error) string {
+	var state interface{ SQLState() string }
+	switch {
+	case err == nil:
+		return ""
+	case errors.Is(err, sql.ErrNoRows):
+		return CODE_NO_ROWS
+	case errors.Is(err, driver.ErrBadConn), errors.Is(err, sql.ErrConnDone), invalidConn(err):
+		return CODE_CONNECTION_LOST
+	case errors.As(err, &state):
+		if code, ok := sqlStates[state.SQLState()]; ok {
+			return code
+		}
+		// class 08 is the connection exceptions
+		if strings.HasPrefix(state.SQLState(), "08") {
+			return CODE_CONNECTION_LOST
+		}
+	}
+	if number, ok := mysqlNumber(err); ok {
+		return mysqlNumbers[number]
+	}
+	return ""
+}
+
+/*
+mysqlNumber() returns the number of the MySQL error of the chain, a
+*mysql.MySQLError of github.com/go-sql-driver/mysql or any struct with
+the same Number and Message fields.
+*/
+func mysqlNumber(err error) (uint16, bool) {
+	for _, err := range errstack.Chain(err) {
+		v := reflect.ValueOf(err)
+		if v.Kind() == reflect.Pointer && !v.IsNil() {
+			v = v.Elem()
+		}
+		if v.Kind() != reflect.Struct {
+			continue
+		}
+		number, message := v.FieldByName("Number"), v.FieldByName("Message")
+		if number.Kind() == reflect.Uint16 && message.Kind() == reflect.String {
+			return uint16(number.Uint()), true
+		}
+	}
+	return 0, false
+}
+
+// invalidConn() reports whether the chain holds the mysql.ErrInvalidConn of the MySQL driver.
+func invalidConn(err error) bool {
+	for _, err := range errstack.Chain(err) {
+		if reflect.TypeOf(err) == errorString && err.Error() == "invalid connection" {
+			return true
+		}
+	}
+	return false
+}
+
+// the type of the errors returned by errors.New(), such as mysql.ErrInvalidConn
+var errorString = reflect.TypeOf(errors.New(""))
+
+/*
+Translate() wraps the database errors into errstack errors with the code
+returned by Classify(), flagged as retryable for serialization failures,
+deadlocks and connection losses, and as timeouts for canceled queries
+whose context expired. Other errors are returned unchanged.
+
+Example:
+
+	_, err := db.ExecContext(ctx, "INSERT INTO users (email) VALUES ($1)", email)
+	if errstack.Code(errsql.Translate(err)) == errsql.CODE_UNIQUE_VIOLATION {
+		return ErrEmailTaken
+	}
+*/
+func Translate(err error) error {
+	code := Classify(err)
+	if code == "" {
+		return err
+	}
+	translated := errstack.NewCode(code, message(code), err)
+	if retryable[code] {
+		translated = translated.Retryable()
+	}
+	if code == CODE_QUERY_CANCELED && errors.Is(err, context.DeadlineExceeded) {
+		translated = translated.TimedOut()
+	}
+	return translated
+}
+
+// message() derives the message from the code, e.g. "unique violation".
+func message(code string) string {
+	return strings.ToLower(strings.ReplaceAll(strings.TrimPrefix(code, "SQL_"), "_", " "))
+}
+
+/*
+TranslateQuery() behaves like Translate(), and attaches the query to the
+error as the "query" field. Unknown errors are wrapped as well, so that
+the field can be attached. It returns nil if the error is nil.
+*/
+func TranslateQuery(err error, query string) error {
+	if err == nil {
+		return nil
+	}
+	translated, ok := Translate(err).(errstack.Error)
+	if !ok {
+		translated = errstack.New("query failed", err)
+	}
+	return translated.With("query", query)
+}
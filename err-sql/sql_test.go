@@ -0,0 +1,95 @@
+package errsql_test
+
+import (
+	"context"
+	"database/sql"
+	"database/sql/driver"
+	"errors"
+	"fmt"
+	"testing"
+
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+
+	. "github.com/the-zucc/errhandling"
+	errsql "github.com/the-zucc/errhandling/err-sql"
+	errstack "github.com/the-zucc/errhandling/err-stack"
+)
+
+func TestErrSql(t *testing.T) {
+	RegisterFailHandler(Fail)
+	RunSpecs(t, "errsql tests")
+}
+
+var _ = Describe("errsql tests", func() {
+	It("WithTx() should commit on success and roll back on Throw", func() {
+		db := sql.OpenDB(&fakeConnector{})
+		defer db.Close()
+		Expect(errsql.WithTx(context.Background(), db, func(tx *sql.Tx) {})).To(Succeed())
+		Expect(log).To(Equal([]string{"begin", "commit"}))
+		log = nil
+		err := errsql.WithTx(context.Background(), db, func(tx *sql.Tx) {
+			Throw_(errors.New("insufficient funds"))
+		})
+		Expect(err.Error()).To(Equal("insufficient funds"))
+		Expect(log).To(Equal([]string{"begin", "rollback"}))
+	})
+	It("Translate() should classify driver errors into stable codes", func() {
+		pgErr := &sqlStateError{state: "40001"}
+		err := errsql.Translate(fmt.Errorf("update balance: %w", pgErr))
+		Expect(errstack.Code(err)).To(Equal(errsql.CODE_SERIALIZATION_FAILURE))
+		Expect(errstack.IsRetryable(err)).To(BeTrue())
+		Expect(errors.Is(err, pgErr)).To(BeTrue())
+		err = errsql.Translate(&MySQLError{Number: 1062, Message: "Duplicate entry"})
+		Expect(err.Error()).To(Equal("Error 1062: Duplicate entry -> unique violation"))
+		Expect(errstack.IsRetryable(err)).To(BeFalse())
+		Expect(errstack.Code(errsql.Translate(fmt.Errorf("query: %w", errors.New("invalid connection"))))).To(Equal(errsql.CODE_CONNECTION_LOST))
+		Expect(errstack.Code(errsql.Translate(sql.ErrNoRows))).To(Equal(errsql.CODE_NO_ROWS))
+		plain := errors.New("syntax error")
+		Expect(errsql.Translate(plain)).To(Equal(plain))
+	})
+})
+
+// this is a database/sql driver recording the transaction operations
+var log []string
+
+type fakeConnector struct{}
+
+func (c *fakeConnector) Connect(context.Context) (driver.Conn, error) { return fakeConn{}, nil }
+func (c *fakeConnector) Driver() driver.Driver                        { return nil }
+
+type fakeConn struct{}
+
+func (fakeConn) Prepare(string) (driver.Stmt, error) { return nil, errors.New("not implemented") }
+func (fakeConn) Close() error                        { return nil }
+func (fakeConn) Begin() (driver.Tx, error) {
+	log = append(log, "begin")
+	return fakeConn{}, nil
+}
+func (fakeConn) Commit() error {
+	log = append(log, "commit")
+	return nil
+}
+func (fakeConn) Rollback() error {
+	log = append(log, "rollback")
+	return nil
+}
+
+// this mimics the errors of lib/pq and pgx
+type sqlStateError struct {
+	state string
+}
+
+func (e *sqlStateError) Error() string    { return "pq: could not serialize access" }
+func (e *sqlStateError) SQLState() string { return e.state }
+
+// MySQLError has the structure of the *mysql.MySQLError of the MySQL driver
+type MySQLError struct {
+	Number   uint16
+	SQLState [5]byte
+	Message  string
+}
+
+func (e *MySQLError) Error() string {
+	return fmt.Sprintf("Error %d: %s", e.Number, e.Message)
+}
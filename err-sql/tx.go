@@ -0,0 +1,54 @@
+/*
+Package errsql integrates the errhandling package with database/sql.
+*/
+package errsql
+
+import (
+	"context"
+	"database/sql"
+
+	"github.com/the-zucc/errhandling"
+	errstack "github.com/the-zucc/errhandling/err-stack"
+)
+
+/*
+WithTx() runs the function in a transaction, which is committed if the
+function succeeds, and rolled back if it throws or panics. The function
+does not return an error: it may Throw instead. If the rollback fails
+too, its failure is joined to the error as a secondary cause.
+
+Example:
+
+	err := errsql.WithTx(ctx, db, func(tx *sql.Tx) {
+		Throw(tx.ExecContext(ctx, "UPDATE accounts SET balance = balance - $1 WHERE id = $2", amount, from))
+		Throw(tx.ExecContext(ctx, "UPDATE accounts SET balance = balance + $1 WHERE id = $2", amount, to))
+	})
+*/
+func WithTx(ctx context.Context, db *sql.DB, f func(tx *sql.Tx)) error {
+	return WithTxOptions(ctx, db, nil, f)
+}
+
+// WithTxOptions() behaves like WithTx(), with the transaction options.
+func WithTxOptions(ctx context.Context, db *sql.DB, opts *sql.TxOptions, f func(tx *sql.Tx)) error {
+	tx, err := db.BeginTx(ctx, opts)
+	if err != nil {
+		return errstack.New("could not begin transaction", err)
+	}
+	if err := run(tx, f); err != nil {
+		if rollbackErr := tx.Rollback(); rollbackErr != nil {
+			errhandling.AppendInto(&err, errstack.New("could not roll back transaction", rollbackErr))
+		}
+		return err
+	}
+	if err := tx.Commit(); err != nil {
+		return errstack.New("could not commit transaction", err)
+	}
+	return nil
+}
+
+// run() runs the function, converting what it throws into an error.
+func run(tx *sql.Tx, f func(tx *sql.Tx)) (e error) {
+	defer errhandling.CatchPanic_(&e)
+	f(tx)
+	return nil
+}
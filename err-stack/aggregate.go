@@ -0,0 +1,60 @@
+package errstack
+
+import "sync"
+
+var (
+	aggregatesMu sync.RWMutex
+	aggregates   []func(error) []error
+)
+
+/*
+RegisterAggregate() registers a function unpacking the outside errors
+aggregating several errors (such as those of go.uber.org/multierr), and
+returning nil for any other error. The aggregates given as causes to
+New(), Join() and the like are then unpacked, each of their errors
+becoming a separate branch, so that none of them is lost in the traces.
+
+Example:
+
+	errstack.RegisterAggregate(func(err error) []error {
+		if agg, ok := err.(*validation.Errors); ok {
+			return agg.List
+		}
+		return nil
+	})
+*/
+func RegisterAggregate(unpack func(error) []error) {
+	aggregatesMu.Lock()
+	defer aggregatesMu.Unlock()
+	aggregates = append(aggregates, unpack)
+}
+
+/*
+this unpacks the outside aggregates among the causes, with the functions
+registered with RegisterAggregate()
+*/
+func unpackAggregates(causes []error) []error {
+	aggregatesMu.RLock()
+	defer aggregatesMu.RUnlock()
+	if len(aggregates) == 0 {
+		return causes
+	}
+	unpacked := make([]error, 0, len(causes))
+	for _, cause := range causes {
+		unpacked = append(unpacked, unpackAggregate(cause)...)
+	}
+	return unpacked
+}
+
+// this unpacks an outside aggregate, or returns the error alone
+func unpackAggregate(err error) []error {
+	if _, ok := err.(Error); ok || err == nil {
+		return []error{err}
+	}
+	for _, unpack := range aggregates {
+		if errs := unpack(err); len(errs) > 0 {
+			return errs
+		}
+	}
+	return []error{err}
+}
@@ -0,0 +1,153 @@
+package errstack
+
+import (
+	"errors"
+	"reflect"
+)
+
+/*
+Chain() returns the errors of the chain of any error, from the outermost
+one to the root cause. Unlike RootCause(), it also unwraps the outside
+errors wrapping another one, such as those returned by fmt.Errorf() with
+%w. Errors with several causes end the chain, their branches having a
+chain each.
+
+Example:
+
+	for _, err := range errstack.Chain(err) {
+		if se, ok := err.(errstack.Error); ok {
+			log.Println(se.Msg(), se.Fields())
+		}
+	}
+*/
+func Chain(err error) []error {
+	chain := []error{}
+	guard := cycleGuard{}
+	for err != nil && !guard.visited(err, len(chain)) {
+		chain = append(chain, err)
+		err = next(err)
+	}
+	return chain
+}
+
+// the maximum number of errors walked in a chain, past which it is cut short
+const maxChainDepth = 1024
+
+/*
+This detects the cycles of the outside errors unwrapping to one of their
+wrappers, by the identity of the pointer errors. The errors are not
+compared with ==, which panics on the comparable errors holding
+uncomparable values; the chains of the other errors are cut short at
+maxChainDepth.
+*/
+type cycleGuard struct {
+	seen map[identity]bool
+}
+
+// this identifies a pointer error, by its type and address
+type identity struct {
+	typ reflect.Type
+	ptr uintptr
+}
+
+// this reports whether the error was already walked, or too many errors were
+func (g *cycleGuard) visited(err error, walked int) bool {
+	if walked >= maxChainDepth {
+		return true
+	}
+	v := reflect.ValueOf(err)
+	if v.Kind() != reflect.Pointer {
+		return false
+	}
+	id := identity{typ: v.Type(), ptr: v.Pointer()}
+	if g.seen == nil {
+		g.seen = map[identity]bool{}
+	}
+	if g.seen[id] {
+		return true
+	}
+	g.seen[id] = true
+	return false
+}
+
+/*
+Root() returns the last error of the chain of any error (see Chain()),
+or nil if the error is nil.
+*/
+func Root(err error) error {
+	chain := Chain(err)
+	if len(chain) == 0 {
+		return nil
+	}
+	return chain[len(chain)-1]
+}
+
+/*
+Frames() returns the call stack captured by the innermost error of the
+chain that captured one, which is the closest to where the failure
+originated, or nil if there is none. Besides the stacked errors, the
+outside errors with a StackTrace() method, such as the errors of
+github.com/pkg/errors, are taken into account.
+*/
+func Frames(err error) []Frame {
+	chain := Chain(err)
+	for i := len(chain) - 1; i >= 0; i-- {
+		if se, ok := chain[i].(Error); ok {
+			if frames := se.StackFrames(); len(frames) > 0 {
+				return frames
+			}
+		} else if frames := tracedFrames(chain[i]); len(frames) > 0 {
+			return frames
+		}
+	}
+	return nil
+}
+
+/*
+FindCause() returns the first error of the chain of any error that is a
+T, searching every branch of the errors with several causes, depth
+first. It is like errors.As(), but returns the error found, and T may be
+any type, an interface or a concrete type.
+
+Example:
+
+	if notFound, ok := errstack.FindCause[*NotFoundError](err); ok {
+		http.Error(w, notFound.Resource+" not found", http.StatusNotFound)
+	}
+*/
+func FindCause[T any](err error) (T, bool) {
+	pending := []error{err}
+	guard := cycleGuard{}
+	for walked := 0; len(pending) > 0; walked++ {
+		err := pending[len(pending)-1]
+		pending = pending[:len(pending)-1]
+		if err == nil || guard.visited(err, walked) {
+			continue
+		}
+		if t, ok := err.(T); ok {
+			return t, true
+		}
+		switch u := err.(type) {
+		case interface{ Unwrap() error }:
+			pending = append(pending, u.Unwrap())
+		case interface{ Unwrap() []error }:
+			causes := u.Unwrap()
+			for i := len(causes) - 1; i >= 0; i-- {
+				pending = append(pending, causes[i])
+			}
+		}
+	}
+	var zero T
+	return zero, false
+}
+
+// this returns the single cause of any error, or nil
+func next(err error) error {
+	if se, ok := err.(Error); ok {
+		if len(se.causes()) > 0 {
+			return nil
+		}
+		return se.Cause
+	}
+	return errors.Unwrap(err)
+}
@@ -0,0 +1,89 @@
+package errstack
+
+/*
+Retryable() returns a copy of the error marked as retryable, meaning
+the operation that failed may succeed if attempted again.
+
+Example:
+
+	return errstack.New("connection reset", err).Retryable()
+*/
+func (e Error) Retryable() Error {
+	e.retryable = true
+	e.changed()
+	return e
+}
+
+/*
+TimedOut() returns a copy of the error marked as caused by a timeout.
+
+Example:
+
+	return errstack.New("no response from upstream").TimedOut()
+*/
+func (e Error) TimedOut() Error {
+	e.timeout = true
+	e.changed()
+	return e
+}
+
+/*
+IsRetryable() reports whether any error in the chain was marked with
+Retryable(), or is an outside error reporting itself as temporary.
+*/
+func IsRetryable(err error) bool {
+	return walk(err, func(err error) bool {
+		if se, ok := err.(Error); ok {
+			return se.retryable
+		}
+		if t, ok := err.(interface{ Temporary() bool }); ok {
+			return t.Temporary()
+		}
+		return false
+	})
+}
+
+/*
+IsTimeout() reports whether any error in the chain was marked with
+TimedOut(), or is an outside error reporting itself as a timeout (such
+as context.DeadlineExceeded or a net.Error).
+*/
+func IsTimeout(err error) bool {
+	return walk(err, func(err error) bool {
+		if se, ok := err.(Error); ok {
+			return se.timeout
+		}
+		if t, ok := err.(interface{ Timeout() bool }); ok {
+			return t.Timeout()
+		}
+		return false
+	})
+}
+
+/*
+walk() calls f on every error of the chain, from the outermost to the
+root cause, until f returns true. Outside errors are unwrapped with
+their Unwrap() method, if any, and every branch of errors with several
+causes is walked in turn.
+*/
+func walk(err error, f func(error) bool) bool {
+	for err != nil {
+		if f(err) {
+			return true
+		}
+		switch e := err.(type) {
+		case interface{ Unwrap() []error }:
+			for _, cause := range e.Unwrap() {
+				if walk(cause, f) {
+					return true
+				}
+			}
+			return false
+		case interface{ Unwrap() error }:
+			err = e.Unwrap()
+		default:
+			return false
+		}
+	}
+	return false
+}
@@ -0,0 +1,110 @@
+package errstack
+
+import (
+	"fmt"
+	"sort"
+	"sync"
+)
+
+var (
+	codesMu sync.Mutex
+	codes   = map[string]bool{}
+)
+
+/*
+RegisterCode() registers an error code and returns it. It panics if the
+code was already registered, which prevents two packages from using the
+same code for different errors. It is meant to be used when declaring
+package-level code constants.
+
+Example:
+
+	var CodeUserNotFound = errstack.RegisterCode("USER_NOT_FOUND")
+*/
+func RegisterCode(code string) string {
+	codesMu.Lock()
+	defer codesMu.Unlock()
+	if codes[code] {
+		panic(New(fmt.Sprintf("error code %q is already registered", code)))
+	}
+	codes[code] = true
+	return code
+}
+
+// RegisteredCodes() returns the registered error codes, sorted.
+func RegisteredCodes() []string {
+	codesMu.Lock()
+	defer codesMu.Unlock()
+	registered := make([]string, 0, len(codes))
+	for code := range codes {
+		registered = append(registered, code)
+	}
+	sort.Strings(registered)
+	return registered
+}
+
+/*
+NewCode() instanciates a stackedError like New(), identified by a stable
+code, so that callers (such as an API layer) can rely on the code rather
+than on the message.
+
+Example:
+
+	return errstack.NewCode(CodeUserNotFound, "could not load user", err)
+*/
+func NewCode(code string, msg string, cause ...error) Error {
+	e := New(msg, cause...)
+	e.code = code
+	return e
+}
+
+/*
+WithCode() returns a copy of the error identified by the provided code,
+like NewCode().
+
+Example:
+
+	return errstack.New("could not load user").WithCause(err).WithCode(CodeUserNotFound)
+*/
+func (e Error) WithCode(code string) Error {
+	e.code = code
+	e.changed()
+	return e
+}
+
+// Code() returns the code of this error, or "" if it has none.
+func (e Error) Code() string {
+	return e.code
+}
+
+/*
+Code() returns the code of the outermost error of the chain that has
+one, or "" if there is none.
+*/
+func Code(err error) string {
+	code := ""
+	walk(err, func(err error) bool {
+		if se, ok := err.(Error); ok && se.code != "" {
+			code = se.code
+			return true
+		}
+		return false
+	})
+	return code
+}
+
+/*
+RootCode() returns the code of the innermost error of the chain that has
+one, or "" if there is none. For errors with several causes, branches
+are searched in order, and the last code found is returned.
+*/
+func RootCode(err error) string {
+	code := ""
+	walk(err, func(err error) bool {
+		if se, ok := err.(Error); ok && se.code != "" {
+			code = se.code
+		}
+		return false
+	})
+	return code
+}
@@ -0,0 +1,112 @@
+package errstack
+
+import (
+	"fmt"
+	"io"
+	"os"
+	"strings"
+)
+
+// the ANSI escape sequences of the colors of the traces
+const (
+	ansiRed    = "\x1b[31m"
+	ansiYellow = "\x1b[33m"
+	ansiDim    = "\x1b[2m"
+	ansiReset  = "\x1b[0m"
+)
+
+/*
+Colored() tells whether the writer is a terminal accepting colors: a
+character device, while the NO_COLOR environment variable is not set
+(see https://no-color.org).
+*/
+func Colored(w io.Writer) bool {
+	if _, ok := os.LookupEnv("NO_COLOR"); ok {
+		return false
+	}
+	f, ok := w.(*os.File)
+	if !ok {
+		return false
+	}
+	info, err := f.Stat()
+	return err == nil && info.Mode()&os.ModeCharDevice != 0
+}
+
+/*
+Fprint() writes the trace of any error to w in DefaultTraceFormat,
+followed by the call stack captured where the failure originated, like
+%+v. If w is a terminal, the message of the error is printed in red, its
+root causes in yellow and the frames of the call stack dimmed.
+
+Example:
+
+	if err := run(); err != nil {
+		errstack.Fprint(os.Stderr, err)
+		os.Exit(1)
+	}
+*/
+func Fprint(w io.Writer, err error) error {
+	f := DefaultTraceFormat
+	f.Color = f.Color || Colored(w)
+	if _, werr := f.WriteTrace(w, err); werr != nil {
+		return werr
+	}
+	_, werr := fmt.Fprintln(w, f.stackTrace(err))
+	return werr
+}
+
+/*
+this returns the call stack captured where the failure originated, as
+printed after the trace by %+v, or "" if it was not captured
+*/
+func (f TraceFormat) stackTrace(err error) string {
+	frames := stackFrames(err)
+	if len(frames) == 0 {
+		return ""
+	}
+	lines := []string{"\n\nStack trace:"}
+	for _, frame := range frames {
+		lines = append(lines, f.paint(ansiDim, frame.String()))
+	}
+	return strings.Join(lines, "\n")
+}
+
+/*
+this colors each line of the text, after its indentation, if the format
+is colored
+*/
+func (f TraceFormat) paint(color, text string) string {
+	if !f.Color {
+		return text
+	}
+	lines := strings.Split(text, "\n")
+	for i, line := range lines {
+		body := strings.TrimLeft(line, "\t ")
+		if body != "" {
+			lines[i] = line[:len(line)-len(body)] + color + body + ansiReset
+		}
+	}
+	return strings.Join(lines, "\n")
+}
+
+/*
+this returns the call stack captured where the failure originated: the
+one of the innermost stacked error, following the first branch of the
+errors with several causes, unless the outside errors it wraps captured
+one as well (see Frames())
+*/
+func stackFrames(err error) []Frame {
+	se, ok := err.(Error)
+	if !ok {
+		return Frames(err)
+	}
+	origin := innermost(se)
+	cause := origin.Cause
+	if len(origin.causes()) > 0 {
+		cause = origin.causes()[0]
+	}
+	if frames := externalFrames(cause); len(frames) > 0 {
+		return frames
+	}
+	return origin.StackFrames()
+}
@@ -0,0 +1,129 @@
+package errstack
+
+// KeyValue is a piece of key/value metadata attached to an error.
+type KeyValue struct {
+	Key    string
+	Value  any
+	Public bool // whether the field is safe to show to users, see WithPublicField()
+}
+
+/*
+With() returns a copy of the error with the key/value pair attached.
+Fields survive wrapping: they can be looked up from any error wrapping
+this one with Field().
+
+Example:
+
+	return errstack.New("user not found", err).With("user_id", id)
+*/
+func (e Error) With(key string, value any) Error {
+	return e.with(KeyValue{Key: key, Value: value})
+}
+
+/*
+WithPublicField() returns a copy of the error with the key/value pair
+attached, like With(), and marked as safe to show to users: renderers
+meant for users (HTTP problems and the like) only expose the fields
+returned by PublicFields(), while logs get all of them.
+
+Example:
+
+	return errstack.NewCode("ORDER_EXISTS", "INSERT INTO orders failed", err).
+		WithPublicField("order_id", id)
+*/
+func (e Error) WithPublicField(key string, value any) Error {
+	return e.with(KeyValue{Key: key, Value: value, Public: true})
+}
+
+// this returns a copy of the error with the field attached
+func (e Error) with(field KeyValue) Error {
+	fields := make([]KeyValue, len(e.fields()), len(e.fields())+1)
+	copy(fields, e.fields())
+	e.edit().fields = append(fields, field)
+	e.changed()
+	return e
+}
+
+/*
+WithField() is the same as With(), named after the other With*()
+methods for builder chains.
+
+Example:
+
+	return errstack.New("user not found").
+		WithCause(err).
+		WithCode(CodeUserNotFound).
+		WithField("user_id", id).
+		WithSeverity(errstack.SeverityWarn)
+*/
+func (e Error) WithField(key string, value any) Error {
+	return e.With(key, value)
+}
+
+/*
+Fields() returns the fields attached to this error, in order, redacted
+as registered with RedactField() and RedactPattern().
+*/
+func (e Error) Fields() []KeyValue {
+	return redactAll(append([]KeyValue{}, e.fields()...))
+}
+
+/*
+Field() returns the value of the field with the provided key, looking
+through the whole chain from the outermost error to the root cause. If
+the key was attached several times, the outermost value wins.
+*/
+func Field(err error, key string) (any, bool) {
+	var value any
+	found := walk(err, func(err error) bool {
+		se, ok := err.(Error)
+		if !ok {
+			return false
+		}
+		for i := len(se.fields()) - 1; i >= 0; i-- {
+			if se.fields()[i].Key == key {
+				value = redactField(se.fields()[i]).Value
+				return true
+			}
+		}
+		return false
+	})
+	return value, found
+}
+
+/*
+Fields() returns the fields attached to every error of the chain, from
+the outermost error to the root cause. Keys attached several times only
+appear once, with their outermost value.
+*/
+func Fields(err error) []KeyValue {
+	fields := []KeyValue{}
+	seen := map[string]bool{}
+	walk(err, func(err error) bool {
+		if se, ok := err.(Error); ok {
+			for i := len(se.fields()) - 1; i >= 0; i-- {
+				if !seen[se.fields()[i].Key] {
+					seen[se.fields()[i].Key] = true
+					fields = append(fields, se.fields()[i])
+				}
+			}
+		}
+		return false
+	})
+	return redactAll(fields)
+}
+
+/*
+PublicFields() returns the fields of the chain of the error attached
+with WithPublicField(), like Fields(). A key attached as a private field
+by an outer error hides the public field of an inner one.
+*/
+func PublicFields(err error) []KeyValue {
+	public := []KeyValue{}
+	for _, field := range Fields(err) {
+		if field.Public {
+			public = append(public, field)
+		}
+	}
+	return public
+}
@@ -0,0 +1,70 @@
+package errstack
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"regexp"
+)
+
+/*
+FingerprintFrames is the number of frames of the call stack, from the
+innermost, that are part of the fingerprints of the errors.
+*/
+var FingerprintFrames = 3
+
+// the volatile parts of the messages, left out of the fingerprints
+var volatilePattern = regexp.MustCompile(
+	`[0-9a-fA-F]{8}-[0-9a-fA-F]{4}-[0-9a-fA-F]{4}-[0-9a-fA-F]{4}-[0-9a-fA-F]{12}` + // UUIDs
+		`|0x[0-9a-fA-F]+` + // addresses
+		`|\b[0-9a-fA-F]*[0-9][0-9a-fA-F]*[a-fA-F][0-9a-fA-F]*\b` + // hexadecimal IDs
+		`|\d+`, // numbers
+)
+
+/*
+Fingerprint() returns a stable hash of any error, identifying the
+failure rather than the occurrence: it is computed from the codes of its
+chain, the messages of its root causes and the functions of the top
+FingerprintFrames frames of its call stack. The volatile parts of the
+messages (numbers, UUIDs, hexadecimal IDs) and the correlation IDs are
+left out, so that all the occurrences of a failure share a fingerprint,
+to be grouped and deduplicated.
+
+Example:
+
+	if !seen[errstack.Fingerprint(err)] {
+		seen[errstack.Fingerprint(err)] = true
+		alert(err)
+	}
+*/
+func Fingerprint(err error) string {
+	if err == nil {
+		return ""
+	}
+	h := sha256.New()
+	write := func(kind, s string) {
+		h.Write([]byte(kind))
+		h.Write([]byte{0})
+		h.Write([]byte(s))
+		h.Write([]byte{0})
+	}
+	walk(err, func(err error) bool {
+		if se, ok := err.(Error); ok && se.code != "" {
+			write("code", se.code)
+		}
+		return false
+	})
+	// the outermost stacked error, past the outside errors wrapping it
+	if se, ok := FindCause[Error](err); ok {
+		err = se
+	}
+	for _, root := range rootMsgs(err) {
+		write("root", volatilePattern.ReplaceAllString(root, "?"))
+	}
+	for i, frame := range stackFrames(err) {
+		if i >= FingerprintFrames {
+			break
+		}
+		write("frame", frame.Function)
+	}
+	return hex.EncodeToString(h.Sum(nil)[:8])
+}
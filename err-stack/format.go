@@ -0,0 +1,58 @@
+package errstack
+
+import (
+	"fmt"
+	"io"
+	"strings"
+)
+
+/*
+Format() implements fmt.Formatter, following the conventions of
+pkg/errors:
+
+  - %s prints the short chain, from the outermost message to the root
+    cause, separated by colons ("open config: read file: ENOENT")
+  - %q prints the short chain, quoted
+  - %v prints the same as Error() ("ENOENT -> read file -> open config")
+  - %+v prints the same as PrintableError(), followed by the call stack
+    captured where the failure originated
+*/
+func (e Error) Format(s fmt.State, verb rune) {
+	switch verb {
+	case 'v':
+		if s.Flag('+') {
+			io.WriteString(s, e.PrintableError()+DefaultTraceFormat.stackTrace(e))
+			return
+		}
+		io.WriteString(s, e.Error())
+	case 's':
+		io.WriteString(s, e.shortChain())
+	case 'q':
+		fmt.Fprintf(s, "%q", e.shortChain())
+	default:
+		fmt.Fprintf(s, "%%!%c(errstack.Error=%s)", verb, e.Error())
+	}
+}
+
+// this returns the messages of the chain, outermost first
+func (e Error) shortChain() string {
+	if len(e.causes()) > 0 {
+		msgs := make([]string, len(e.causes()))
+		for i, cause := range e.causes() {
+			msgs[i] = shortChain(cause)
+		}
+		return fmt.Sprintf("%s: [%s]", Redact(e.msg), strings.Join(msgs, "; "))
+	}
+	if e.Cause == nil {
+		return Redact(e.msg)
+	}
+	return fmt.Sprintf("%s: %s", Redact(e.msg), shortChain(e.Cause))
+}
+
+// this returns the short chain of any error
+func shortChain(err error) string {
+	if se, ok := err.(Error); ok {
+		return se.shortChain()
+	}
+	return Redact(err.Error())
+}
@@ -0,0 +1,103 @@
+package errstack
+
+import (
+	"fmt"
+	"runtime"
+	"strings"
+)
+
+// the maximum number of frames captured when an error is created
+const maxFrames = 32
+
+// Frame is a frame of the call stack captured when an error is created.
+type Frame struct {
+	Function string `json:"function"`
+	File     string `json:"file"`
+	Line     int    `json:"line"`
+}
+
+func (f Frame) String() string {
+	return fmt.Sprintf("%s\n\t%s:%d", f.Function, f.File, f.Line)
+}
+
+/*
+This captures the call stack of the caller, leaving out the frames of
+this package, so that the stack starts where the error was created. It
+captures nothing if CaptureStacks is false, or if the error with the
+message is not sampled (see SampleStacks()).
+*/
+func callers(msg string) []uintptr {
+	if !CaptureStacks || !sampleStack(msg) {
+		return nil
+	}
+	pcs := make([]uintptr, maxFrames)
+	n := runtime.Callers(3, pcs)
+	pcs = pcs[:n]
+	frames := runtime.CallersFrames(pcs)
+	skipped := 0
+	for {
+		frame, more := frames.Next()
+		if !inPackage(frame.Function) {
+			break
+		}
+		skipped++
+		if !more {
+			break
+		}
+	}
+	if skipped >= len(pcs) {
+		return pcs
+	}
+	return pcs[skipped:]
+}
+
+// this reports whether the function belongs to this package
+func inPackage(function string) bool {
+	return strings.HasPrefix(function, "github.com/the-zucc/errhandling/err-stack.")
+}
+
+// StackFrames() returns the call stack captured when the error was created.
+func (e Error) StackFrames() []Frame {
+	if len(e.stack()) == 0 {
+		return append([]Frame(nil), e.frames()...)
+	}
+	return framesOf(e.stack())
+}
+
+// this resolves the frames of the program counters of a call stack
+func framesOf(pcs []uintptr) []Frame {
+	result := make([]Frame, 0, len(pcs))
+	frames := runtime.CallersFrames(pcs)
+	for {
+		frame, more := frames.Next()
+		result = append(result, Frame{
+			Function: frame.Function,
+			File:     frame.File,
+			Line:     frame.Line,
+		})
+		if !more {
+			return result
+		}
+	}
+}
+
+/*
+This returns the innermost stacked error of the chain (following the
+first branch of errors with several causes), whose call stack is the
+closest to where the failure originated.
+*/
+func innermost(e Error) Error {
+	for {
+		var cause error
+		if len(e.causes()) > 0 {
+			cause = e.causes()[0]
+		} else {
+			cause = e.Cause
+		}
+		se, ok := cause.(Error)
+		if !ok {
+			return e
+		}
+		e = se
+	}
+}
@@ -0,0 +1,130 @@
+package errstack
+
+import (
+	"bytes"
+	"encoding/gob"
+	"errors"
+	"fmt"
+	"io"
+)
+
+// the errors can be sent as values of the error interface
+func init() {
+	gob.RegisterName("errstack.Error", Error{})
+}
+
+/*
+This is the gob document of an error. Outside errors only carry their
+message, and are marked as external.
+*/
+type gobError struct {
+	Message   string
+	External  bool
+	Code      string
+	ID        string
+	Severity  Level
+	Public    string
+	Retryable bool
+	Timeout   bool
+	Fields    []gobField
+	Frames    []Frame
+	Cause     *gobError
+	Causes    []*gobError
+}
+
+/*
+This is a field of a gob document. The values gob cannot encode (see
+gob.Register()) are sent formatted with fmt.Sprint().
+*/
+type gobField struct {
+	Key   string
+	Value any
+}
+
+/*
+GobEncode() implements gob.GobEncoder: it encodes the error and its
+whole cause chain, with the codes, severities, public messages,
+classification flags, fields and call stacks of its errors, so that
+errors keep their structure over net/rpc or in caches. The errors are
+registered with gob, so that they can be sent as values of the error
+interface.
+*/
+func (e Error) GobEncode() ([]byte, error) {
+	b := &bytes.Buffer{}
+	if err := gob.NewEncoder(b).Encode(toGob(e)); err != nil {
+		return nil, New("could not encode error", err)
+	}
+	return b.Bytes(), nil
+}
+
+// GobDecode() implements gob.GobDecoder, decoding an error encoded with GobEncode().
+func (e *Error) GobDecode(data []byte) error {
+	doc := &gobError{}
+	if err := gob.NewDecoder(bytes.NewReader(data)).Decode(doc); err != nil {
+		return New("could not decode error", err)
+	}
+	decoded, ok := fromGob(doc).(Error)
+	if !ok {
+		return New("the outermost error of the document is external")
+	}
+	*e = decoded
+	return nil
+}
+
+// this converts an error of the chain to its gob document
+func toGob(err error) *gobError {
+	e, ok := err.(Error)
+	if !ok {
+		return &gobError{Message: Redact(err.Error()), External: true}
+	}
+	doc := &gobError{
+		Message:   Redact(e.msg),
+		Code:      e.code,
+		ID:        e.id,
+		Severity:  e.severity,
+		Public:    e.public,
+		Retryable: e.retryable,
+		Timeout:   e.timeout,
+		Frames:    e.StackFrames(),
+	}
+	for _, field := range e.Fields() {
+		if gob.NewEncoder(io.Discard).Encode(&gobField{Value: field.Value}) != nil {
+			field.Value = fmt.Sprint(field.Value)
+		}
+		doc.Fields = append(doc.Fields, gobField{Key: field.Key, Value: field.Value})
+	}
+	if len(e.causes()) > 0 {
+		for _, cause := range e.causes() {
+			doc.Causes = append(doc.Causes, toGob(cause))
+		}
+	} else if e.Cause != nil {
+		doc.Cause = toGob(e.Cause)
+	}
+	return doc
+}
+
+// this rebuilds an error of the chain from its gob document
+func fromGob(doc *gobError) error {
+	if doc.External {
+		return errors.New(doc.Message)
+	}
+	causes := []error{}
+	if doc.Cause != nil {
+		causes = append(causes, fromGob(doc.Cause))
+	}
+	for _, cause := range doc.Causes {
+		causes = append(causes, fromGob(cause))
+	}
+	e := newError(doc.Message, causes...)
+	e.id = doc.ID
+	e.code = doc.Code
+	e.severity = doc.Severity
+	e.public = doc.Public
+	e.retryable = doc.Retryable
+	e.timeout = doc.Timeout
+	e.edit().frames = doc.Frames
+	for _, field := range doc.Fields {
+		e = e.With(field.Key, field.Value)
+	}
+	return e
+}
@@ -1,6 +1,21 @@
+/*
+Package errstack provides Error, the error type of the errhandling
+module: an error decorated with its cause chain, its call stack and its
+metadata, printed with the whole trace by PrintableError().
+
+It is the only error package of the module. The handlederror package it
+was formerly called is gone: besides the import path, its users need to
+account for New() returning an Error instead of an error, and for the
+causes being held by value (Cause is an error, and RootCause() a
+method). CHANGELOG.md describes the migration.
+*/
 package errstack
 
-import "fmt"
+import (
+	"fmt"
+	"io"
+	"strings"
+)
 
 type StackedError interface {
 	PrintableError() string
@@ -10,15 +25,84 @@ type StackedError interface {
 This struct is used to store all information regarding an error.
 It decorates the error and reports it properly (with the nested
 causes and such) to the developer.
+
+Errors are comparable, so that sentinel errors can be compared with ==
+and matched by errors.Is() through any wrapper: their slices and maps
+are held behind a pointer, shared by the copies of an error and
+replaced (never modified) by the builders.
 */
 type Error struct {
-	msg       string // the error message
-	RootCause *error // the root cause
-	Cause     *error // the underlying cause of the error
+	msg       string   // the error message
+	Cause     error    // the underlying cause of the error, nil for a root cause
+	origin    *byte    // identifies the errors derived from the same New()
+	id        string   // the correlation ID of the error
+	joined    bool     // whether the error was returned by Join()
+	code      string   // the stable code identifying the error, if any
+	severity  Level    // the severity of the error, if set
+	public    string   // the message that is safe to show to users, if any
+	key       string   // the key of the message in the message bundles
+	retryable bool     // whether the failed operation may be retried
+	timeout   bool     // whether the failure is due to a timeout
+	details   *details // the slices and maps of the error, nil if it has none
+	memo      *memo    // the rendered forms of the error, once rendered
+}
+
+// this holds the slices and maps of an error, which are never modified once set
+type details struct {
+	causes []error        // the underlying causes, when there are several
+	fields []KeyValue     // the key/value metadata attached to the error
+	params map[string]any // the parameters of the message templates
+	stack  []uintptr      // the program counters of the call stack at creation
+	frames []Frame        // the call stack of an error decoded from JSON
+}
+
+// this returns a copy of the details of the error, to be modified
+func (e *Error) edit() *details {
+	d := &details{}
+	if e.details != nil {
+		*d = *e.details
+	}
+	e.details = d
+	return d
+}
+
+func (e Error) causes() []error {
+	if e.details == nil {
+		return nil
+	}
+	return e.details.causes
+}
+
+func (e Error) fields() []KeyValue {
+	if e.details == nil {
+		return nil
+	}
+	return e.details.fields
+}
+
+func (e Error) params() map[string]any {
+	if e.details == nil {
+		return nil
+	}
+	return e.details.params
+}
+
+func (e Error) stack() []uintptr {
+	if e.details == nil {
+		return nil
+	}
+	return e.details.stack
+}
+
+func (e Error) frames() []Frame {
+	if e.details == nil {
+		return nil
+	}
+	return e.details.frames
 }
 
 func (e Error) Msg() string {
-	return e.msg
+	return Redact(e.msg)
 }
 
 /*
@@ -30,11 +114,110 @@ following format:
 <some error> -> <some other error> -> some other error
 */
 func (e Error) Error() string {
+	cache := e.cache()
+	if cache == nil {
+		return e.text()
+	}
+	m, ok := cache.text.load()
+	if ok {
+		return m.s
+	}
+	return cache.text.store(m, e.text())
+}
+
+// this renders the message returned by Error()
+func (e Error) text() string {
 	// TODO check if this should only return e.msg instead. Seems logical.
+	if len(e.causes()) > 0 {
+		msgs := make([]string, len(e.causes()))
+		for i, cause := range e.causes() {
+			msgs[i] = cause.Error()
+		}
+		return Redact(fmt.Sprintf("[%s] -> %s", strings.Join(msgs, "; "), e.msg))
+	}
+	if e.Cause == nil {
+		return Redact(e.msg)
+	}
+	return Redact(fmt.Sprintf("%s -> %s", e.Cause.Error(), e.msg))
+}
+
+/*
+WithCause() returns a copy of the error with the provided errors added
+to its causes, the nil ones being ignored. An error given several causes
+has them as separate branches, as with New().
+
+Example:
+
+	return errstack.New("could not sync").WithCause(err).WithCode("SYNC_FAILED")
+*/
+func (e Error) WithCause(causes ...error) Error {
+	all := append([]error{}, e.causes()...)
+	if e.Cause != nil {
+		all = append(all, e.Cause)
+	}
+	for _, cause := range unpackAggregates(causes) {
+		if cause != nil {
+			all = append(all, cause)
+		}
+	}
+	e.Cause = nil
+	d := e.edit()
+	d.causes = nil
+	if len(all) > 1 {
+		d.causes = all
+	} else if len(all) == 1 {
+		e.Cause = all[0]
+	}
+	e.changed()
+	return e
+}
+
+/*
+Unwrap() returns the underlying causes of the error, so that errors.Is()
+and errors.As() can search the whole chain, including every branch of
+errors with several causes.
+*/
+func (e Error) Unwrap() []error {
+	if len(e.causes()) > 0 {
+		return e.causes()
+	}
 	if e.Cause == nil {
-		return e.msg
+		return nil
+	}
+	return []error{e.Cause}
+}
+
+/*
+Is() reports whether the target is this error, or an error derived from
+it with With() and the like, so that errors.Is() matches the sentinel
+errors created with New() whatever was added to them.
+
+Example:
+
+	var ERROR_NOT_FOUND = errstack.New("not found")
+
+	if errors.Is(err, ERROR_NOT_FOUND) { ... }
+*/
+func (e Error) Is(target error) bool {
+	t, ok := target.(Error)
+	return ok && e.origin != nil && e.origin == t.origin
+}
+
+/*
+RootCause() returns the innermost error of the chain, where the failure
+originated: the first error without a cause, or the outside error that
+caused a stacked error. Errors with several causes are their own root
+cause, their branches having a root cause each.
+*/
+func (e Error) RootCause() error {
+	for len(e.causes()) == 0 && e.Cause != nil {
+		cause, ok := e.Cause.(Error)
+		if !ok {
+			return e.Cause
+		}
+		e = cause
 	}
-	return fmt.Sprintf("%s -> %s", *(e.Cause), e.msg)
+	return e
 }
 
 /*
@@ -56,92 +239,148 @@ Sample format:
 
 Example usage:
 
-	func Example() handlederror.Error{
+	func Example() error {
 		val, err := someFunctionCall()
 		if err != nil { // good old if err != nil
-			return handlederror.New("oops, something went wrong.", err)
+			return errstack.New("oops, something went wrong.", err)
 		}
 	}
 
-	var errMsg := Example().PrintableError() // this prints
+	errMsg := Example().(errstack.Error).PrintableError()
+
+The trace is rendered with DefaultTraceFormat.
 */
 func (e Error) PrintableError() string {
-	if se, ok := (*e.RootCause).(Error); ok {
-		return fmt.Sprintf(
-			"error:\n\t%s\n\nRoot cause:\n\t%s\n\nFull error trace:\n%s",
-			e.msg,
-			se.msg,
-			e.errorTrace(false),
-		)
-	}
-	return fmt.Sprintf(
-		"error:\n\t%s\n\nRoot cause:\n\t%s\n\nFull error trace:\n%s",
-		e.msg,
-		*(e.RootCause),
-		e.errorTrace(false),
-	)
+	cache := e.cache()
+	if cache == nil {
+		return DefaultTraceFormat.Render(e)
+	}
+	m, ok := cache.printable.load()
+	if ok {
+		return m.s
+	}
+	return cache.printable.store(m, DefaultTraceFormat.Render(e))
 }
 
 /*
-This returns the error trace as a printable string
+WriteTo() writes the same as PrintableError() to w, streaming it line by
+line (see TraceFormat.WriteTrace()), and implements io.WriterTo.
 */
-func (e Error) errorTrace(isCause bool) string {
-	// if he is a cause
-	if isCause {
-		// if he has a cause
-		if e.Cause != nil {
-			// is the cause a stackedError?
-			if cause_, ok := (*e.Cause).(Error); ok {
-				// if it is, include its stack trace in the returned message
-				return fmt.Sprintf("\tcaused by: %s\n%s", e.msg, cause_.errorTrace(true))
-			}
-			// if not a stackedError, return the normal message, decorated.
-			return fmt.Sprintf("\tcaused by: %s\n\tcaused by: %s", e.msg, *e.Cause)
-		}
-		return fmt.Sprintf("\tcaused by: %s", e.msg)
-	}
+func (e Error) WriteTo(w io.Writer) (int64, error) {
+	return DefaultTraceFormat.WriteTrace(w, e)
+}
 
-	// if we are here, then he is not a cause.
-	// if he *has* a cause
-	if e.Cause != nil {
-		// is the cause a stackedError?
-		if cause_, ok := (*e.Cause).(Error); ok {
-			// if it is, include its stack trace in the returned message
-			return fmt.Sprintf("\t%s\n%s", e.msg, cause_.errorTrace(true))
-		}
-		// if not a stackedError, return the normal message, decorated.
-		return fmt.Sprintf("\t%s\n\tcaused by: %s", e.msg, *(e.Cause))
+// this returns the message of the root cause of any error
+func rootMsg(err error) string {
+	se, ok := err.(Error)
+	if !ok {
+		return Redact(err.Error())
+	}
+	if root, ok := se.RootCause().(Error); ok {
+		return Redact(root.msg)
 	}
-	return fmt.Sprintf("\t%s", e.msg)
+	return Redact(se.RootCause().Error())
 }
 
-// this instanciates a stackedError
-func New(msg string, cause ...error) error {
-	returnedErr := new(error) // instantiate an error pointer
-	if len(cause) == 0 {      // if no cause was provided
-		*returnedErr = Error{ // set the error pointer's pointed value to a stackedError
-			msg:       msg,
-			RootCause: returnedErr,
-			Cause:     nil, // this error has no cause, it's a root cause
+/*
+Join() returns an error with all the non-nil provided errors as causes,
+or nil if there are none. Unlike a chain of single causes, the causes
+of a joined error are rendered as separate branches by PrintableError().
+Errors that were themselves returned by Join() are flattened, as are the
+aggregates unpacked by the functions registered with RegisterAggregate().
+
+Example:
+
+	var errs []error
+	for _, f := range files {
+		errs = append(errs, f.Close())
+	}
+	return errstack.Join(errs...)
+*/
+func Join(errs ...error) error {
+	causes := make([]error, 0, len(errs))
+	for _, err := range unpackAggregates(errs) {
+		if se, ok := err.(Error); ok && se.joined {
+			causes = append(causes, se.causes()...)
+		} else if err != nil {
+			causes = append(causes, err)
 		}
-		return *returnedErr // return the struct
 	}
+	if len(causes) == 0 {
+		return nil
+	}
+	msg := fmt.Sprintf("%d errors occurred", len(causes))
+	if len(causes) == 1 {
+		msg = "1 error occurred"
+	}
+	return Error{
+		msg:     msg,
+		joined:  true,
+		origin:  new(byte),
+		details: &details{causes: causes, stack: callers(msg)},
+		memo:    newMemo(),
+	}
+}
 
-	// if we are here, a cause was provided
-	// if the cause is a stackedError
-	if hc, isCauseStacked := (cause[0]).(Error); isCauseStacked {
-		*returnedErr = Error{
-			msg:       msg,
-			RootCause: hc.RootCause,
-			Cause:     &cause[0],
+/*
+This rebuilds the chain of the error, applying f to every stacked error
+of the chain (causes first).
+*/
+func rebuild(err error, f func(Error) Error) error {
+	e, ok := err.(Error)
+	if !ok {
+		return err
+	}
+	if len(e.causes()) > 0 {
+		causes := make([]error, len(e.causes()))
+		for i, cause := range e.causes() {
+			causes[i] = rebuild(cause, f)
 		}
-		return *returnedErr
+		e.edit().causes = causes
+	} else if e.Cause != nil {
+		e.Cause = rebuild(e.Cause, f)
 	}
-	// if we are here, the cause is an outside error.
-	*returnedErr = Error{
-		msg:       msg,
-		RootCause: returnedErr,
-		Cause:     &cause[0],
+	e.changed()
+	return f(e)
+}
+
+/*
+this instanciates a stackedError. When several causes are provided,
+each of them is kept as a separate branch of the error.
+
+New() used to return an error holding the Error, and now returns the
+Error itself, so that the builders can be chained. Sentinel errors are
+compared as before: an Error is equal to its copies, so that
+
+	var ErrNotFound = errstack.New("not found")
+	...
+	if err == ErrNotFound {
+
+holds for the sentinel itself, returned or thrown as is. The errors
+derived from it with the builders (With(), WithCode() and the like) are
+new values, matched by errors.Is() rather than ==. Declaring the
+sentinel as an error keeps the old type:
+
+	var ErrNotFound error = errstack.New("not found")
+*/
+func New(msg string, cause ...error) Error {
+	e := newError(msg, cause...)
+	e.edit().stack = callers(msg)
+	return e
+}
+
+/*
+this builds the stackedError returned by New(). The errors hold their
+causes by value, so that copying or deriving an error never alters the
+chain of another one.
+*/
+func newError(msg string, cause ...error) Error {
+	e := Error{msg: msg, origin: new(byte), memo: newMemo()}
+	cause = unpackAggregates(cause)
+	if len(cause) > 1 { // the causes branch out from this error
+		e.edit().causes = append([]error{}, cause...)
+	} else if len(cause) == 1 {
+		e.Cause = cause[0]
 	}
-	return *returnedErr
+	return e
 }
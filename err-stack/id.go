@@ -0,0 +1,96 @@
+package errstack
+
+import (
+	"crypto/rand"
+	"encoding/hex"
+)
+
+/*
+NewID is the generator of the correlation IDs of the errors, called by
+Identify() for every occurrence of an error that is reported. It
+defaults to 16 random hexadecimal characters, and can be replaced, e.g.
+to use the IDs of a tracing system.
+*/
+var NewID = func() string {
+	b := make([]byte, 8)
+	rand.Read(b)
+	return hex.EncodeToString(b)
+}
+
+/*
+ID() returns the correlation ID of this error, set with WithID() or
+Identify(), or "" if it has none. It is printed by the renderers and
+returned to clients by the HTTP and gRPC integrations, so that support
+can match an ID reported by a user to the log entries of the error.
+*/
+func (e Error) ID() string {
+	return e.id
+}
+
+/*
+WithID() returns a copy of the error with the provided correlation ID,
+typically the ID of the request that failed.
+
+Example:
+
+	return errstack.New("could not place order", err).WithID(r.Header.Get("X-Request-ID"))
+*/
+func (e Error) WithID(id string) Error {
+	e.id = id
+	e.changed()
+	return e
+}
+
+/*
+ID() returns the correlation ID of any error: the one of the outermost
+stacked or identified error of its chain, or "" if it has none.
+*/
+func ID(err error) string {
+	id := ""
+	walk(err, func(err error) bool {
+		switch err := err.(type) {
+		case Error:
+			id = err.id
+		case identified:
+			id = err.id
+		}
+		return id != ""
+	})
+	return id
+}
+
+/*
+Identify() returns the error with a correlation ID for this occurrence
+of it: the error itself if its chain already has one (see ID()), or
+else a copy of it with an ID generated by NewID(), wrapping it if it is
+not a stacked error. The integrations reporting errors (HTTP responses,
+gRPC statuses, error trackers) identify them this way, once per
+occurrence, so that every throw of a sentinel error gets its own ID
+while the sentinel itself has none, and still compares equal to its
+copies.
+
+Example:
+
+	err = errstack.Identify(err)
+	log.Error("request failed", "err", err, "id", errstack.ID(err))
+	http.Error(w, "internal error, ID "+errstack.ID(err), http.StatusInternalServerError)
+*/
+func Identify(err error) error {
+	if err == nil || ID(err) != "" {
+		return err
+	}
+	if se, ok := err.(Error); ok {
+		return se.WithID(NewID())
+	}
+	return identified{err, NewID()}
+}
+
+// this is an outside error identified by Identify()
+type identified struct {
+	error
+	id string
+}
+
+func (e identified) Unwrap() error {
+	return e.error
+}
@@ -0,0 +1,152 @@
+package errstack
+
+import (
+	"encoding/json"
+	"errors"
+)
+
+/*
+This is the JSON document of an error. Outside errors only carry their
+message, and are marked as external.
+*/
+type jsonError struct {
+	Message       string         `json:"message"`
+	ID            string         `json:"id,omitempty"`
+	External      bool           `json:"external,omitempty"`
+	Code          string         `json:"code,omitempty"`
+	Severity      string         `json:"severity,omitempty"`
+	PublicMessage string         `json:"public_message,omitempty"`
+	Retryable     bool           `json:"retryable,omitempty"`
+	Timeout       bool           `json:"timeout,omitempty"`
+	Fields        map[string]any `json:"fields,omitempty"`
+	Frames        []Frame        `json:"frames,omitempty"`
+	Cause         *jsonError     `json:"cause,omitempty"`
+	Causes        []*jsonError   `json:"causes,omitempty"`
+	Metadata      *Metadata      `json:"metadata,omitempty"` // on the outermost error only
+}
+
+/*
+MarshalJSON() encodes the error and its whole cause chain as a nested
+JSON document, so that errors can be shipped to log aggregators in a
+structured form:
+
+	{
+	  "message": "open config",
+	  "id": "9f86d081884c7d65",
+	  "code": "CONFIG_UNREADABLE",
+	  "fields": {"path": "app.yaml"},
+	  "frames": [{"function": "main.load", "file": "/src/main.go", "line": 12}],
+	  "cause": {"message": "ENOENT", "external": true}
+	}
+
+Errors with several causes have a "causes" array instead of "cause". The
+metadata of the program set with SetMetadata(), if any, is included as
+the "metadata" object of the outermost error.
+*/
+func (e Error) MarshalJSON() ([]byte, error) {
+	doc := toJSON(e)
+	if m := CurrentMetadata(); !m.empty() {
+		doc.Metadata = &m
+	}
+	return json.Marshal(doc)
+}
+
+/*
+UnmarshalJSON() decodes an error encoded with MarshalJSON(). Outside
+errors of the chain are decoded as plain errors carrying their message,
+and the call stacks are available through StackFrames().
+*/
+func (e *Error) UnmarshalJSON(data []byte) error {
+	doc := &jsonError{}
+	if err := json.Unmarshal(data, doc); err != nil {
+		return err
+	}
+	decoded, ok := fromJSON(doc).(Error)
+	if !ok {
+		return New("the outermost error of the document is external")
+	}
+	*e = decoded
+	return nil
+}
+
+/*
+Decode() decodes an error encoded with MarshalJSON(), which may also be
+a single outside error.
+*/
+func Decode(data []byte) (error, error) {
+	doc := &jsonError{}
+	if err := json.Unmarshal(data, doc); err != nil {
+		return nil, New("could not decode error", err)
+	}
+	return fromJSON(doc), nil
+}
+
+// this converts an error of the chain to its JSON document
+func toJSON(err error) *jsonError {
+	e, ok := err.(Error)
+	if !ok {
+		return &jsonError{Message: Redact(err.Error()), External: true}
+	}
+	doc := &jsonError{
+		Message:       Redact(e.msg),
+		ID:            e.id,
+		Code:          e.code,
+		PublicMessage: e.public,
+		Retryable:     e.retryable,
+		Timeout:       e.timeout,
+		Frames:        e.StackFrames(),
+	}
+	if e.severity != 0 {
+		doc.Severity = e.severity.String()
+	}
+	if len(e.fields()) > 0 {
+		doc.Fields = map[string]any{}
+		for _, field := range e.Fields() {
+			doc.Fields[field.Key] = field.Value
+		}
+	}
+	if len(e.causes()) > 0 {
+		for _, cause := range e.causes() {
+			doc.Causes = append(doc.Causes, toJSON(cause))
+		}
+	} else if e.Cause != nil {
+		doc.Cause = toJSON(e.Cause)
+	}
+	return doc
+}
+
+// this rebuilds an error of the chain from its JSON document
+func fromJSON(doc *jsonError) error {
+	if doc.External {
+		return errors.New(doc.Message)
+	}
+	causes := []error{}
+	if doc.Cause != nil {
+		causes = append(causes, fromJSON(doc.Cause))
+	}
+	for _, cause := range doc.Causes {
+		causes = append(causes, fromJSON(cause))
+	}
+	e := newError(doc.Message, causes...)
+	e.id = doc.ID
+	e.code = doc.Code
+	e.public = doc.PublicMessage
+	e.retryable = doc.Retryable
+	e.timeout = doc.Timeout
+	e.edit().frames = doc.Frames
+	e.severity = parseLevel(doc.Severity)
+	for key, value := range doc.Fields {
+		e = e.With(key, value)
+	}
+	return e
+}
+
+// this parses the name of a severity level, returning 0 if unknown
+func parseLevel(name string) Level {
+	for level := SeverityDebug; level <= SeverityFatal; level++ {
+		if level.String() == name {
+			return level
+		}
+	}
+	return 0
+}
@@ -0,0 +1,106 @@
+package errstack
+
+import (
+	"fmt"
+	"strings"
+	"sync"
+	"text/template"
+)
+
+var (
+	bundlesMu sync.RWMutex
+	bundles   = map[string]map[string]*template.Template{}
+)
+
+/*
+RegisterBundle() registers the messages of a locale, keyed by message
+key. Messages are text/template templates, executed with the parameters
+provided to WithKey(). The public message of a key is looked up under
+"<key>.public".
+
+Example:
+
+	errstack.RegisterBundle("fr", map[string]string{
+		"order.exists":        "la commande {{.id}} existe déjà",
+		"order.exists.public": "Cette commande a déjà été passée.",
+	})
+*/
+func RegisterBundle(locale string, messages map[string]string) error {
+	templates := map[string]*template.Template{}
+	for key, msg := range messages {
+		tmpl, err := template.New(key).Parse(msg)
+		if err != nil {
+			return New(fmt.Sprintf("invalid message %s in bundle %s", key, locale), err)
+		}
+		templates[key] = tmpl
+	}
+	bundlesMu.Lock()
+	defer bundlesMu.Unlock()
+	if bundles[locale] == nil {
+		bundles[locale] = map[string]*template.Template{}
+	}
+	for key, tmpl := range templates {
+		bundles[locale][key] = tmpl
+	}
+	return nil
+}
+
+/*
+WithKey() returns a copy of the error identified by a message key, so
+that its message and public message can be translated by Localize().
+The parameters are passed to the message templates.
+
+Example:
+
+	return errstack.New("order already exists").
+		WithKey("order.exists", map[string]any{"id": id})
+*/
+func (e Error) WithKey(key string, params map[string]any) Error {
+	e.key = key
+	e.edit().params = params
+	e.changed()
+	return e
+}
+
+/*
+Localize() returns a copy of the error chain in which the messages and
+public messages of the errors with a message key are translated to the
+provided locale. If the locale has no bundle, its language is tried
+("fr" for "fr-CA"), and otherwise messages are left untouched.
+
+Example:
+
+	fmt.Println(errstack.Localize(err, "fr-CA").(errstack.Error).PrintableError())
+*/
+func Localize(err error, locale string) error {
+	return rebuild(err, func(e Error) Error {
+		if e.key == "" {
+			return e
+		}
+		if msg, ok := translate(locale, e.key, e.params()); ok {
+			e.msg = msg
+		}
+		if public, ok := translate(locale, e.key+".public", e.params()); ok {
+			e.public = public
+		}
+		return e
+	})
+}
+
+// translate() renders the message of the key in the locale, if any.
+func translate(locale string, key string, params map[string]any) (string, bool) {
+	bundlesMu.RLock()
+	defer bundlesMu.RUnlock()
+	for _, l := range []string{locale, strings.SplitN(locale, "-", 2)[0]} {
+		tmpl, ok := bundles[l][key]
+		if !ok {
+			continue
+		}
+		var msg strings.Builder
+		if err := tmpl.Execute(&msg, params); err != nil {
+			return "", false
+		}
+		return msg.String(), true
+	}
+	return "", false
+}
@@ -0,0 +1,120 @@
+package errstack
+
+import (
+	"fmt"
+	"html"
+	"strconv"
+	"strings"
+)
+
+/*
+SourceURL is the template of the links to the source of the frames of
+the call stacks rendered by RenderMarkdown() and RenderHTML(), in which
+"{file}" and "{line}" are replaced by the frame's. The frames are not
+links if it is empty.
+
+Example:
+
+	errstack.SourceURL = "https://github.com/acme/shop/blob/v1.4.2/{file}#L{line}"
+	errstack.SourceRoot = "/home/ci/src/shop/"
+*/
+var SourceURL = ""
+
+/*
+SourceRoot is trimmed from the paths of the files of the frames before
+they are put in SourceURL, typically the directory the program was built
+in.
+*/
+var SourceRoot = ""
+
+/*
+RenderMarkdown() renders any error in Markdown, for issue trackers: its
+message, root cause and ID, then its trace (in DefaultTraceFormat) and
+its call stack in collapsible sections.
+*/
+func RenderMarkdown(err error) string {
+	f := DefaultTraceFormat.withDefaults()
+	f.Color = false
+	lines := []string{"**" + markdownEscape(message(err)) + "**", ""}
+	for _, root := range rootMsgs(err) {
+		lines = append(lines, "Root cause: `"+strings.ReplaceAll(root, "`", "'")+"`  ")
+	}
+	if id := ID(err); id != "" {
+		lines = append(lines, "Error ID: `"+strings.ReplaceAll(id, "`", "'")+"`  ")
+	}
+	lines = append(lines,
+		"",
+		"<details>",
+		"<summary>Full error trace</summary>",
+		"",
+		"```",
+		f.fullTrace(err),
+		"```",
+		"",
+		"</details>",
+	)
+	if frames := stackFrames(err); len(frames) > 0 {
+		lines = append(lines, "", "<details>", "<summary>Stack trace</summary>", "")
+		for _, frame := range frames {
+			location := fmt.Sprintf("%s:%d", frame.File, frame.Line)
+			if url := sourceURL(frame); url != "" {
+				location = fmt.Sprintf("[%s](%s)", location, url)
+			}
+			lines = append(lines, fmt.Sprintf("- `%s` %s", frame.Function, location))
+		}
+		lines = append(lines, "", "</details>")
+	}
+	return strings.Join(lines, "\n")
+}
+
+/*
+RenderHTML() renders any error as an HTML fragment, for dashboards: its
+message, root cause and ID, then its trace (in DefaultTraceFormat) and
+its call stack in collapsible sections. The fragment is a div of the class
+"errstack".
+*/
+func RenderHTML(err error) string {
+	f := DefaultTraceFormat.withDefaults()
+	f.Color = false
+	b := &strings.Builder{}
+	b.WriteString(`<div class="errstack">` + "\n")
+	fmt.Fprintf(b, "<p><strong>%s</strong></p>\n", html.EscapeString(message(err)))
+	for _, root := range rootMsgs(err) {
+		fmt.Fprintf(b, "<p>Root cause: <code>%s</code></p>\n", html.EscapeString(root))
+	}
+	if id := ID(err); id != "" {
+		fmt.Fprintf(b, "<p>Error ID: <code>%s</code></p>\n", html.EscapeString(id))
+	}
+	fmt.Fprintf(b, "<details><summary>Full error trace</summary><pre>%s</pre></details>\n", html.EscapeString(f.fullTrace(err)))
+	if frames := stackFrames(err); len(frames) > 0 {
+		b.WriteString("<details><summary>Stack trace</summary><ol>\n")
+		for _, frame := range frames {
+			location := html.EscapeString(fmt.Sprintf("%s:%d", frame.File, frame.Line))
+			if url := sourceURL(frame); url != "" {
+				location = fmt.Sprintf(`<a href="%s">%s</a>`, html.EscapeString(url), location)
+			}
+			fmt.Fprintf(b, "<li><code>%s</code> %s</li>\n", html.EscapeString(frame.Function), location)
+		}
+		b.WriteString("</ol></details>\n")
+	}
+	b.WriteString("</div>")
+	return b.String()
+}
+
+// this returns the link to the source of the frame, or ""
+func sourceURL(frame Frame) string {
+	if SourceURL == "" {
+		return ""
+	}
+	return strings.NewReplacer(
+		"{file}", strings.TrimPrefix(frame.File, SourceRoot),
+		"{line}", strconv.Itoa(frame.Line),
+	).Replace(SourceURL)
+}
+
+// this escapes the characters of a message that Markdown would interpret
+func markdownEscape(msg string) string {
+	return strings.NewReplacer(
+		`\`, `\\`, "*", `\*`, "_", `\_`, "`", "\\`", "[", `\[`, "]", `\]`, "<", "&lt;",
+	).Replace(msg)
+}
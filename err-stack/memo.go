@@ -0,0 +1,120 @@
+package errstack
+
+import "sync/atomic"
+
+/*
+This caches the rendered forms of an error, so that the errors logged at
+several layers, whose causes are rendered again by each of them, are
+rendered once. It is shared by the copies of the error, and replaced by
+the builders (see changed()).
+
+Only the errors whose chains hold nothing but stacked errors are cached:
+the outside errors may change, and are rendered every time.
+*/
+type memo struct {
+	text      memoSlot     // Error()
+	printable memoSlot     // PrintableError()
+	stacked   atomic.Int32 // whether the chain only holds stacked errors, once known
+}
+
+// the values of memo.stacked
+const (
+	stackedUnknown int32 = iota
+	stackedOnly
+	stackedNot
+)
+
+/*
+This is a cached rendered form. It is only reused if the settings it was
+rendered with did not change since.
+*/
+type memoSlot struct {
+	atomic.Pointer[memoized]
+}
+
+// this is a rendered form of an error, with the settings it was rendered with
+type memoized struct {
+	generation uint64
+	format     TraceFormat
+	depth      int
+	s          string
+}
+
+/*
+the generation of the settings of the package, bumped when redaction
+patterns, redacted fields or templates are registered
+*/
+var generation atomic.Uint64
+
+// this marks the settings of the package as changed, discarding the caches
+func settingsChanged() {
+	generation.Add(1)
+}
+
+// this returns a cache for a new or changed error, nil if caching is off
+func newMemo() *memo {
+	if !memoize {
+		return nil
+	}
+	return &memo{}
+}
+
+// this returns the cache of the error, nil if its forms must not be cached
+func (e Error) cache() *memo {
+	if e.memo == nil || !e.onlyStacked() {
+		return nil
+	}
+	return e.memo
+}
+
+// this reports whether the chain of the error only holds stacked errors
+func (e Error) onlyStacked() bool {
+	if e.memo != nil {
+		switch e.memo.stacked.Load() {
+		case stackedOnly:
+			return true
+		case stackedNot:
+			return false
+		}
+	}
+	only := true
+	for _, cause := range e.Unwrap() {
+		if se, ok := cause.(Error); !ok || !se.onlyStacked() {
+			only = false
+			break
+		}
+	}
+	if e.memo != nil {
+		if only {
+			e.memo.stacked.Store(stackedOnly)
+		} else {
+			e.memo.stacked.Store(stackedNot)
+		}
+	}
+	return only
+}
+
+// this discards the rendered forms cached for the error, as it changed
+func (e *Error) changed() {
+	e.memo = newMemo()
+}
+
+/*
+this returns the cached form if it is up to date, or else the current
+settings, to store a new rendering with
+*/
+func (s *memoSlot) load() (memoized, bool) {
+	current := memoized{generation: generation.Load(), format: DefaultTraceFormat, depth: MaxTraceDepth}
+	m := s.Load()
+	if m != nil && m.generation == current.generation && m.format == current.format && m.depth == current.depth {
+		return *m, true
+	}
+	return current, false
+}
+
+// this caches a form rendered with the settings returned by load()
+func (s *memoSlot) store(m memoized, rendered string) string {
+	m.s = rendered
+	s.Store(&m)
+	return rendered
+}
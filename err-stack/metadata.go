@@ -0,0 +1,82 @@
+package errstack
+
+import (
+	"os"
+	"path"
+	"runtime/debug"
+	"sync"
+)
+
+/*
+Metadata describes the program the errors occur in, so that the errors
+it reports are attributable without tagging them at each call site. It
+is set once with SetMetadata(), and included in the JSON documents of
+the errors (see MarshalJSON()) and in the events of the reporting
+integrations.
+*/
+type Metadata struct {
+	Service  string `json:"service,omitempty"`
+	Version  string `json:"version,omitempty"`
+	Commit   string `json:"commit,omitempty"`
+	Hostname string `json:"hostname,omitempty"`
+	Region   string `json:"region,omitempty"`
+}
+
+var (
+	metadataMu sync.RWMutex
+	metadata   Metadata
+)
+
+/*
+SetMetadata() sets the metadata of the program, none being set by
+default.
+
+Example:
+
+	meta := errstack.BuildMetadata()
+	meta.Region = os.Getenv("REGION")
+	errstack.SetMetadata(meta)
+*/
+func SetMetadata(m Metadata) {
+	metadataMu.Lock()
+	defer metadataMu.Unlock()
+	metadata = m
+}
+
+// CurrentMetadata() returns the metadata set with SetMetadata().
+func CurrentMetadata() Metadata {
+	metadataMu.RLock()
+	defer metadataMu.RUnlock()
+	return metadata
+}
+
+/*
+BuildMetadata() returns the metadata that can be found out from the
+program itself: the name of its main package as the service, its module
+version, the VCS revision it was built from, and the host name.
+*/
+func BuildMetadata() Metadata {
+	m := Metadata{}
+	m.Hostname, _ = os.Hostname()
+	info, ok := debug.ReadBuildInfo()
+	if !ok {
+		return m
+	}
+	if info.Path != "" {
+		m.Service = path.Base(info.Path)
+	}
+	if info.Main.Version != "(devel)" {
+		m.Version = info.Main.Version
+	}
+	for _, setting := range info.Settings {
+		if setting.Key == "vcs.revision" {
+			m.Commit = setting.Value
+		}
+	}
+	return m
+}
+
+// this reports whether no metadata is set
+func (m Metadata) empty() bool {
+	return m == Metadata{}
+}
@@ -0,0 +1,76 @@
+package errstack
+
+import (
+	"fmt"
+	"strings"
+)
+
+/*
+Newf() instanciates a stackedError with a message formatted with
+fmt.Sprintf(). As with fmt.Errorf(), the errors formatted with the %w
+verb become the causes of the returned error. Their messages are left
+out of the message of the error, along with the separators preceding
+the trailing ones, as they are already rendered as its causes:
+
+	errstack.Newf("could not open %s: %w", "app.yaml", err)
+
+has the message "could not open app.yaml".
+*/
+func Newf(format string, args ...any) Error {
+	formatted := fmt.Errorf(format, args...)
+	switch wrapped := formatted.(type) {
+	case interface{ Unwrap() []error }:
+		return New(withoutWrapped(format, args), wrapped.Unwrap()...)
+	case interface{ Unwrap() error }:
+		return New(withoutWrapped(format, args), wrapped.Unwrap())
+	}
+	return New(formatted.Error())
+}
+
+/*
+this formats the message of Newf() without the errors formatted with %w,
+formatting them with an empty precision instead
+*/
+func withoutWrapped(format string, args []any) string {
+	b := strings.Builder{}
+	for i := 0; i < len(format); i++ {
+		if format[i] != '%' {
+			b.WriteByte(format[i])
+			continue
+		}
+		// the verb is the first letter of the directive, or the second %
+		end := i + 1
+		for end < len(format) && format[end] != '%' && !isLetter(format[end]) {
+			end++
+		}
+		if end < len(format) && format[end] == 'w' {
+			b.WriteString("%.0v")
+		} else {
+			b.WriteString(format[i:min(end+1, len(format))])
+		}
+		i = end
+	}
+	return strings.TrimRight(fmt.Sprintf(b.String(), args...), ":;, ")
+}
+
+// this reports whether the byte is an ASCII letter
+func isLetter(c byte) bool {
+	return 'a' <= c && c <= 'z' || 'A' <= c && c <= 'Z'
+}
+
+/*
+NewfCause() behaves like Newf(), with the provided error as the cause of
+the returned error. The cause comes first, so that go vet can still
+check the format against its arguments.
+
+Example:
+
+	return errstack.NewfCause(err, "could not open %s", path)
+*/
+func NewfCause(cause error, format string, args ...any) Error {
+	msg := fmt.Sprintf(format, args...)
+	if cause == nil {
+		return New(msg)
+	}
+	return New(msg, cause)
+}
@@ -0,0 +1,13 @@
+//go:build errhandling_nostack
+
+package errstack
+
+/*
+CaptureStacks reports whether the errors capture the call stack where
+they are created. It is false, as the program is built with the
+errhandling_nostack build tag.
+*/
+const CaptureStacks = false
+
+// the rendered forms of the errors are not cached without stacks
+const memoize = false
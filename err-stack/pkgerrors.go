@@ -0,0 +1,57 @@
+package errstack
+
+import "reflect"
+
+/*
+StackTrace() returns the program counters of the call stack captured
+when the error was created, so that the error reporters looking for a
+StackTrace() method, like those supporting the errors of
+github.com/pkg/errors, handle stacked errors as well. It returns nil for
+the errors decoded from JSON, whose call stacks were resolved.
+*/
+func (e Error) StackTrace() []uintptr {
+	if len(e.stack()) == 0 {
+		return nil
+	}
+	return append([]uintptr{}, e.stack()...)
+}
+
+/*
+this returns the call stack captured by the innermost outside error of
+the chain of any error implementing StackTrace(), such as the errors of
+github.com/pkg/errors, or nil if there is none
+*/
+func externalFrames(err error) []Frame {
+	chain := Chain(err)
+	for i := len(chain) - 1; i >= 0; i-- {
+		if frames := tracedFrames(chain[i]); len(frames) > 0 {
+			return frames
+		}
+	}
+	return nil
+}
+
+/*
+this returns the call stack of an outside error implementing
+StackTrace(). The method is found by reflection, as the errors of
+github.com/pkg/errors return their own StackTrace type, a slice of
+program counters that this package does not import.
+*/
+func tracedFrames(err error) []Frame {
+	if _, ok := err.(Error); ok {
+		return nil
+	}
+	method := reflect.ValueOf(err).MethodByName("StackTrace")
+	if !method.IsValid() || method.Type().NumIn() != 0 || method.Type().NumOut() != 1 {
+		return nil
+	}
+	if out := method.Type().Out(0); out.Kind() != reflect.Slice || out.Elem().Kind() != reflect.Uintptr {
+		return nil
+	}
+	trace := method.Call(nil)[0]
+	pcs := make([]uintptr, trace.Len())
+	for i := range pcs {
+		pcs[i] = uintptr(trace.Index(i).Uint())
+	}
+	return framesOf(pcs)
+}
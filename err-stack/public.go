@@ -0,0 +1,34 @@
+package errstack
+
+/*
+WithPublicMessage() returns a copy of the error carrying a message that
+is safe to display to users, alongside the detailed internal chain.
+Renderers meant for users (HTTP responses, CLI output) show the public
+message, while logs get the full trace.
+
+Example:
+
+	return errstack.New("INSERT INTO orders failed", err).
+		WithPublicMessage("Your order could not be saved, please retry.")
+*/
+func (e Error) WithPublicMessage(msg string) Error {
+	e.public = msg
+	e.changed()
+	return e
+}
+
+/*
+PublicMessage() returns the public message of the outermost error of the
+chain that has one, or "" if there is none.
+*/
+func PublicMessage(err error) string {
+	msg := ""
+	walk(err, func(err error) bool {
+		if se, ok := err.(Error); ok && se.public != "" {
+			msg = se.public
+			return true
+		}
+		return false
+	})
+	return msg
+}
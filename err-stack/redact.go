@@ -0,0 +1,160 @@
+package errstack
+
+import (
+	"fmt"
+	"log/slog"
+	"regexp"
+	"strings"
+	"sync"
+)
+
+// Redacted replaces the sensitive data in the messages and fields of errors.
+const Redacted = "[REDACTED]"
+
+var (
+	redactMu       sync.RWMutex
+	redactPatterns []*regexp.Regexp
+	redactFields   = map[string]int{}
+)
+
+/*
+RedactPattern() registers a pattern whose matches are replaced with
+"[REDACTED]" in the messages of the errors and in the values of their
+fields (as printed with fmt.Sprint() for the values that are not
+strings), wherever they are printed: Error(), PrintableError(), the JSON
+documents, the slog values and the reporters built on them. It returns
+a function unregistering the pattern, e.g. at the end of a test.
+
+Example:
+
+	errstack.RedactPattern(regexp.MustCompile(`(?i)password=[^&\s]+`))
+	errstack.RedactPattern(regexp.MustCompile(`postgres://[^\s]+`))
+*/
+func RedactPattern(pattern *regexp.Regexp) (unregister func()) {
+	redactMu.Lock()
+	defer redactMu.Unlock()
+	redactPatterns = append(redactPatterns, pattern)
+	settingsChanged()
+	var once sync.Once
+	return func() {
+		once.Do(func() {
+			redactMu.Lock()
+			defer redactMu.Unlock()
+			for i, registered := range redactPatterns {
+				if registered == pattern {
+					redactPatterns = append(redactPatterns[:i:i], redactPatterns[i+1:]...)
+					break
+				}
+			}
+			settingsChanged()
+		})
+	}
+}
+
+/*
+RedactField() registers the keys of the fields whose values are always
+replaced with "[REDACTED]", whatever their type. Keys are compared
+regardless of case. It returns a function unregistering the keys, like
+RedactPattern().
+
+Example:
+
+	errstack.RedactField("password", "token", "dsn")
+*/
+func RedactField(keys ...string) (unregister func()) {
+	redactMu.Lock()
+	defer redactMu.Unlock()
+	for _, key := range keys {
+		redactFields[strings.ToLower(key)]++
+	}
+	settingsChanged()
+	var once sync.Once
+	return func() {
+		once.Do(func() {
+			redactMu.Lock()
+			defer redactMu.Unlock()
+			for _, key := range keys {
+				key = strings.ToLower(key)
+				if redactFields[key]--; redactFields[key] <= 0 {
+					delete(redactFields, key)
+				}
+			}
+			settingsChanged()
+		})
+	}
+}
+
+/*
+Redact() replaces the matches of the patterns registered with
+RedactPattern() in the string, for the integrations printing the
+messages of outside errors themselves.
+*/
+func Redact(s string) string {
+	redactMu.RLock()
+	defer redactMu.RUnlock()
+	for _, pattern := range redactPatterns {
+		s = pattern.ReplaceAllString(s, Redacted)
+	}
+	return s
+}
+
+/*
+Secret is a string that is never printed: it formats, encodes and logs
+as "[REDACTED]", so that it can be attached to errors as a field without
+leaking. Its value is recovered by converting it back to a string.
+
+Example:
+
+	return errstack.New("could not connect", err).With("dsn", errstack.Secret(dsn))
+*/
+type Secret string
+
+func (s Secret) String() string {
+	return Redacted
+}
+
+func (s Secret) GoString() string {
+	return Redacted
+}
+
+func (s Secret) MarshalText() ([]byte, error) {
+	return []byte(Redacted), nil
+}
+
+func (s Secret) LogValue() slog.Value {
+	return slog.StringValue(Redacted)
+}
+
+// this redacts the value of a field, depending on its key and type
+func redactField(field KeyValue) KeyValue {
+	redactMu.RLock()
+	masked := redactFields[strings.ToLower(field.Key)] > 0
+	patterns := len(redactPatterns) > 0
+	redactMu.RUnlock()
+	switch value := field.Value.(type) {
+	case Secret:
+		masked = true
+	case string:
+		if !masked {
+			field.Value = Redact(value)
+		}
+	default:
+		if !masked && patterns && value != nil {
+			if s := fmt.Sprint(value); Redact(s) != s {
+				field.Value = Redact(s)
+			}
+		}
+	}
+	if masked {
+		field.Value = Redacted
+	}
+	return field
+}
+
+// this redacts the values of the fields
+func redactAll(fields []KeyValue) []KeyValue {
+	for i, field := range fields {
+		fields[i] = redactField(field)
+	}
+	return fields
+}
@@ -0,0 +1,146 @@
+package errstack
+
+import (
+	"container/list"
+	"sync"
+	"sync/atomic"
+	"time"
+)
+
+/*
+Sampler decides whether an expensive operation is performed for an
+error, such as capturing its call stack (see SampleStacks()) or sending
+it to the reporters. It is called with a key identifying the error, and
+returns true if the error is sampled. Samplers are called concurrently.
+*/
+type Sampler func(key string) bool
+
+// the sampler of the call stacks, nil to capture them all
+var stackSampler atomic.Pointer[Sampler]
+
+/*
+SampleStacks() sets the sampler deciding whether the errors created by
+New() and the like capture their call stack, the key being the message
+of the error. The errors that are not sampled carry no frames, so that
+storms of errors cost less, while a representative share of them keep
+their diagnostics. A nil sampler captures every stack, which is the
+default.
+
+Example:
+
+	errstack.SampleStacks(errstack.RateLimit(10))
+*/
+func SampleStacks(s Sampler) {
+	if s == nil {
+		stackSampler.Store(nil)
+		return
+	}
+	stackSampler.Store(&s)
+}
+
+// this reports whether the error with the key captures its call stack
+func sampleStack(key string) bool {
+	s := stackSampler.Load()
+	return s == nil || (*s)(key)
+}
+
+// EveryNth() returns a sampler sampling one error out of n, starting with the first.
+func EveryNth(n int) Sampler {
+	var count atomic.Uint64
+	return func(string) bool {
+		return n <= 1 || (count.Add(1)-1)%uint64(n) == 0
+	}
+}
+
+/*
+PerSecond() returns a sampler sampling at most rate errors per second,
+with bursts of up to rate errors (at least one).
+*/
+func PerSecond(rate float64) Sampler {
+	mu := sync.Mutex{}
+	burst := rate
+	if burst < 1 {
+		burst = 1
+	}
+	tokens, last := burst, time.Now()
+	return func(string) bool {
+		mu.Lock()
+		defer mu.Unlock()
+		now := time.Now()
+		tokens += now.Sub(last).Seconds() * rate
+		if tokens > burst {
+			tokens = burst
+		}
+		last = now
+		if tokens < 1 {
+			return false
+		}
+		tokens--
+		return true
+	}
+}
+
+// the maximum number of keys tracked by the samplers of PerKey()
+const maxSampledKeys = 4096
+
+/*
+PerKey() returns a sampler applying a separate sampler to each key, as
+returned by newSampler, so that every kind of error is sampled, however
+frequent the others. Past 4096 keys, the sampler of the least recently
+seen key is dropped for each new key.
+
+Example:
+
+	// the first occurrence of each fingerprint, then one out of 100
+	sampler := errstack.PerKey(func() errstack.Sampler {
+		return errstack.EveryNth(100)
+	})
+*/
+func PerKey(newSampler func() Sampler) Sampler {
+	mu := sync.Mutex{}
+	samplers := map[string]*list.Element{}
+	recent := list.New() // of the keyed samplers, the most recently seen first
+	return func(key string) bool {
+		mu.Lock()
+		elem, ok := samplers[key]
+		if ok {
+			recent.MoveToFront(elem)
+		} else {
+			if recent.Len() >= maxSampledKeys {
+				delete(samplers, recent.Remove(recent.Back()).(keyedSampler).key)
+			}
+			elem = recent.PushFront(keyedSampler{key, newSampler()})
+			samplers[key] = elem
+		}
+		s := elem.Value.(keyedSampler).sampler
+		mu.Unlock()
+		return s(key)
+	}
+}
+
+// this is the sampler of a key, in the recency list of PerKey()
+type keyedSampler struct {
+	key     string
+	sampler Sampler
+}
+
+/*
+Dedup() returns a sampler sampling the first occurrence of each key, and
+then one occurrence out of n, to be used with fingerprints as keys.
+*/
+func Dedup(n int) Sampler {
+	return PerKey(func() Sampler {
+		return EveryNth(n)
+	})
+}
+
+/*
+RateLimit() returns a sampler sampling at most rate occurrences of each
+key per second, the first occurrence always being sampled, to be used
+with fingerprints as keys.
+*/
+func RateLimit(rate float64) Sampler {
+	return PerKey(func() Sampler {
+		return PerSecond(rate)
+	})
+}
@@ -0,0 +1,54 @@
+package errstack
+
+import (
+	"encoding/base64"
+	"encoding/json"
+)
+
+/*
+Serialize() encodes the error and its whole cause chain in a compact
+form meant to cross process boundaries, e.g. in an HTTP header: the JSON
+document of MarshalJSON() without the call stacks, which only make sense
+in the process that captured them, encoded in unpadded base64url so that
+it is safe in headers. It returns nil if the error is nil.
+*/
+func Serialize(err error) []byte {
+	if err == nil {
+		return nil
+	}
+	doc := toJSON(err)
+	stripFrames(doc)
+	data, jsonErr := json.Marshal(doc)
+	if jsonErr != nil {
+		// some field value cannot be encoded, fall back to the message
+		data, _ = json.Marshal(&jsonError{Message: Redact(err.Error()), External: true})
+	}
+	encoded := make([]byte, base64.RawURLEncoding.EncodedLen(len(data)))
+	base64.RawURLEncoding.Encode(encoded, data)
+	return encoded
+}
+
+/*
+Parse() decodes an error encoded with Serialize(). The errors of the
+chain get back their messages, codes, severities, public messages,
+classification flags and fields.
+*/
+func Parse(data []byte) (error, error) {
+	decoded := make([]byte, base64.RawURLEncoding.DecodedLen(len(data)))
+	n, err := base64.RawURLEncoding.Decode(decoded, data)
+	if err != nil {
+		return nil, New("could not parse error", err)
+	}
+	return Decode(decoded[:n])
+}
+
+// this removes the call stacks from the document
+func stripFrames(doc *jsonError) {
+	doc.Frames = nil
+	if doc.Cause != nil {
+		stripFrames(doc.Cause)
+	}
+	for _, cause := range doc.Causes {
+		stripFrames(cause)
+	}
+}
@@ -0,0 +1,62 @@
+package errstack
+
+import "fmt"
+
+// Level is the severity of an error.
+type Level int
+
+const (
+	SeverityDebug Level = iota + 1
+	SeverityInfo
+	SeverityWarn
+	SeverityError
+	SeverityFatal
+)
+
+func (l Level) String() string {
+	switch l {
+	case SeverityDebug:
+		return "debug"
+	case SeverityInfo:
+		return "info"
+	case SeverityWarn:
+		return "warn"
+	case SeverityError:
+		return "error"
+	case SeverityFatal:
+		return "fatal"
+	}
+	return fmt.Sprintf("Level(%d)", int(l))
+}
+
+/*
+WithSeverity() returns a copy of the error with the provided severity.
+
+Example:
+
+	return errstack.New("cache miss", err).WithSeverity(errstack.SeverityDebug)
+*/
+func (e Error) WithSeverity(level Level) Error {
+	e.severity = level
+	e.changed()
+	return e
+}
+
+/*
+Severity() returns the severity of the error. Severities propagate and
+escalate through wrapping: the highest severity set on any error of the
+chain is returned, and SeverityError if none was set.
+*/
+func Severity(err error) Level {
+	var level Level
+	walk(err, func(err error) bool {
+		if se, ok := err.(Error); ok && se.severity > level {
+			level = se.severity
+		}
+		return false
+	})
+	if level == 0 {
+		return SeverityError
+	}
+	return level
+}
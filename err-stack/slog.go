@@ -0,0 +1,118 @@
+package errstack
+
+import (
+	"context"
+	"errors"
+	"log/slog"
+)
+
+/*
+LogValue() implements slog.LogValuer, so that slog.Any("err", err)
+expands the error into a group of attributes: its message, code,
+severity, root cause, correlation ID, fields and the messages of the
+whole chain.
+*/
+func (e Error) LogValue() slog.Value {
+	attrs := []slog.Attr{
+		slog.String("msg", e.Msg()),
+		slog.String("error", e.Error()),
+	}
+	if code := Code(e); code != "" {
+		attrs = append(attrs, slog.String("code", code))
+	}
+	attrs = append(
+		attrs,
+		slog.String("severity", Severity(e).String()),
+		slog.String("root_cause", rootMsg(e)),
+	)
+	if id := ID(e); id != "" {
+		attrs = append(attrs, slog.String("id", id))
+	}
+	if fields := Fields(e); len(fields) > 0 {
+		fieldAttrs := make([]any, len(fields))
+		for i, field := range fields {
+			fieldAttrs[i] = slog.Any(field.Key, field.Value)
+		}
+		attrs = append(attrs, slog.Group("fields", fieldAttrs...))
+	}
+	trace := []string{}
+	walk(e, func(err error) bool {
+		trace = append(trace, message(err))
+		return false
+	})
+	attrs = append(attrs, slog.Any("trace", trace))
+	return slog.GroupValue(attrs...)
+}
+
+/*
+SlogHandler is a slog.Handler middleware that expands the errors found
+in the attributes of a record, even when a stacked error is wrapped by
+an outside error (e.g. with fmt.Errorf("...: %w", err)).
+*/
+type SlogHandler struct {
+	next slog.Handler
+}
+
+/*
+NewSlogHandler() returns a SlogHandler passing the records on to the
+provided handler.
+
+Example:
+
+	logger := slog.New(errstack.NewSlogHandler(slog.NewJSONHandler(os.Stderr, nil)))
+	logger.Error("request failed", "err", err)
+*/
+func NewSlogHandler(next slog.Handler) *SlogHandler {
+	return &SlogHandler{next: next}
+}
+
+func (h *SlogHandler) Enabled(ctx context.Context, level slog.Level) bool {
+	return h.next.Enabled(ctx, level)
+}
+
+func (h *SlogHandler) Handle(ctx context.Context, record slog.Record) error {
+	expanded := slog.NewRecord(record.Time, record.Level, record.Message, record.PC)
+	record.Attrs(func(attr slog.Attr) bool {
+		expanded.AddAttrs(expandAttr(attr))
+		return true
+	})
+	return h.next.Handle(ctx, expanded)
+}
+
+func (h *SlogHandler) WithAttrs(attrs []slog.Attr) slog.Handler {
+	expanded := make([]slog.Attr, len(attrs))
+	for i, attr := range attrs {
+		expanded[i] = expandAttr(attr)
+	}
+	return &SlogHandler{next: h.next.WithAttrs(expanded)}
+}
+
+func (h *SlogHandler) WithGroup(name string) slog.Handler {
+	return &SlogHandler{next: h.next.WithGroup(name)}
+}
+
+// this expands the attribute if it holds an error wrapping a stacked error
+func expandAttr(attr slog.Attr) slog.Attr {
+	if attr.Value.Kind() != slog.KindAny {
+		return attr
+	}
+	err, ok := attr.Value.Any().(error)
+	if !ok {
+		return attr
+	}
+	if _, ok := err.(Error); ok {
+		return attr
+	}
+	var se Error
+	if !errors.As(err, &se) {
+		return attr
+	}
+	attrs := se.LogValue().Group()
+	for i := range attrs {
+		// the outside error's message replaces the stacked error's one
+		if attrs[i].Key == "error" {
+			attrs[i] = slog.String("error", Redact(err.Error()))
+		}
+	}
+	return slog.Attr{Key: attr.Key, Value: slog.GroupValue(attrs...)}
+}
@@ -0,0 +1,20 @@
+//go:build !errhandling_nostack
+
+package errstack
+
+/*
+CaptureStacks reports whether the errors capture the call stack where
+they are created. It is true unless the program is built with the
+errhandling_nostack build tag:
+
+	go build -tags errhandling_nostack ./...
+
+in which case the errors carry no frames, and the traces, fingerprints
+and reports are made of their messages, codes and fields only, and the
+rendered forms of the errors are not cached either, so that
+latency-sensitive deployments do not pay for the capture.
+*/
+const CaptureStacks = true
+
+// the rendered forms of the errors are cached (see memo)
+const memoize = true
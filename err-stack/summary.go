@@ -0,0 +1,75 @@
+package errstack
+
+import (
+	"fmt"
+	"strings"
+	"unicode/utf8"
+)
+
+/*
+MaxSummaryLength is the maximum length, in characters, of the summaries
+returned by Summary().
+*/
+var MaxSummaryLength = 120
+
+/*
+Summary() returns a one-line summary of any error, for log lines, span
+events or HTTP headers, where the whole trace is too big: the messages
+of its chain, outermost first, as many as fit in MaxSummaryLength, the
+others being counted.
+
+	open config -> read file -> ENOENT
+	load order -> query orders (+3 causes)
+*/
+func Summary(err error) string {
+	if err == nil {
+		return ""
+	}
+	msgs := []string{}
+	chain := Chain(err)
+	for i, err := range chain {
+		msg := message(err)
+		if i+1 < len(chain) {
+			// outside errors usually end with the message of their cause
+			msg = strings.TrimSuffix(strings.TrimSuffix(msg, Redact(chain[i+1].Error())), ": ")
+		}
+		msgs = append(msgs, strings.ReplaceAll(msg, "\n", " "))
+	}
+	// the branches of the last error of the chain are always left out
+	branches := 0
+	if se, ok := chain[len(chain)-1].(Error); ok && len(se.causes()) > 0 {
+		branches = countErrors(se) - 1
+	}
+	summary, included := msgs[0], 1
+	for ; included < len(msgs); included++ {
+		line := summary + " -> " + msgs[included]
+		if left := len(msgs) - included - 1 + branches; left > 0 {
+			line += moreSummary(left)
+		}
+		if utf8.RuneCountInString(line) > MaxSummaryLength {
+			break
+		}
+		summary += " -> " + msgs[included]
+	}
+	if left := len(msgs) - included + branches; left > 0 {
+		summary += moreSummary(left)
+	}
+	return truncate(summary, MaxSummaryLength)
+}
+
+// this counts the causes left out of a summary
+func moreSummary(n int) string {
+	if n == 1 {
+		return " (+1 cause)"
+	}
+	return fmt.Sprintf(" (+%d causes)", n)
+}
+
+// this truncates a line to the length, ending it with an ellipsis
+func truncate(line string, length int) string {
+	if length <= 0 || utf8.RuneCountInString(line) <= length {
+		return line
+	}
+	runes := []rune(line)
+	return string(runes[:length-1]) + "…"
+}
@@ -0,0 +1,93 @@
+package errstack
+
+import (
+	"strings"
+	"sync"
+	"text/template"
+)
+
+var (
+	templatesMu sync.RWMutex
+	templates   = map[string]*template.Template{}
+)
+
+/*
+TemplateData is what the templates registered with RegisterTemplate()
+are executed with:
+
+  - .Message is the message of the error
+  - .Root is the message of its root cause, and .Roots the ones of each
+    branch of the errors with several causes
+  - .Chain is the messages of its chain (see Chain()), outermost first
+  - .Frames is the call stack captured where the failure originated
+  - .Fields is the fields of the whole chain (see Fields()), by key
+  - .Code is its code (see Code())
+  - .ID is its correlation ID (see ID())
+  - .Trace is its trace, in the format the template is used by
+*/
+type TemplateData struct {
+	Err     error
+	Message string
+	Root    string
+	Roots   []string
+	Chain   []string
+	Frames  []Frame
+	Fields  map[string]any
+	Code    string
+	ID      string
+	Trace   string
+}
+
+/*
+RegisterTemplate() parses a text/template and registers it under the
+name, so that the traces of the formats whose Template is this name are
+rendered with it, in PrintableError(), Fprint() and the other renderers.
+The "join" function joins a list of strings with a separator.
+
+Example:
+
+	errstack.RegisterTemplate("oneline",
+		`{{.Code}} {{.Message}} (root: {{.Root}}) {{range $k, $v := .Fields}}{{$k}}={{$v}} {{end}}`)
+	errstack.DefaultTraceFormat.Template = "oneline"
+*/
+func RegisterTemplate(name, text string) error {
+	tmpl, err := template.New(name).Funcs(template.FuncMap{"join": strings.Join}).Parse(text)
+	if err != nil {
+		return New("could not parse the error template", err).With("template", name)
+	}
+	templatesMu.Lock()
+	defer templatesMu.Unlock()
+	templates[name] = tmpl
+	settingsChanged()
+	return nil
+}
+
+// this executes the template of the format, registered with RegisterTemplate()
+func (f TraceFormat) execute(out *traceWriter, err error) error {
+	templatesMu.RLock()
+	tmpl, ok := templates[f.Template]
+	templatesMu.RUnlock()
+	if !ok {
+		return New("no error template is registered under this name").With("template", f.Template)
+	}
+	builtin := f
+	builtin.Template = ""
+	data := TemplateData{
+		Err:     err,
+		Message: message(err),
+		Root:    rootMsg(err),
+		Roots:   rootMsgs(err),
+		Code:    Code(err),
+		ID:      ID(err),
+		Frames:  stackFrames(err),
+		Fields:  map[string]any{},
+		Trace:   builtin.fullTrace(err),
+	}
+	for _, cause := range Chain(err) {
+		data.Chain = append(data.Chain, message(cause))
+	}
+	for _, field := range Fields(err) {
+		data.Fields[field.Key] = field.Value
+	}
+	return tmpl.Execute(out.w, data)
+}
@@ -0,0 +1,261 @@
+package errstack
+
+import (
+	"bufio"
+	"bytes"
+	"fmt"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+/*
+MarshalText() implements encoding.TextMarshaler: it encodes the error
+and its whole cause chain without the call stacks, as EncodeText() does,
+so that it can be stored in text columns or text-based protocols, and
+decoded with UnmarshalText() or ParseText().
+*/
+func (e Error) MarshalText() ([]byte, error) {
+	return EncodeText(e, false), nil
+}
+
+/*
+UnmarshalText() implements encoding.TextUnmarshaler, decoding an error
+encoded with MarshalText() or EncodeText().
+*/
+func (e *Error) UnmarshalText(data []byte) error {
+	err, parseErr := ParseText(data)
+	if parseErr != nil {
+		return parseErr
+	}
+	decoded, ok := err.(Error)
+	if !ok {
+		return New("the outermost error of the text is external")
+	}
+	*e = decoded
+	return nil
+}
+
+/*
+EncodeText() encodes any error and its whole cause chain as text, one
+line per error, each cause indented one tab deeper than the error it
+caused. A line holds the quoted message of the error, followed by its
+attributes and its fields (as quoted key/value pairs, the values being
+formatted with fmt.Sprint()). The call stacks are encoded as "at" lines
+if frames is true:
+
+	"load order" code="ORDER_MISSING" id="9f86d081884c7d65" severity=warn "order_id"="42"
+		at "main.loadOrder" "/src/orders.go" 42
+		"no rows" external
+
+It returns nil if the error is nil.
+*/
+func EncodeText(err error, frames bool) []byte {
+	if err == nil {
+		return nil
+	}
+	b := &bytes.Buffer{}
+	writeText(b, toJSON(err), 0, frames)
+	return b.Bytes()
+}
+
+// this writes the lines of an error of the chain and of its causes
+func writeText(b *bytes.Buffer, doc *jsonError, depth int, frames bool) {
+	indent := strings.Repeat("\t", depth)
+	b.WriteString(indent + strconv.Quote(doc.Message))
+	if doc.External {
+		b.WriteString(" external")
+	}
+	for _, attr := range []struct{ name, value string }{
+		{"code", doc.Code}, {"id", doc.ID}, {"public", doc.PublicMessage},
+	} {
+		if attr.value != "" {
+			b.WriteString(" " + attr.name + "=" + strconv.Quote(attr.value))
+		}
+	}
+	if doc.Severity != "" {
+		b.WriteString(" severity=" + doc.Severity)
+	}
+	if doc.Retryable {
+		b.WriteString(" retryable")
+	}
+	if doc.Timeout {
+		b.WriteString(" timeout")
+	}
+	keys := make([]string, 0, len(doc.Fields))
+	for key := range doc.Fields {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+	for _, key := range keys {
+		b.WriteString(" " + strconv.Quote(key) + "=" + strconv.Quote(fmt.Sprint(doc.Fields[key])))
+	}
+	b.WriteString("\n")
+	if frames {
+		for _, frame := range doc.Frames {
+			fmt.Fprintf(b, "%s\tat %s %s %d\n", indent, strconv.Quote(frame.Function), strconv.Quote(frame.File), frame.Line)
+		}
+	}
+	if doc.Cause != nil {
+		writeText(b, doc.Cause, depth+1, frames)
+	}
+	for _, cause := range doc.Causes {
+		writeText(b, cause, depth+1, frames)
+	}
+}
+
+/*
+ParseText() decodes an error encoded with EncodeText() or MarshalText(),
+which may also be a single outside error. The values of the fields are
+decoded as strings.
+*/
+func ParseText(data []byte) (error, error) {
+	// the errors being decoded, by depth
+	parents := []*jsonError{}
+	var root *jsonError
+	scanner := bufio.NewScanner(bytes.NewReader(data))
+	scanner.Buffer(nil, len(data)+1)
+	for n := 1; scanner.Scan(); n++ {
+		line := scanner.Text()
+		if strings.TrimSpace(line) == "" {
+			continue
+		}
+		body := strings.TrimLeft(line, "\t")
+		depth := len(line) - len(body)
+		if strings.HasPrefix(body, "at ") {
+			if depth == 0 || depth > len(parents) {
+				return nil, New("could not parse error: misplaced frame").With("line", n)
+			}
+			frame, err := parseFrame(body[len("at "):])
+			if err != nil {
+				return nil, New("could not parse error", err).With("line", n)
+			}
+			parent := parents[depth-1]
+			parent.Frames = append(parent.Frames, frame)
+			continue
+		}
+		doc, err := parseError(body)
+		if err != nil {
+			return nil, New("could not parse error", err).With("line", n)
+		}
+		switch {
+		case depth == 0 && root == nil:
+			root = doc
+		case depth == 0 || depth > len(parents):
+			return nil, New("could not parse error: misplaced cause").With("line", n)
+		default:
+			parent := parents[depth-1]
+			if parent.Cause != nil {
+				parent.Causes = []*jsonError{parent.Cause}
+				parent.Cause = nil
+			}
+			if len(parent.Causes) > 0 {
+				parent.Causes = append(parent.Causes, doc)
+			} else {
+				parent.Cause = doc
+			}
+		}
+		parents = append(parents[:depth], doc)
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, New("could not parse error", err)
+	}
+	if root == nil {
+		return nil, New("could not parse error: no error in the text")
+	}
+	return fromJSON(root), nil
+}
+
+// this parses the line of an error, after its indentation
+func parseError(line string) (*jsonError, error) {
+	msg, rest, err := unquote(line)
+	if err != nil {
+		return nil, err
+	}
+	doc := &jsonError{Message: msg}
+	for rest = strings.TrimLeft(rest, " "); rest != ""; rest = strings.TrimLeft(rest, " ") {
+		if rest[0] == '"' {
+			var key, value string
+			if key, rest, err = unquote(rest); err != nil {
+				return nil, err
+			}
+			if !strings.HasPrefix(rest, "=") {
+				return nil, New("a field has no value").With("field", key)
+			}
+			if value, rest, err = unquote(rest[1:]); err != nil {
+				return nil, err
+			}
+			if doc.Fields == nil {
+				doc.Fields = map[string]any{}
+			}
+			doc.Fields[key] = value
+			continue
+		}
+		name := rest
+		if end := strings.IndexAny(rest, " ="); end >= 0 {
+			name = rest[:end]
+		}
+		rest = rest[len(name):]
+		value := ""
+		if strings.HasPrefix(rest, "=") {
+			rest = rest[1:]
+			if strings.HasPrefix(rest, `"`) {
+				if value, rest, err = unquote(rest); err != nil {
+					return nil, err
+				}
+			} else {
+				value, _, _ = strings.Cut(rest, " ")
+				rest = rest[len(value):]
+			}
+		}
+		switch name {
+		case "external":
+			doc.External = true
+		case "code":
+			doc.Code = value
+		case "id":
+			doc.ID = value
+		case "public":
+			doc.PublicMessage = value
+		case "severity":
+			doc.Severity = value
+		case "retryable":
+			doc.Retryable = true
+		case "timeout":
+			doc.Timeout = true
+		default:
+			return nil, New("unknown attribute").With("attribute", name)
+		}
+	}
+	return doc, nil
+}
+
+// this parses an "at" line, after its "at"
+func parseFrame(line string) (Frame, error) {
+	function, rest, err := unquote(line)
+	if err != nil {
+		return Frame{}, err
+	}
+	file, rest, err := unquote(strings.TrimLeft(rest, " "))
+	if err != nil {
+		return Frame{}, err
+	}
+	number, err := strconv.Atoi(strings.TrimSpace(rest))
+	if err != nil {
+		return Frame{}, New("invalid line number", err)
+	}
+	return Frame{Function: function, File: file, Line: number}, nil
+}
+
+// this unquotes the quoted string the text starts with, returning the rest
+func unquote(text string) (string, string, error) {
+	quoted, err := strconv.QuotedPrefix(text)
+	if err != nil {
+		return "", "", New("expected a quoted string", err)
+	}
+	value, err := strconv.Unquote(quoted)
+	if err != nil {
+		return "", "", New("expected a quoted string", err)
+	}
+	return value, text[len(quoted):], nil
+}
@@ -0,0 +1,433 @@
+package errstack
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+	"strings"
+	"unicode/utf8"
+)
+
+/*
+MaxTraceDepth is the number of nested causes rendered by the trace of
+PrintableError(). The deeper causes are summarized as "… N more causes",
+so that pathologically deep chains still render in bounded space.
+*/
+var MaxTraceDepth = 64
+
+/*
+TraceFormat configures the rendering of the traces of PrintableError().
+The zero TraceFormat renders the default format.
+*/
+type TraceFormat struct {
+	// Indent is the indentation of a level of the trace, "\t" if empty.
+	Indent string
+	// CausedBy is the label of the causes, "caused by" if empty.
+	CausedBy string
+	/*
+		RootFirst renders the root cause first, and the outermost error
+		last. CausedBy is better set to a label such as "wrapped by" then.
+	*/
+	RootFirst bool
+	/*
+		MaxWidth is the width, in characters, beyond which the lines are
+		wrapped, 0 for no limit. A tab counts as one character.
+	*/
+	MaxWidth int
+	// OmitRootCause leaves out the "Root cause:" section.
+	OmitRootCause bool
+	// OmitID leaves out the "Error ID:" section (see ID()).
+	OmitID bool
+	/*
+		Color prints the message of the error in red and its root causes
+		in yellow, with ANSI escape sequences.
+	*/
+	Color bool
+	/*
+		Tree renders the trace as a tree, each error branching out to its
+		causes, which suits the errors with several causes. RootFirst and
+		CausedBy do not apply to trees.
+	*/
+	Tree bool
+	// ASCII draws the trees with ASCII characters instead of box-drawing ones.
+	ASCII bool
+	/*
+		Template is the name of a template registered with
+		RegisterTemplate() that renders the traces instead, if not empty.
+	*/
+	Template string
+}
+
+/*
+DefaultTraceFormat is the format of the traces of PrintableError(). It
+is meant to be set once, when the program starts.
+
+Example:
+
+	errstack.DefaultTraceFormat = errstack.TraceFormat{
+		Indent:    "  ",
+		CausedBy:  "wrapped by",
+		RootFirst: true,
+	}
+*/
+var DefaultTraceFormat = TraceFormat{}
+
+/*
+Render() returns the full printable error message of any error, with
+its root cause and its trace, in this format.
+*/
+func (f TraceFormat) Render(err error) string {
+	b := &strings.Builder{}
+	if _, werr := f.WriteTrace(b, err); werr != nil {
+		// the template failed, so fall back to the built-in format
+		b.Reset()
+		f.Template = ""
+		f.WriteTrace(b, err)
+	}
+	return b.String()
+}
+
+/*
+WriteTrace() writes the same as Render() to w, line by line, so that
+very deep or wide errors are streamed without building their whole
+trace in memory. It returns the number of bytes written, and the first
+error of the writer.
+*/
+func (f TraceFormat) WriteTrace(w io.Writer, err error) (int64, error) {
+	f = f.withDefaults()
+	out := &traceWriter{w: bufio.NewWriter(w)}
+	if f.Template != "" {
+		if terr := f.execute(out, err); terr != nil && out.err == nil {
+			out.err = terr
+		}
+		return out.flush()
+	}
+	out.line("error:")
+	out.line(f.paint(ansiRed, f.wrap(f.Indent+message(err))))
+	if !f.OmitRootCause {
+		roots := rootMsgs(err)
+		out.line("")
+		if len(roots) > 1 {
+			out.line("Root causes:")
+		} else {
+			out.line("Root cause:")
+		}
+		for _, root := range roots {
+			out.line(f.paint(ansiYellow, f.wrap(f.Indent+root)))
+		}
+	}
+	if id := ID(err); id != "" && !f.OmitID {
+		out.line("")
+		out.line("Error ID:")
+		out.line(f.Indent + id)
+	}
+	out.line("")
+	out.line("Full error trace:")
+	f.writeFullTrace(out, err)
+	return out.flush()
+}
+
+// this writes the lines of a trace, keeping the first error of the writer
+type traceWriter struct {
+	w     *bufio.Writer
+	n     int64
+	err   error
+	lines int
+}
+
+func (t *traceWriter) line(line string) {
+	if t.lines > 0 {
+		t.write("\n")
+	}
+	t.lines++
+	t.write(line)
+}
+
+func (t *traceWriter) Write(p []byte) (int, error) {
+	t.write(string(p))
+	return len(p), t.err
+}
+
+func (t *traceWriter) write(s string) {
+	if t.err != nil {
+		return
+	}
+	n, err := t.w.WriteString(s)
+	t.n += int64(n)
+	t.err = err
+}
+
+func (t *traceWriter) flush() (int64, error) {
+	if t.err == nil {
+		t.err = t.w.Flush()
+	}
+	return t.n, t.err
+}
+
+// this returns the format with the defaults of its empty fields
+func (f TraceFormat) withDefaults() TraceFormat {
+	if f.Indent == "" {
+		f.Indent = "\t"
+	}
+	if f.CausedBy == "" {
+		f.CausedBy = "caused by"
+	}
+	return f
+}
+
+/*
+this returns the trace of the error, as a tree if so configured, or as
+rendered by the template of the format
+*/
+func (f TraceFormat) fullTrace(err error) string {
+	if f.Template != "" {
+		b := &strings.Builder{}
+		out := &traceWriter{w: bufio.NewWriter(b)}
+		if terr := f.execute(out, err); terr == nil {
+			if _, werr := out.flush(); werr == nil {
+				return b.String()
+			}
+		}
+		f.Template = ""
+	}
+	b := &strings.Builder{}
+	out := &traceWriter{w: bufio.NewWriter(b)}
+	f.writeFullTrace(out, err)
+	out.flush()
+	return b.String()
+}
+
+// this writes the trace of the error, as a tree if so configured
+func (f TraceFormat) writeFullTrace(out *traceWriter, err error) {
+	if f.Tree {
+		f.tree(out, err)
+	} else {
+		f.trace(out, err)
+	}
+}
+
+/*
+this returns the messages of the root causes of any error: one per
+branch of the errors with several causes
+*/
+func rootMsgs(err error) []string {
+	if se, ok := err.(Error); ok && len(se.causes()) > 0 {
+		roots := make([]string, len(se.causes()))
+		for i, cause := range se.causes() {
+			roots[i] = rootMsg(cause)
+		}
+		return roots
+	}
+	return []string{rootMsg(err)}
+}
+
+/*
+this is a task of the rendering of a trace: a line to write, or a run
+of errors to expand, starting with err, where each error is the single
+cause of the previous one
+*/
+type traceTask struct {
+	line  string
+	err   error
+	level int  // the indentation level of the run
+	depth int  // the number of errors rendered above the run
+	label bool // whether the first line of the run is labeled
+}
+
+/*
+This writes the error trace. The chain is walked iteratively, run by
+run, so that deep chains do not grow the call stack; as errors hold
+their causes by value, it cannot loop back on itself.
+*/
+func (f TraceFormat) trace(out *traceWriter, err error) {
+	stack := []traceTask{{err: err}}
+	for len(stack) > 0 {
+		task := stack[len(stack)-1]
+		stack = stack[:len(stack)-1]
+		if task.err == nil {
+			out.line(task.line)
+			continue
+		}
+		// the tasks of the run, in the order they are rendered
+		tasks := f.run(task)
+		for i := len(tasks) - 1; i >= 0; i-- {
+			stack = append(stack, tasks[i])
+		}
+	}
+}
+
+// this expands a run of errors into the tasks rendering it
+func (f TraceFormat) run(task traceTask) []traceTask {
+	indent := strings.Repeat(f.Indent, task.level+1)
+	errs := []error{}
+	truncated := 0
+	depth := task.depth
+	for err := task.err; ; depth++ {
+		if depth >= MaxTraceDepth {
+			truncated = countErrors(err)
+			break
+		}
+		errs = append(errs, err)
+		se, ok := err.(Error)
+		if !ok || len(se.causes()) > 0 || se.Cause == nil {
+			break
+		}
+		err = se.Cause
+	}
+	// the branches of the last error of the run, if it has several causes
+	branches := []traceTask{}
+	if se, ok := errs[len(errs)-1].(Error); ok && truncated == 0 {
+		for i, cause := range se.causes() {
+			header := fmt.Sprintf("%s [%d of %d]:", f.CausedBy, i+1, len(se.causes()))
+			if f.RootFirst {
+				header = fmt.Sprintf("[%d of %d]:", i+1, len(se.causes()))
+			}
+			branches = append(branches,
+				traceTask{line: f.wrap(indent + header)},
+				traceTask{err: cause, level: task.level + 1, depth: depth + 1},
+			)
+		}
+	}
+	tasks := []traceTask{}
+	if !f.RootFirst {
+		for i, err := range errs {
+			tasks = append(tasks, f.line(indent, message(err), i > 0 || task.label))
+		}
+		if truncated > 0 {
+			tasks = append(tasks, traceTask{line: indent + moreCauses(truncated)})
+		}
+		return append(tasks, branches...)
+	}
+	tasks = append(tasks, branches...)
+	if truncated > 0 {
+		tasks = append(tasks, traceTask{line: indent + moreCauses(truncated)})
+	}
+	for i := len(errs) - 1; i >= 0; i-- {
+		tasks = append(tasks, f.line(indent, message(errs[i]), len(tasks) > 0 || task.label))
+	}
+	return tasks
+}
+
+// this is an error of a tree that remains to be rendered
+type treeNode struct {
+	err    error
+	prefix string // the drawing of the branches above the error
+	last   bool   // whether the error is the last cause of its parent
+	depth  int
+}
+
+/*
+This writes the error trace as a tree:
+
+	sync
+	└─ 2 errors occurred
+	   ├─ disk full
+	   └─ timeout
+*/
+func (f TraceFormat) tree(out *traceWriter, err error) {
+	branch, last, pipe, space := "├─ ", "└─ ", "│  ", "   "
+	if f.ASCII {
+		branch, last, pipe = "|- ", "`- ", "|  "
+	}
+	stack := []treeNode{{err: err}}
+	for len(stack) > 0 {
+		node := stack[len(stack)-1]
+		stack = stack[:len(stack)-1]
+		connector, below := "", ""
+		if node.depth > 0 {
+			connector, below = branch, pipe
+			if node.last {
+				connector, below = last, space
+			}
+		}
+		if node.depth >= MaxTraceDepth {
+			out.line(f.Indent + node.prefix + connector + moreCauses(countErrors(node.err)))
+			continue
+		}
+		out.line(f.wrap(f.Indent + node.prefix + connector + message(node.err)))
+		causes := []error{}
+		if se, ok := node.err.(Error); ok {
+			causes = se.causes()
+			if len(causes) == 0 && se.Cause != nil {
+				causes = []error{se.Cause}
+			}
+		}
+		for i := len(causes) - 1; i >= 0; i-- {
+			stack = append(stack, treeNode{
+				err:    causes[i],
+				prefix: node.prefix + below,
+				last:   i == len(causes)-1,
+				depth:  node.depth + 1,
+			})
+		}
+	}
+}
+
+// this returns the task writing the line of an error of the trace
+func (f TraceFormat) line(indent, msg string, labeled bool) traceTask {
+	if labeled {
+		msg = f.CausedBy + ": " + msg
+	}
+	return traceTask{line: f.wrap(indent + msg)}
+}
+
+/*
+this wraps a line longer than MaxWidth at its spaces, indenting the
+continuation lines one more level
+*/
+func (f TraceFormat) wrap(line string) string {
+	if f.MaxWidth <= 0 || utf8.RuneCountInString(line) <= f.MaxWidth {
+		return line
+	}
+	body := strings.TrimLeft(line, "\t ")
+	indent := line[:len(line)-len(body)] + f.Indent
+	lines := []string{}
+	current := line[:len(line)-len(body)]
+	start := len(current)
+	for _, word := range strings.Fields(body) {
+		if len(current) > start && utf8.RuneCountInString(current)+1+utf8.RuneCountInString(word) > f.MaxWidth {
+			lines = append(lines, current)
+			current, start = indent, len(indent)
+		}
+		if len(current) > start {
+			current += " "
+		}
+		current += word
+	}
+	return strings.Join(append(lines, current), "\n")
+}
+
+// this returns the message of an error of the trace
+func message(err error) string {
+	if se, ok := err.(Error); ok {
+		return Redact(se.msg)
+	}
+	return Redact(err.Error())
+}
+
+// this counts the errors of a chain, including every branch
+func countErrors(err error) int {
+	count := 0
+	pending := []error{err}
+	for len(pending) > 0 {
+		err := pending[len(pending)-1]
+		pending = pending[:len(pending)-1]
+		count++
+		if se, ok := err.(Error); ok {
+			if len(se.causes()) > 0 {
+				pending = append(pending, se.causes()...)
+			} else if se.Cause != nil {
+				pending = append(pending, se.Cause)
+			}
+		}
+	}
+	return count
+}
+
+// this summarizes the causes left out of a trace
+func moreCauses(n int) string {
+	if n == 1 {
+		return "… 1 more cause"
+	}
+	return fmt.Sprintf("… %d more causes", n)
+}
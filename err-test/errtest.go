@@ -0,0 +1,143 @@
+/*
+Package errtest provides testify-style assertions for code using the
+errhandling package, whose errors are thrown up the call stack and
+stacked into chains.
+*/
+package errtest
+
+import (
+	"errors"
+	"strings"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/the-zucc/errhandling"
+	errstack "github.com/the-zucc/errhandling/err-stack"
+)
+
+/*
+AssertRootCause() asserts that a root cause of the error (the innermost
+error of the chain, or of one of its branches) matches the target, as
+per errors.Is().
+
+Example:
+
+	errtest.AssertRootCause(t, err, sql.ErrNoRows)
+*/
+func AssertRootCause(t assert.TestingT, err error, target error, msgAndArgs ...any) bool {
+	if h, ok := t.(interface{ Helper() }); ok {
+		h.Helper()
+	}
+	if err == nil {
+		return assert.Fail(t, "Expected an error, got nil", msgAndArgs...)
+	}
+	for _, root := range roots(err) {
+		if errors.Is(root, target) {
+			return true
+		}
+	}
+	return assert.Fail(t, "Root cause mismatch:\n"+
+		"root causes: "+joinMessages(roots(err))+"\n"+
+		"target:      "+target.Error(), msgAndArgs...)
+}
+
+/*
+AssertThrows() asserts that the function throws an error up the call
+stack with Throw(), Return() or the like. Other panics are not caught.
+
+Example:
+
+	errtest.AssertThrows(t, func() {
+		ParseConfig([]byte("{"))
+	})
+*/
+func AssertThrows(t assert.TestingT, f func(), msgAndArgs ...any) bool {
+	if h, ok := t.(interface{ Helper() }); ok {
+		h.Helper()
+	}
+	if _, err := errhandling.CaptureThrow(f); err == nil {
+		return assert.Fail(t, "Expected the function to throw an error", msgAndArgs...)
+	}
+	return true
+}
+
+/*
+AssertChainContains() asserts that an error of the chain of the error,
+including all its branches, has a message containing the substring.
+Stacked errors are checked against their own message only, not the one
+they render with their causes.
+
+Example:
+
+	errtest.AssertChainContains(t, err, "could not load user")
+*/
+func AssertChainContains(t assert.TestingT, err error, substring string, msgAndArgs ...any) bool {
+	if h, ok := t.(interface{ Helper() }); ok {
+		h.Helper()
+	}
+	if err == nil {
+		return assert.Fail(t, "Expected an error, got nil", msgAndArgs...)
+	}
+	messages := []string{}
+	walk(err, func(err error) {
+		messages = append(messages, message(err))
+	})
+	for _, msg := range messages {
+		if strings.Contains(msg, substring) {
+			return true
+		}
+	}
+	return assert.Fail(t, "No error of the chain contains \""+substring+"\":\n"+
+		strings.Join(messages, "\n"), msgAndArgs...)
+}
+
+// message() returns the own message of the error.
+func message(err error) string {
+	if se, ok := err.(errstack.Error); ok {
+		return se.Msg()
+	}
+	return err.Error()
+}
+
+// roots() returns the innermost errors of the chain and its branches.
+func roots(err error) []error {
+	found := []error{}
+	walk(err, func(err error) {
+		switch e := err.(type) {
+		case interface{ Unwrap() []error }:
+			if len(e.Unwrap()) == 0 {
+				found = append(found, err)
+			}
+		case interface{ Unwrap() error }:
+			if e.Unwrap() == nil {
+				found = append(found, err)
+			}
+		default:
+			found = append(found, err)
+		}
+	})
+	return found
+}
+
+func joinMessages(errs []error) string {
+	messages := make([]string, len(errs))
+	for i, err := range errs {
+		messages[i] = message(err)
+	}
+	return strings.Join(messages, ", ")
+}
+
+// walk() calls f with every error of the chain, including all branches.
+func walk(err error, f func(error)) {
+	if err == nil {
+		return
+	}
+	f(err)
+	switch e := err.(type) {
+	case interface{ Unwrap() []error }:
+		for _, cause := range e.Unwrap() {
+			walk(cause, f)
+		}
+	case interface{ Unwrap() error }:
+		walk(e.Unwrap(), f)
+	}
+}
@@ -0,0 +1,56 @@
+package errtest_test
+
+import (
+	"errors"
+	"flag"
+	"fmt"
+	"testing"
+
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+
+	. "github.com/the-zucc/errhandling"
+	errstack "github.com/the-zucc/errhandling/err-stack"
+	errtest "github.com/the-zucc/errhandling/err-test"
+)
+
+func TestErrTest(t *testing.T) {
+	RegisterFailHandler(Fail)
+	RunSpecs(t, "errtest tests")
+}
+
+// recorder is an assert.TestingT recording the failures
+type recorder struct {
+	failures []string
+}
+
+func (r *recorder) Errorf(format string, args ...any) {
+	r.failures = append(r.failures, fmt.Sprintf(format, args...))
+}
+
+var _ = Describe("errtest tests", func() {
+	It("should assert on root causes, throws and chain messages", func() {
+		errNotFound := errors.New("not found")
+		err := errstack.New("load user", errstack.New("query users", errNotFound))
+		t := &recorder{}
+		Expect(errtest.AssertRootCause(t, err, errNotFound)).To(BeTrue())
+		Expect(errtest.AssertThrows(t, func() { Throw_(err) })).To(BeTrue())
+		Expect(errtest.AssertChainContains(t, err, "query")).To(BeTrue())
+		Expect(t.failures).To(BeEmpty())
+		Expect(errtest.AssertRootCause(t, err, errors.New("other"))).To(BeFalse())
+		Expect(errtest.AssertThrows(t, func() {})).To(BeFalse())
+		Expect(errtest.AssertChainContains(t, err, "load user -> ")).To(BeFalse())
+		Expect(t.failures).To(HaveLen(3))
+	})
+	It("AssertGolden() should compare normalized traces with golden files", func() {
+		err := errstack.Identify(errstack.New("checkout", errstack.New("charge card", errors.New("card declined"))))
+		t := &recorder{}
+		Expect(errtest.AssertGolden(t, "checkout", err, errtest.StripPaths|errtest.StripLines|errtest.StripStack|errtest.StripIDs)).To(BeTrue())
+		Expect(t.failures).To(BeEmpty())
+		Expect(errtest.Normalize("\t/home/ci/src/shop/checkout.go:42 at 0xc000012345", errtest.StripAll)).
+			To(Equal("\tcheckout.go:? at 0x?"))
+	})
+	It("errtest should leave the -update flag to the test binaries", func() {
+		Expect(flag.Lookup("update")).To(BeNil())
+	})
+})
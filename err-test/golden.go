@@ -0,0 +1,99 @@
+package errtest
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"regexp"
+	"strings"
+
+	"github.com/stretchr/testify/assert"
+)
+
+/*
+Update makes AssertGolden() write the golden files instead of comparing
+against them. It is set by the ERRTEST_UPDATE environment variable:
+
+	ERRTEST_UPDATE=1 go test ./...
+
+The package defines no flag of its own, which would clash with the
+flags of the test binaries; those with an -update flag may set Update
+from it in TestMain().
+*/
+var Update = os.Getenv("ERRTEST_UPDATE") != ""
+
+// Normalization selects what Normalize() strips from traces.
+type Normalization int
+
+const (
+	StripAddresses Normalization = 1 << iota // hexadecimal addresses, e.g. in panic values
+	StripPaths                               // directories of the files, keeping their names
+	StripLines                               // line numbers, which change with every edit
+	StripStack                               // the "Stack trace:" section altogether
+	StripIDs                                 // the correlation IDs, generated for every error
+
+	StripAll = StripAddresses | StripPaths | StripLines | StripStack | StripIDs
+)
+
+var (
+	addressPattern = regexp.MustCompile(`0x[0-9a-fA-F]+`)
+	pathPattern    = regexp.MustCompile(`(?m)^(\s*)\S*/([^/\s]+\.go)`)
+	linePattern    = regexp.MustCompile(`\.go:\d+`)
+	idPattern      = regexp.MustCompile(`(Error ID:\n\s*)\S+`)
+)
+
+/*
+Normalize() strips the parts of a trace that vary between machines and
+runs, as selected by the normalization flags.
+*/
+func Normalize(trace string, n Normalization) string {
+	if n&StripStack != 0 {
+		if i := strings.Index(trace, "\n\nStack trace:"); i >= 0 {
+			trace = trace[:i]
+		}
+	}
+	if n&StripAddresses != 0 {
+		trace = addressPattern.ReplaceAllString(trace, "0x?")
+	}
+	if n&StripPaths != 0 {
+		trace = pathPattern.ReplaceAllString(trace, "$1$2")
+	}
+	if n&StripLines != 0 {
+		trace = linePattern.ReplaceAllString(trace, ".go:?")
+	}
+	if n&StripIDs != 0 {
+		trace = idPattern.ReplaceAllString(trace, "${1}?")
+	}
+	return trace
+}
+
+/*
+AssertGolden() asserts that the trace of the error, as printed with
+%+v and normalized with Normalize(), matches the golden file
+testdata/<name>.golden. Setting Update writes the golden files instead.
+
+Example:
+
+	errtest.AssertGolden(t, "checkout_failure", err, errtest.StripPaths|errtest.StripLines)
+*/
+func AssertGolden(t assert.TestingT, name string, err error, n Normalization, msgAndArgs ...any) bool {
+	if h, ok := t.(interface{ Helper() }); ok {
+		h.Helper()
+	}
+	trace := Normalize(fmt.Sprintf("%+v", err), n) + "\n"
+	path := filepath.Join("testdata", name+".golden")
+	if Update {
+		if mkdirErr := os.MkdirAll("testdata", 0o755); mkdirErr != nil {
+			return assert.Fail(t, "Could not create testdata: "+mkdirErr.Error(), msgAndArgs...)
+		}
+		if writeErr := os.WriteFile(path, []byte(trace), 0o644); writeErr != nil {
+			return assert.Fail(t, "Could not update "+path+": "+writeErr.Error(), msgAndArgs...)
+		}
+		return true
+	}
+	golden, readErr := os.ReadFile(path)
+	if readErr != nil {
+		return assert.Fail(t, "Could not read "+path+" (run with ERRTEST_UPDATE=1 to create it): "+readErr.Error(), msgAndArgs...)
+	}
+	return assert.Equal(t, string(golden), trace, msgAndArgs...)
+}
@@ -0,0 +1,131 @@
+/*
+Package errvalidation collects field-level validation failures into a
+single stacked error, renderable both for humans and as JSON.
+*/
+package errvalidation
+
+import (
+	"errors"
+	"fmt"
+	"net/http"
+	"strings"
+	"sync"
+
+	errstack "github.com/the-zucc/errhandling/err-stack"
+)
+
+// CODE_VALIDATION_FAILED is the code of the errors returned by Err().
+var CODE_VALIDATION_FAILED = errstack.RegisterCode("VALIDATION_FAILED")
+
+/*
+FieldError is the failure of the validation of a field. Code is a
+stable identifier of the failure, e.g. "REQUIRED" or "INVALID", that
+clients can rely on, while Message is meant for humans.
+*/
+type FieldError struct {
+	Field   string `json:"field"`
+	Code    string `json:"code"`
+	Message string `json:"message,omitempty"`
+}
+
+func (e FieldError) Error() string {
+	if e.Message == "" {
+		return fmt.Sprintf("%s: %s", e.Field, e.Code)
+	}
+	return fmt.Sprintf("%s: %s", e.Field, e.Message)
+}
+
+/*
+ValidationErrors collects the FieldErrors of a validation. It is safe
+for concurrent use, and its zero value is ready to use.
+
+Example:
+
+	func (r SignupRequest) Validate() error {
+		var errs errvalidation.ValidationErrors
+		errs.Check(r.Name != "", "name", "REQUIRED", "is required")
+		errs.Check(strings.Contains(r.Email, "@"), "email", "INVALID", "must be an email address")
+		return errs.Err()
+	}
+*/
+type ValidationErrors struct {
+	mu   sync.Mutex
+	errs []FieldError
+}
+
+// Add() adds the failure of the field.
+func (v *ValidationErrors) Add(field string, code string, msg string) {
+	v.mu.Lock()
+	defer v.mu.Unlock()
+	v.errs = append(v.errs, FieldError{Field: field, Code: code, Message: msg})
+}
+
+// Check() adds the failure of the field if ok is false, and returns ok.
+func (v *ValidationErrors) Check(ok bool, field string, code string, msg string) bool {
+	if !ok {
+		v.Add(field, code, msg)
+	}
+	return ok
+}
+
+// Errors() returns the failures collected so far.
+func (v *ValidationErrors) Errors() []FieldError {
+	v.mu.Lock()
+	defer v.mu.Unlock()
+	return append([]FieldError{}, v.errs...)
+}
+
+/*
+Err() returns nil if no failure was collected, and otherwise a stacked
+error with the CODE_VALIDATION_FAILED code caused by the FieldErrors,
+with the 400 "http_status" field and a public message listing them:
+
+	name: is required; email: must be an email address
+*/
+func (v *ValidationErrors) Err() error {
+	fieldErrs := v.Errors()
+	if len(fieldErrs) == 0 {
+		return nil
+	}
+	causes := make([]error, len(fieldErrs))
+	msgs := make([]string, len(fieldErrs))
+	for i, fieldErr := range fieldErrs {
+		causes[i] = fieldErr
+		msgs[i] = fieldErr.Error()
+	}
+	return errstack.NewCode(CODE_VALIDATION_FAILED, "validation failed", causes...).
+		With("http_status", http.StatusBadRequest).
+		WithPublicMessage(strings.Join(msgs, "; "))
+}
+
+/*
+FieldErrors() returns the FieldErrors of the chain of the error, which
+is typically passed to json.Marshal() to render them for clients:
+
+	[{"field": "email", "code": "INVALID", "message": "must be an email address"}]
+*/
+func FieldErrors(err error) []FieldError {
+	fieldErrs := []FieldError{}
+	walk(err, func(err error) {
+		if fieldErr, ok := err.(FieldError); ok {
+			fieldErrs = append(fieldErrs, fieldErr)
+		}
+	})
+	return fieldErrs
+}
+
+// walk() calls f with every error of the chain, including all branches.
+func walk(err error, f func(error)) {
+	if err == nil {
+		return
+	}
+	f(err)
+	switch e := err.(type) {
+	case interface{ Unwrap() []error }:
+		for _, cause := range e.Unwrap() {
+			walk(cause, f)
+		}
+	default:
+		walk(errors.Unwrap(err), f)
+	}
+}
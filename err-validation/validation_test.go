@@ -0,0 +1,36 @@
+package errvalidation_test
+
+import (
+	"encoding/json"
+	"testing"
+
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+
+	errstack "github.com/the-zucc/errhandling/err-stack"
+	errvalidation "github.com/the-zucc/errhandling/err-validation"
+)
+
+func TestErrValidation(t *testing.T) {
+	RegisterFailHandler(Fail)
+	RunSpecs(t, "errvalidation tests")
+}
+
+var _ = Describe("errvalidation tests", func() {
+	It("ValidationErrors should join the field errors into one error", func() {
+		var errs errvalidation.ValidationErrors
+		Expect(errs.Err()).To(BeNil())
+		errs.Check(false, "name", "REQUIRED", "is required")
+		errs.Check(true, "age", "INVALID", "must be positive")
+		errs.Add("email", "INVALID", "")
+		err := errstack.New("signup", errs.Err())
+		Expect(errstack.Code(err)).To(Equal(errvalidation.CODE_VALIDATION_FAILED))
+		Expect(errstack.PublicMessage(err)).To(Equal("name: is required; email: INVALID"))
+		doc, jsonErr := json.Marshal(errvalidation.FieldErrors(err))
+		Expect(jsonErr).To(BeNil())
+		Expect(doc).To(MatchJSON(`[
+			{"field": "name", "code": "REQUIRED", "message": "is required"},
+			{"field": "email", "code": "INVALID"}
+		]`))
+	})
+})
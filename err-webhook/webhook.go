@@ -0,0 +1,293 @@
+/*
+Package errwebhook reports errors to a webhook, for the teams without an
+error tracker: the errors are posted as JSON, in batches, to a URL such
+as a Slack bridge or an internal incident system.
+*/
+package errwebhook
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/the-zucc/errhandling"
+	errhttp "github.com/the-zucc/errhandling/err-http"
+	errstack "github.com/the-zucc/errhandling/err-stack"
+)
+
+/*
+Event is the JSON document of a reported error. Error is the JSON
+document of its chain (see errstack.Error's MarshalJSON()), including
+the metadata set with errstack.SetMetadata().
+*/
+type Event struct {
+	Time        time.Time       `json:"time"`
+	Summary     string          `json:"summary"`
+	Fingerprint string          `json:"fingerprint"`
+	Code        string          `json:"code,omitempty"`
+	ID          string          `json:"id,omitempty"`
+	Severity    string          `json:"severity"`
+	Caller      string          `json:"caller,omitempty"`
+	Error       json.RawMessage `json:"error"`
+}
+
+/*
+Config configures New(). Only URL is required:
+
+  - Client sends the requests, http.DefaultClient if nil
+  - Header is added to every request, e.g. for an Authorization header
+  - BatchSize is the maximum number of events per request (50 if unset)
+  - FlushInterval is how long events wait for a batch to fill (5s if
+    unset)
+  - QueueSize is the number of events waiting to be sent (1000 if
+    unset); past it, the reported errors are dropped rather than
+    blocking their callers
+  - Retry is the policy of the requests (3 attempts with exponential
+    backoff if unset); the requests failing with a network error, a 429
+    or a 5xx status are retried
+  - Encode encodes the body of the requests, {"events": [...]} if nil,
+    e.g. to the format of a chat webhook
+  - OnError, if set, is called with the failures of the requests, whose
+    events are dropped
+*/
+type Config struct {
+	URL           string
+	Client        *http.Client
+	Header        http.Header
+	BatchSize     int
+	FlushInterval time.Duration
+	QueueSize     int
+	Retry         errhandling.RetryPolicy
+	Encode        func(events []Event) ([]byte, error)
+	OnError       func(err error)
+}
+
+/*
+Reporter posts the errors it is given to a webhook, in the background.
+It is safe for concurrent use.
+*/
+type Reporter struct {
+	cfg     Config
+	queue   chan Event
+	stop    chan struct{}
+	done    chan struct{}
+	once    sync.Once
+	closed  atomic.Bool
+	dropped atomic.Uint64
+}
+
+/*
+New() returns a reporter posting to the webhook of the configuration,
+and starts sending in the background until Close() is called.
+
+Example:
+
+	reporter := errwebhook.New(errwebhook.Config{URL: os.Getenv("ERRORS_WEBHOOK")})
+	defer reporter.Close(context.Background())
+	reporter.Install()
+*/
+func New(cfg Config) *Reporter {
+	if cfg.Client == nil {
+		cfg.Client = http.DefaultClient
+	}
+	if cfg.BatchSize < 1 {
+		cfg.BatchSize = 50
+	}
+	if cfg.FlushInterval <= 0 {
+		cfg.FlushInterval = 5 * time.Second
+	}
+	if cfg.QueueSize < 1 {
+		cfg.QueueSize = 1000
+	}
+	if cfg.Retry.MaxAttempts == 0 {
+		cfg.Retry.MaxAttempts = 3
+		cfg.Retry.Backoff = errhandling.ExponentialBackoff{Initial: 500 * time.Millisecond, Max: 10 * time.Second, Jitter: 0.2}
+	}
+	if cfg.Retry.RetryIf == nil {
+		cfg.Retry.RetryIf = retryable
+	}
+	if cfg.Encode == nil {
+		cfg.Encode = encode
+	}
+	r := &Reporter{
+		cfg:   cfg,
+		queue: make(chan Event, cfg.QueueSize),
+		stop:  make(chan struct{}),
+		done:  make(chan struct{}),
+	}
+	go r.run()
+	return r
+}
+
+/*
+Install() registers the reporter with errhandling.OnReport(), so that
+the errors passed to errhandling.Report() are posted to the webhook.
+The returned function removes it.
+*/
+func (r *Reporter) Install() (remove func()) {
+	return errhandling.OnReport(func(ev errhandling.Event) {
+		r.enqueue(newEvent(ev.Err, ev.Caller.File, ev.Caller.Line))
+	})
+}
+
+/*
+Report() queues the error to be posted to the webhook, and returns
+false if it was dropped, because the queue is full or the reporter is
+closed. It does nothing if the error is nil.
+*/
+func (r *Reporter) Report(err error) bool {
+	if err == nil {
+		return true
+	}
+	return r.enqueue(newEvent(err, "", 0))
+}
+
+// Dropped() returns the number of events dropped so far.
+func (r *Reporter) Dropped() uint64 {
+	return r.dropped.Load()
+}
+
+/*
+Close() sends the queued events, and stops the reporter. It returns
+early, with the error of the context, if the context is done first.
+*/
+func (r *Reporter) Close(ctx context.Context) error {
+	r.once.Do(func() {
+		r.closed.Store(true)
+		close(r.stop)
+	})
+	select {
+	case <-r.done:
+		return nil
+	case <-ctx.Done():
+		if cause := context.Cause(ctx); cause != ctx.Err() {
+			return errstack.New("could not send the queued error events", ctx.Err(), cause)
+		}
+		return errstack.New("could not send the queued error events", ctx.Err())
+	}
+}
+
+// this queues the event, unless the queue is full or the reporter closed
+func (r *Reporter) enqueue(ev Event) bool {
+	if !r.closed.Load() {
+		select {
+		case r.queue <- ev:
+			return true
+		default:
+		}
+	}
+	r.dropped.Add(1)
+	return false
+}
+
+// this sends the queued events in batches, until the reporter is closed
+func (r *Reporter) run() {
+	defer close(r.done)
+	ticker := time.NewTicker(r.cfg.FlushInterval)
+	defer ticker.Stop()
+	batch := make([]Event, 0, r.cfg.BatchSize)
+	flush := func() {
+		if len(batch) > 0 {
+			r.send(batch)
+			batch = make([]Event, 0, r.cfg.BatchSize)
+		}
+	}
+	for {
+		select {
+		case ev := <-r.queue:
+			if batch = append(batch, ev); len(batch) >= r.cfg.BatchSize {
+				flush()
+			}
+		case <-ticker.C:
+			flush()
+		case <-r.stop:
+			for {
+				select {
+				case ev := <-r.queue:
+					if batch = append(batch, ev); len(batch) >= r.cfg.BatchSize {
+						flush()
+					}
+				default:
+					flush()
+					return
+				}
+			}
+		}
+	}
+}
+
+// this posts a batch of events, with retries
+func (r *Reporter) send(batch []Event) {
+	body, err := r.cfg.Encode(batch)
+	if err == nil {
+		err = errhandling.Retry_(context.Background(), r.cfg.Retry, func() error {
+			return r.post(body)
+		})
+	}
+	if err != nil {
+		r.dropped.Add(uint64(len(batch)))
+		if r.cfg.OnError != nil {
+			r.cfg.OnError(errstack.New("could not post the error events", err).
+				With("url", r.cfg.URL).
+				With("events", len(batch)))
+		}
+	}
+}
+
+// this posts the body to the webhook once
+func (r *Reporter) post(body []byte) error {
+	return errhandling.Try(func() {
+		req := errhandling.Throw(http.NewRequest(http.MethodPost, r.cfg.URL, bytes.NewReader(body)))
+		req.Header.Set("Content-Type", "application/json")
+		for key, values := range r.cfg.Header {
+			req.Header[key] = values
+		}
+		resp := errhttp.ThrowStatus(r.cfg.Client.Do(req))
+		resp.Body.Close()
+	}).Err()
+}
+
+// this reports whether a request may be retried
+func retryable(err error) bool {
+	var se *errhttp.StatusError
+	if errors.As(err, &se) {
+		return se.Status == http.StatusTooManyRequests || se.Status >= 500
+	}
+	return true
+}
+
+// this is the default encoding of the batches
+func encode(events []Event) ([]byte, error) {
+	return json.Marshal(map[string][]Event{"events": events})
+}
+
+// this converts a reported error to its event
+func newEvent(err error, file string, line int) Event {
+	err = errstack.Identify(err)
+	ev := Event{
+		Time:        time.Now().UTC(),
+		Summary:     errstack.Summary(err),
+		Fingerprint: errstack.Fingerprint(err),
+		Code:        errstack.Code(err),
+		ID:          errstack.ID(err),
+		Severity:    errstack.Severity(err).String(),
+	}
+	if file != "" {
+		ev.Caller = fmt.Sprintf("%s:%d", file, line)
+	}
+	var doc []byte
+	if se, ok := err.(errstack.Error); ok {
+		doc, _ = se.MarshalJSON()
+	}
+	if doc == nil {
+		doc, _ = json.Marshal(map[string]any{"message": errstack.Redact(err.Error()), "external": true})
+	}
+	ev.Error = doc
+	return ev
+}
@@ -0,0 +1,86 @@
+package errwebhook_test
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"sync"
+	"testing"
+	"time"
+
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+
+	. "github.com/the-zucc/errhandling"
+	errstack "github.com/the-zucc/errhandling/err-stack"
+	errwebhook "github.com/the-zucc/errhandling/err-webhook"
+)
+
+func TestErrWebhook(t *testing.T) {
+	RegisterFailHandler(Fail)
+	RunSpecs(t, "errwebhook tests")
+}
+
+var _ = Describe("errwebhook tests", func() {
+	It("Reporter should post the reported errors in batches, retrying the failed requests", func() {
+		mu := sync.Mutex{}
+		requests, batches := 0, [][]errwebhook.Event{}
+		server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			mu.Lock()
+			defer mu.Unlock()
+			if requests++; requests == 1 {
+				w.WriteHeader(http.StatusServiceUnavailable)
+				return
+			}
+			body := map[string][]errwebhook.Event{}
+			Expect(json.NewDecoder(r.Body).Decode(&body)).To(Succeed())
+			Expect(r.Header.Get("Authorization")).To(Equal("Bearer secret"))
+			batches = append(batches, body["events"])
+		}))
+		defer server.Close()
+
+		reporter := errwebhook.New(errwebhook.Config{
+			URL:           server.URL,
+			Header:        http.Header{"Authorization": {"Bearer secret"}},
+			BatchSize:     2,
+			FlushInterval: time.Hour,
+			Retry:         RetryPolicy{MaxAttempts: 2},
+		})
+		defer reporter.Install()()
+		Expect(reporter.Report(errstack.NewCode("CONFIG", "open config", errors.New("ENOENT")))).To(BeTrue())
+		Expect(reporter.Report(errors.New("no connection"))).To(BeTrue())
+		Report(errstack.New("shutdown failed"))
+		Expect(reporter.Close(context.Background())).To(Succeed())
+		Expect(reporter.Report(errors.New("too late"))).To(BeFalse())
+
+		Expect(requests).To(Equal(3))
+		Expect(batches).To(HaveLen(2))
+		Expect(batches[0]).To(HaveLen(2))
+		Expect(batches[0][0].Summary).To(Equal("open config -> ENOENT"))
+		Expect(batches[0][0].Code).To(Equal("CONFIG"))
+		Expect(string(batches[0][0].Error)).To(ContainSubstring(`"code":"CONFIG"`))
+		Expect(string(batches[0][1].Error)).To(ContainSubstring(`"external":true`))
+		Expect(batches[1][0].Summary).To(Equal("shutdown failed"))
+		Expect(batches[1][0].Caller).To(ContainSubstring("webhook_test.go"))
+		Expect(reporter.Dropped()).To(Equal(uint64(1)))
+	})
+	It("Reporter should drop the events of the requests that keep failing", func() {
+		server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			w.WriteHeader(http.StatusBadRequest)
+		}))
+		defer server.Close()
+		failures := []error{}
+		reporter := errwebhook.New(errwebhook.Config{
+			URL:     server.URL,
+			OnError: func(err error) { failures = append(failures, err) },
+		})
+		reporter.Report(errors.New("no connection"))
+		Expect(reporter.Close(context.Background())).To(Succeed())
+		Expect(failures).To(HaveLen(1))
+		events, _ := errstack.Field(failures[0], "events")
+		Expect(events).To(Equal(1))
+		Expect(reporter.Dropped()).To(Equal(uint64(1)))
+	})
+})
@@ -0,0 +1,184 @@
+/*
+Package errhandlingconnect integrates the errhandling package with
+Connect, like errhandlinggrpc does with gRPC: handlers may Throw, stacked
+errors are converted to connect.Error values carrying their code and
+public message (and their fields and whole chain in errhandlinggrpc.Debug
+mode), and clients re-hydrate them back into stacked errors.
+*/
+package errhandlingconnect
+
+import (
+	"context"
+	"errors"
+	"io"
+
+	"connectrpc.com/connect"
+	"github.com/the-zucc/errhandling"
+	errstack "github.com/the-zucc/errhandling/err-stack"
+	errhandlinggrpc "github.com/the-zucc/errhandling/errhandling-grpc"
+	"google.golang.org/genproto/googleapis/rpc/errdetails"
+)
+
+/*
+Code() returns the Connect code for the error: the code of a
+connect.Error it wraps, and otherwise the code errhandlinggrpc.Code()
+returns, as Connect codes are the gRPC ones.
+*/
+func Code(err error) connect.Code {
+	var ce *connect.Error
+	if errors.As(err, &ce) {
+		return ce.Code()
+	}
+	return connect.Code(errhandlinggrpc.Code(err))
+}
+
+/*
+ToError() converts the error to a connect.Error with the code returned
+by Code(), the public message of the error (or
+errhandlinggrpc.InternalMessage), and the errhandlinggrpc.ErrorInfo() of
+the error as a detail, so that FromError() can re-hydrate it on the
+client side. The fields and the chain of the error are only sent in
+errhandlinggrpc.Debug mode.
+*/
+func ToError(err error) *connect.Error {
+	if err == nil {
+		return nil
+	}
+	msg := errstack.PublicMessage(err)
+	if msg == "" {
+		msg = errhandlinggrpc.InternalMessage
+	}
+	ce := connect.NewError(Code(err), errors.New(msg))
+	if detail, detailErr := connect.NewErrorDetail(errhandlinggrpc.ErrorInfo(err)); detailErr == nil {
+		ce.AddDetail(detail)
+	}
+	return ce
+}
+
+/*
+FromError() converts an error received from a server back into a
+stacked error. If the server used ToError(), the error carries the code
+of the server's error, and wraps its whole chain in Debug mode. It still
+carries the connect.Error, for connect.CodeOf() and errors.As().
+*/
+func FromError(err error) error {
+	var ce *connect.Error
+	if !errors.As(err, &ce) {
+		return err
+	}
+	var cause error = errors.New(ce.Message())
+	for _, detail := range ce.Details() {
+		value, valueErr := detail.Value()
+		if valueErr != nil {
+			continue
+		}
+		if info, ok := value.(*errdetails.ErrorInfo); ok {
+			if decoded, ok := errhandlinggrpc.FromErrorInfo(info); ok {
+				cause = decoded
+			} else if info.GetReason() != "" {
+				cause = errstack.NewCode(info.GetReason(), ce.Message())
+			}
+		}
+	}
+	return remoteError{cause: cause, connectErr: ce}
+}
+
+// remoteError is an error re-hydrated from a connect.Error.
+type remoteError struct {
+	cause      error
+	connectErr *connect.Error
+}
+
+func (e remoteError) Error() string {
+	return e.cause.Error()
+}
+
+func (e remoteError) Unwrap() error {
+	return e.cause
+}
+
+// As() lets errors.As() find the connect.Error.
+func (e remoteError) As(target any) bool {
+	if ce, ok := target.(**connect.Error); ok {
+		*ce = e.connectErr
+		return true
+	}
+	return false
+}
+
+/*
+NewInterceptor() returns an interceptor which, on the handler side,
+recovers what the handlers throw (and any other panic) and converts the
+errors with ToError(), and on the client side converts the errors with
+FromError() and stacks them under the name of the procedure.
+
+Example:
+
+	path, handler := ordersv1connect.NewOrdersHandler(server,
+		connect.WithInterceptors(errhandlingconnect.NewInterceptor()),
+	)
+*/
+func NewInterceptor() connect.Interceptor {
+	return interceptor{}
+}
+
+type interceptor struct{}
+
+func (interceptor) WrapUnary(next connect.UnaryFunc) connect.UnaryFunc {
+	return func(ctx context.Context, req connect.AnyRequest) (connect.AnyResponse, error) {
+		if req.Spec().IsClient {
+			resp, err := next(ctx, req)
+			return resp, rehydrate(req.Spec().Procedure, err)
+		}
+		resp, err := unary(ctx, req, next)
+		if err != nil {
+			return nil, ToError(err)
+		}
+		return resp, nil
+	}
+}
+
+func unary(ctx context.Context, req connect.AnyRequest, next connect.UnaryFunc) (resp connect.AnyResponse, err error) {
+	defer errhandling.CatchPanic(&resp, &err)
+	return next(ctx, req)
+}
+
+func (interceptor) WrapStreamingClient(next connect.StreamingClientFunc) connect.StreamingClientFunc {
+	return func(ctx context.Context, spec connect.Spec) connect.StreamingClientConn {
+		return clientConn{StreamingClientConn: next(ctx, spec)}
+	}
+}
+
+func (interceptor) WrapStreamingHandler(next connect.StreamingHandlerFunc) connect.StreamingHandlerFunc {
+	return func(ctx context.Context, conn connect.StreamingHandlerConn) error {
+		if err := stream(ctx, conn, next); err != nil {
+			return ToError(err)
+		}
+		return nil
+	}
+}
+
+func stream(ctx context.Context, conn connect.StreamingHandlerConn, next connect.StreamingHandlerFunc) (err error) {
+	defer errhandling.CatchPanic_(&err)
+	return next(ctx, conn)
+}
+
+type clientConn struct {
+	connect.StreamingClientConn
+}
+
+func (c clientConn) Send(m any) error {
+	return rehydrate(c.Spec().Procedure, c.StreamingClientConn.Send(m))
+}
+
+func (c clientConn) Receive(m any) error {
+	return rehydrate(c.Spec().Procedure, c.StreamingClientConn.Receive(m))
+}
+
+// rehydrate() converts the error of a call, leaving io.EOF untouched.
+func rehydrate(procedure string, err error) error {
+	if err == nil || errors.Is(err, io.EOF) {
+		return err
+	}
+	return errstack.New("call "+procedure, FromError(err))
+}
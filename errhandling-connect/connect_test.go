@@ -0,0 +1,49 @@
+package errhandlingconnect_test
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"testing"
+
+	"connectrpc.com/connect"
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+
+	. "github.com/the-zucc/errhandling"
+	errstack "github.com/the-zucc/errhandling/err-stack"
+	errhandlingconnect "github.com/the-zucc/errhandling/errhandling-connect"
+	errhandlinggrpc "github.com/the-zucc/errhandling/errhandling-grpc"
+)
+
+func TestConnect(t *testing.T) {
+	RegisterFailHandler(Fail)
+	RunSpecs(t, "errhandlingconnect tests")
+}
+
+var _ = Describe("errhandlingconnect tests", func() {
+	It("should carry a thrown error from the handler to the client in Debug mode", func() {
+		errhandlinggrpc.Debug = true
+		defer func() { errhandlinggrpc.Debug = false }()
+		handler := errhandlingconnect.NewInterceptor().WrapUnary(func(ctx context.Context, req connect.AnyRequest) (connect.AnyResponse, error) {
+			Throw_(errstack.NewCode("OUT_OF_STOCK", "reserve items", errors.New("no stock")).
+				With("http_status", http.StatusConflict))
+			return nil, nil
+		})
+		_, handlerErr := handler(context.Background(), connect.NewRequest(&struct{}{}))
+		Expect(connect.CodeOf(handlerErr)).To(Equal(connect.CodeAlreadyExists))
+		err := errhandlingconnect.FromError(handlerErr)
+		Expect(connect.CodeOf(err)).To(Equal(connect.CodeAlreadyExists))
+		Expect(errstack.RootCode(err)).To(Equal("OUT_OF_STOCK"))
+		Expect(err.Error()).To(Equal("no stock -> reserve items"))
+	})
+	It("should only send the code and the public message of the errors by default", func() {
+		ce := errhandlingconnect.ToError(errstack.NewCode("OUT_OF_STOCK", "SELECT stock FROM items", errors.New("no stock")).
+			With("sku", "A-42"))
+		Expect(ce.Message()).To(Equal(errhandlinggrpc.InternalMessage))
+		err := errhandlingconnect.FromError(ce)
+		Expect(errstack.RootCode(err)).To(Equal("OUT_OF_STOCK"))
+		_, ok := errstack.Field(err, "sku")
+		Expect(ok).To(BeFalse())
+	})
+})
@@ -0,0 +1,35 @@
+module github.com/the-zucc/errhandling/errhandling-connect
+
+go 1.21
+
+require (
+	connectrpc.com/connect v1.17.0
+	github.com/onsi/gomega v1.24.2
+	github.com/the-zucc/errhandling v0.0.0-00010101000000-000000000000
+	github.com/the-zucc/errhandling/errhandling-grpc v0.0.0-00010101000000-000000000000
+	google.golang.org/genproto/googleapis/rpc v0.0.0-20240318140521-94a12d6c2237
+)
+
+require (
+	github.com/fsnotify/fsnotify v1.4.9 // indirect
+	github.com/nxadm/tail v1.4.8 // indirect
+	github.com/the-zucc/errhandling/err-proto v0.0.0-00010101000000-000000000000 // indirect
+	golang.org/x/sys v0.18.0 // indirect
+	google.golang.org/grpc v1.64.0 // indirect
+	google.golang.org/protobuf v1.34.2 // indirect
+	gopkg.in/tomb.v1 v1.0.0-20141024135613-dd632973f1e7 // indirect
+)
+
+require (
+	github.com/google/go-cmp v0.6.0 // indirect
+	github.com/onsi/ginkgo v1.16.5
+	golang.org/x/net v0.23.0 // indirect
+	golang.org/x/text v0.14.0 // indirect
+	gopkg.in/yaml.v3 v3.0.1 // indirect
+)
+
+replace github.com/the-zucc/errhandling => ../
+
+replace github.com/the-zucc/errhandling/err-proto => ../err-proto
+
+replace github.com/the-zucc/errhandling/errhandling-grpc => ../errhandling-grpc
@@ -0,0 +1,137 @@
+/*
+Package errhandlingexpvar publishes statistics on the errors thrown with
+the errhandling package with expvar, and serves them over HTTP, so that
+operators can inspect the live composition of the errors of a service
+without a metrics stack: the thrown errors counted by code and severity,
+and the recent failures kept by errhandling.KeepRecent().
+*/
+package errhandlingexpvar
+
+import (
+	"encoding/json"
+	"expvar"
+	"net/http"
+	"sync"
+	"time"
+
+	"github.com/the-zucc/errhandling"
+	errstack "github.com/the-zucc/errhandling/err-stack"
+)
+
+// Stats counts the thrown errors by code and severity.
+type Stats struct {
+	mu         sync.Mutex
+	total      int64
+	byCode     map[string]int64
+	bySeverity map[string]int64
+}
+
+/*
+Snapshot is the state of the statistics, as published with expvar and
+served by Handler(). The errors without a code are counted under "".
+*/
+type Snapshot struct {
+	Total      int64            `json:"total"`
+	ByCode     map[string]int64 `json:"by_code"`
+	BySeverity map[string]int64 `json:"by_severity"`
+	Recent     []Recent         `json:"recent"`
+}
+
+// Recent is a failure kept by errhandling.KeepRecent(), summarized.
+type Recent struct {
+	Summary     string    `json:"summary"`
+	Code        string    `json:"code,omitempty"`
+	Fingerprint string    `json:"fingerprint"`
+	First       time.Time `json:"first"`
+	Last        time.Time `json:"last"`
+	Count       int       `json:"count"`
+}
+
+// NewStats() returns empty statistics.
+func NewStats() *Stats {
+	return &Stats{byCode: map[string]int64{}, bySeverity: map[string]int64{}}
+}
+
+/*
+Install() publishes the statistics with expvar under the provided name
+(served by expvar at /debug/vars), and installs a throw hook that
+updates them for every thrown error. It returns an error if a variable
+is already published under the name.
+
+Example:
+
+	stats, err := errhandlingexpvar.Install("errors")
+	...
+	http.Handle("/debug/errors", stats.Handler())
+*/
+func Install(name string) (*Stats, error) {
+	if expvar.Get(name) != nil {
+		return nil, errstack.New("an expvar variable is already published under the name").With("name", name)
+	}
+	s := NewStats()
+	expvar.Publish(name, expvar.Func(func() any {
+		return s.Snapshot()
+	}))
+	errhandling.OnThrow(func(ev errhandling.Event) {
+		s.Observe(ev.Err)
+	})
+	return s, nil
+}
+
+// Observe() updates the statistics for the error.
+func (s *Stats) Observe(err error) {
+	if err == nil {
+		return
+	}
+	code, severity := errstack.Code(err), errstack.Severity(err).String()
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.total++
+	s.byCode[code]++
+	s.bySeverity[severity]++
+}
+
+/*
+Snapshot() returns the current statistics, along with the recent
+failures kept by errhandling.KeepRecent(), if it is in use.
+*/
+func (s *Stats) Snapshot() Snapshot {
+	s.mu.Lock()
+	snapshot := Snapshot{
+		Total:      s.total,
+		ByCode:     make(map[string]int64, len(s.byCode)),
+		BySeverity: make(map[string]int64, len(s.bySeverity)),
+		Recent:     []Recent{},
+	}
+	for code, n := range s.byCode {
+		snapshot.ByCode[code] = n
+	}
+	for severity, n := range s.bySeverity {
+		snapshot.BySeverity[severity] = n
+	}
+	s.mu.Unlock()
+	for _, recent := range errhandling.Recent() {
+		snapshot.Recent = append(snapshot.Recent, Recent{
+			Summary:     errstack.Summary(recent.Err),
+			Code:        errstack.Code(recent.Err),
+			Fingerprint: recent.Fingerprint,
+			First:       recent.First,
+			Last:        recent.Last,
+			Count:       recent.Count,
+		})
+	}
+	return snapshot
+}
+
+/*
+Handler() returns an http.Handler serving the snapshot of the statistics
+as JSON, typically mounted at /debug/errors.
+*/
+func (s *Stats) Handler() http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "application/json")
+		enc := json.NewEncoder(w)
+		enc.SetIndent("", "  ")
+		enc.Encode(s.Snapshot())
+	})
+}
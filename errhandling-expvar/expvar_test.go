@@ -0,0 +1,54 @@
+package errhandlingexpvar_test
+
+import (
+	"encoding/json"
+	"errors"
+	"expvar"
+	"net/http/httptest"
+	"testing"
+
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+
+	. "github.com/the-zucc/errhandling"
+	errstack "github.com/the-zucc/errhandling/err-stack"
+	errhandlingexpvar "github.com/the-zucc/errhandling/errhandling-expvar"
+)
+
+func TestErrHandlingExpvar(t *testing.T) {
+	RegisterFailHandler(Fail)
+	RunSpecs(t, "errhandlingexpvar tests")
+}
+
+var _ = Describe("errhandlingexpvar tests", func() {
+	It("Install() should publish the thrown errors by code and severity, and the recent failures", func() {
+		stats, err := errhandlingexpvar.Install("errors")
+		Expect(err).To(BeNil())
+		_, err = errhandlingexpvar.Install("errors")
+		Expect(err).NotTo(BeNil())
+		defer KeepRecent(10)()
+		for i := 0; i < 2; i++ {
+			_ = func() (e error) {
+				defer Catch_(&e)
+				Throw_(errstack.NewCode("CONFIG", "open config", errors.New("ENOENT")).WithSeverity(errstack.SeverityWarn))
+				return nil
+			}()
+		}
+
+		published := errhandlingexpvar.Snapshot{}
+		Expect(json.Unmarshal([]byte(expvar.Get("errors").String()), &published)).To(Succeed())
+		Expect(published.Total).To(Equal(int64(2)))
+		Expect(published.ByCode).To(Equal(map[string]int64{"CONFIG": 2}))
+		Expect(published.BySeverity).To(Equal(map[string]int64{"warn": 2}))
+		Expect(published.Recent).To(HaveLen(1))
+		Expect(published.Recent[0].Summary).To(Equal("open config -> ENOENT"))
+		Expect(published.Recent[0].Count).To(Equal(2))
+
+		rec := httptest.NewRecorder()
+		stats.Handler().ServeHTTP(rec, httptest.NewRequest("GET", "/debug/errors", nil))
+		Expect(rec.Header().Get("Content-Type")).To(Equal("application/json"))
+		served := errhandlingexpvar.Snapshot{}
+		Expect(json.Unmarshal(rec.Body.Bytes(), &served)).To(Succeed())
+		Expect(served).To(Equal(published))
+	})
+})
@@ -0,0 +1,78 @@
+/*
+Package errhandlinggorm is a GORM plugin translating the errors of the
+queries into errstack errors with the codes of the errsql package.
+*/
+package errhandlinggorm
+
+import (
+	"errors"
+
+	errsql "github.com/the-zucc/errhandling/err-sql"
+	errstack "github.com/the-zucc/errhandling/err-stack"
+	"gorm.io/gorm"
+)
+
+// gormCodes maps the errors of GORM to the codes of the errsql package
+var gormCodes = map[error]string{
+	gorm.ErrRecordNotFound:     errsql.CODE_NO_ROWS,
+	gorm.ErrDuplicatedKey:      errsql.CODE_UNIQUE_VIOLATION,
+	gorm.ErrForeignKeyViolated: errsql.CODE_FOREIGN_KEY_VIOLATION,
+}
+
+/*
+Plugin translates the errors of the queries with errsql.Translate(), as
+well as the errors of GORM itself (gorm.ErrRecordNotFound, and
+gorm.ErrDuplicatedKey and gorm.ErrForeignKeyViolated if TranslateError
+is enabled). The errors carry the "query" and "table" fields, and still
+match the original errors with errors.Is().
+
+Example:
+
+	db := Must(gorm.Open(postgres.Open(dsn), &gorm.Config{}))
+	Must_(db.Use(errhandlinggorm.Plugin{}))
+*/
+type Plugin struct{}
+
+func (Plugin) Name() string {
+	return "errhandling"
+}
+
+func (Plugin) Initialize(db *gorm.DB) error {
+	callbacks := db.Callback()
+	for _, register := range []func() error{
+		func() error { return callbacks.Create().After("*").Register("errhandling:translate", translate) },
+		func() error { return callbacks.Query().After("*").Register("errhandling:translate", translate) },
+		func() error { return callbacks.Update().After("*").Register("errhandling:translate", translate) },
+		func() error { return callbacks.Delete().After("*").Register("errhandling:translate", translate) },
+		func() error { return callbacks.Row().After("*").Register("errhandling:translate", translate) },
+		func() error { return callbacks.Raw().After("*").Register("errhandling:translate", translate) },
+	} {
+		if err := register(); err != nil {
+			return errstack.New("could not register the errhandling callbacks", err)
+		}
+	}
+	return nil
+}
+
+// translate() is the callback translating the error of the statement.
+func translate(db *gorm.DB) {
+	if db.Error == nil {
+		return
+	}
+	var se errstack.Error
+	if errors.As(db.Error, &se) {
+		// already translated
+		return
+	}
+	err := errsql.TranslateQuery(db.Error, db.Statement.SQL.String())
+	for gormErr, code := range gormCodes {
+		if errors.Is(db.Error, gormErr) {
+			err = errstack.NewCode(code, gormErr.Error(), err)
+			break
+		}
+	}
+	if se, ok := err.(errstack.Error); ok && db.Statement.Table != "" {
+		err = se.With("table", db.Statement.Table)
+	}
+	db.Error = err
+}
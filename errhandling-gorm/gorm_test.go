@@ -0,0 +1,103 @@
+package errhandlinggorm_test
+
+import (
+	"context"
+	"database/sql"
+	"database/sql/driver"
+	"errors"
+	"io"
+	"testing"
+
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+	"gorm.io/gorm"
+	"gorm.io/gorm/callbacks"
+	"gorm.io/gorm/clause"
+	"gorm.io/gorm/logger"
+	"gorm.io/gorm/migrator"
+	"gorm.io/gorm/schema"
+
+	errsql "github.com/the-zucc/errhandling/err-sql"
+	errstack "github.com/the-zucc/errhandling/err-stack"
+	errhandlinggorm "github.com/the-zucc/errhandling/errhandling-gorm"
+)
+
+func TestGorm(t *testing.T) {
+	RegisterFailHandler(Fail)
+	RunSpecs(t, "errhandlinggorm tests")
+}
+
+type User struct {
+	ID    uint
+	Email string
+}
+
+var _ = Describe("errhandlinggorm tests", func() {
+	It("Plugin should translate the errors of the queries", func() {
+		db, err := gorm.Open(dialector{}, &gorm.Config{
+			SkipDefaultTransaction: true,
+			Logger:                 logger.Discard,
+		})
+		Expect(err).To(BeNil())
+		Expect(db.Use(errhandlinggorm.Plugin{})).To(Succeed())
+		err = db.Create(&User{Email: "jane@example.com"}).Error
+		Expect(errstack.Code(err)).To(Equal(errsql.CODE_UNIQUE_VIOLATION))
+		table, _ := errstack.Field(err, "table")
+		Expect(table).To(Equal("users"))
+		err = db.First(&User{}, 42).Error
+		Expect(errstack.Code(err)).To(Equal(errsql.CODE_NO_ROWS))
+		Expect(errors.Is(err, gorm.ErrRecordNotFound)).To(BeTrue())
+	})
+})
+
+// this is a GORM dialector whose inserts violate a unique constraint,
+// and whose queries return no rows
+type dialector struct{}
+
+func (dialector) Name() string { return "fake" }
+
+func (dialector) Initialize(db *gorm.DB) error {
+	callbacks.RegisterDefaultCallbacks(db, &callbacks.Config{})
+	db.ConnPool = sql.OpenDB(connector{})
+	return nil
+}
+
+func (dialector) Migrator(db *gorm.DB) gorm.Migrator {
+	return migrator.Migrator{Config: migrator.Config{DB: db}}
+}
+
+func (dialector) DataTypeOf(*schema.Field) string                     { return "" }
+func (dialector) DefaultValueOf(*schema.Field) clause.Expression      { return nil }
+func (dialector) BindVarTo(w clause.Writer, _ *gorm.Statement, _ any) { w.WriteByte('?') }
+func (dialector) QuoteTo(w clause.Writer, s string)                   { w.WriteString(s) }
+func (dialector) Explain(sql string, _ ...any) string                 { return sql }
+
+type connector struct{}
+
+func (connector) Connect(context.Context) (driver.Conn, error) { return conn{}, nil }
+func (connector) Driver() driver.Driver                        { return nil }
+
+type conn struct{}
+
+func (conn) Prepare(string) (driver.Stmt, error) { return nil, errors.New("not implemented") }
+func (conn) Close() error                        { return nil }
+func (conn) Begin() (driver.Tx, error)           { return nil, errors.New("not implemented") }
+
+func (conn) ExecContext(context.Context, string, []driver.NamedValue) (driver.Result, error) {
+	return nil, uniqueViolation{}
+}
+
+func (conn) QueryContext(context.Context, string, []driver.NamedValue) (driver.Rows, error) {
+	return noRows{}, nil
+}
+
+type noRows struct{}
+
+func (noRows) Columns() []string         { return []string{"id", "email"} }
+func (noRows) Close() error              { return nil }
+func (noRows) Next([]driver.Value) error { return io.EOF }
+
+type uniqueViolation struct{}
+
+func (uniqueViolation) Error() string    { return "duplicate key value violates unique constraint" }
+func (uniqueViolation) SQLState() string { return "23505" }
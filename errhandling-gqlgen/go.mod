@@ -0,0 +1,30 @@
+module github.com/the-zucc/errhandling/errhandling-gqlgen
+
+go 1.21
+
+require (
+	github.com/99designs/gqlgen v0.17.45
+	github.com/onsi/gomega v1.24.2
+	github.com/the-zucc/errhandling v0.0.0-00010101000000-000000000000
+	github.com/vektah/gqlparser/v2 v2.5.11
+)
+
+require (
+	github.com/fsnotify/fsnotify v1.4.9 // indirect
+	github.com/google/uuid v1.6.0 // indirect
+	github.com/kr/text v0.2.0 // indirect
+	github.com/nxadm/tail v1.4.8 // indirect
+	github.com/sosodev/duration v1.2.0 // indirect
+	golang.org/x/sys v0.18.0 // indirect
+	gopkg.in/tomb.v1 v1.0.0-20141024135613-dd632973f1e7 // indirect
+)
+
+require (
+	github.com/google/go-cmp v0.6.0 // indirect
+	github.com/onsi/ginkgo v1.16.5
+	golang.org/x/net v0.23.0 // indirect
+	golang.org/x/text v0.14.0 // indirect
+	gopkg.in/yaml.v3 v3.0.1 // indirect
+)
+
+replace github.com/the-zucc/errhandling => ../
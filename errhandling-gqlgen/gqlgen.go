@@ -0,0 +1,76 @@
+/*
+Package errhandlinggqlgen integrates the errhandling package with
+gqlgen: resolvers may Throw, and stacked errors are presented as GraphQL
+errors carrying their public message and code.
+*/
+package errhandlinggqlgen
+
+import (
+	"context"
+	"errors"
+
+	"github.com/99designs/gqlgen/graphql"
+	"github.com/the-zucc/errhandling"
+	errstack "github.com/the-zucc/errhandling/err-stack"
+	"github.com/vektah/gqlparser/v2/gqlerror"
+)
+
+/*
+Debug makes ErrorPresenter() add the whole cause chain, as encoded by
+errstack.Error's MarshalJSON(), in the "chain" extension. It must never
+be enabled for public APIs.
+*/
+var Debug bool
+
+// InternalMessage is the message of the errors without a public message.
+var InternalMessage = "internal system error"
+
+/*
+ErrorPresenter() is a graphql.ErrorPresenterFunc presenting stacked
+errors with their public message (or InternalMessage), and their code
+and correlation ID (their "correlation_id" field if set, the one given
+by errstack.Identify() otherwise) as the "code" and "correlation_id"
+extensions. Other errors, like the validation errors of gqlgen, are
+presented by graphql.DefaultErrorPresenter().
+
+Example:
+
+	srv := handler.NewDefaultServer(generated.NewExecutableSchema(cfg))
+	srv.SetErrorPresenter(errhandlinggqlgen.ErrorPresenter)
+	srv.SetRecoverFunc(errhandlinggqlgen.Recover)
+*/
+func ErrorPresenter(ctx context.Context, err error) *gqlerror.Error {
+	var se errstack.Error
+	if !errors.As(err, &se) {
+		return graphql.DefaultErrorPresenter(ctx, err)
+	}
+	err = errstack.Identify(err)
+	gqlErr := gqlerror.WrapPath(graphql.GetPath(ctx), err)
+	gqlErr.Message = errstack.PublicMessage(err)
+	if gqlErr.Message == "" {
+		gqlErr.Message = InternalMessage
+	}
+	gqlErr.Extensions = map[string]any{}
+	if code := errstack.Code(err); code != "" {
+		gqlErr.Extensions["code"] = code
+	}
+	if id, ok := errstack.Field(err, "correlation_id"); ok {
+		gqlErr.Extensions["correlation_id"] = id
+	} else if id := errstack.ID(err); id != "" {
+		gqlErr.Extensions["correlation_id"] = id
+	}
+	if Debug {
+		gqlErr.Extensions["chain"] = se
+	}
+	return gqlErr
+}
+
+/*
+Recover() is a graphql.RecoverFunc converting what the resolvers throw
+back into the thrown error, and any other panic into an error, the same
+way CatchPanic_() does.
+*/
+func Recover(ctx context.Context, panicInfo any) (err error) {
+	defer errhandling.CatchPanic_(&err)
+	panic(panicInfo)
+}
@@ -0,0 +1,38 @@
+package errhandlinggqlgen_test
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+
+	. "github.com/the-zucc/errhandling"
+	errstack "github.com/the-zucc/errhandling/err-stack"
+	errhandlinggqlgen "github.com/the-zucc/errhandling/errhandling-gqlgen"
+)
+
+func TestGqlgen(t *testing.T) {
+	RegisterFailHandler(Fail)
+	RunSpecs(t, "errhandlinggqlgen tests")
+}
+
+var _ = Describe("errhandlinggqlgen tests", func() {
+	It("should present what a resolver throws", func() {
+		var panicInfo any
+		func() {
+			defer func() { panicInfo = recover() }()
+			Throw_(errstack.NewCode("ORDER_MISSING", "load order", errors.New("no rows")).
+				With("correlation_id", "req-42").
+				WithPublicMessage("This order does not exist."))
+		}()
+		err := errhandlinggqlgen.Recover(context.Background(), panicInfo)
+		gqlErr := errhandlinggqlgen.ErrorPresenter(context.Background(), err)
+		Expect(gqlErr.Message).To(Equal("This order does not exist."))
+		Expect(gqlErr.Extensions).To(Equal(map[string]any{
+			"code":           "ORDER_MISSING",
+			"correlation_id": "req-42",
+		}))
+	})
+})
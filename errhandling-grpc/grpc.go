@@ -0,0 +1,321 @@
+/*
+Package errhandlinggrpc integrates the errhandling package with gRPC:
+server interceptors recover what handlers throw and convert stacked
+errors to statuses carrying their code and public message (and their
+fields and whole chain in Debug mode), and client interceptors
+re-hydrate these statuses back into stacked errors.
+*/
+package errhandlinggrpc
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+
+	"github.com/the-zucc/errhandling"
+	errproto "github.com/the-zucc/errhandling/err-proto"
+	errstack "github.com/the-zucc/errhandling/err-stack"
+	"google.golang.org/genproto/googleapis/rpc/errdetails"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+	"google.golang.org/protobuf/protoadapt"
+)
+
+/*
+Domain is the domain of the errdetails.ErrorInfo details attached to the
+statuses, typically the name of the service.
+*/
+var Domain string
+
+/*
+Debug makes ToStatus() and ErrorInfo() add the fields and the whole
+cause chain of the errors, with their stacks, to the statuses, so that
+the clients re-hydrate the errors of the server as they were. It must
+never be enabled for public APIs.
+*/
+var Debug bool
+
+// InternalMessage is the message of the statuses of the errors without a public message.
+var InternalMessage = "internal system error"
+
+// chainKey is the metadata key of the encoded chain in the ErrorInfo.
+const chainKey = "errstack-chain"
+
+// httpCodes converts the "http_status" fields (e.g. from an errcatalog)
+var httpCodes = map[int]codes.Code{
+	http.StatusBadRequest:          codes.InvalidArgument,
+	http.StatusUnauthorized:        codes.Unauthenticated,
+	http.StatusForbidden:           codes.PermissionDenied,
+	http.StatusNotFound:            codes.NotFound,
+	http.StatusConflict:            codes.AlreadyExists,
+	http.StatusPreconditionFailed:  codes.FailedPrecondition,
+	http.StatusTooManyRequests:     codes.ResourceExhausted,
+	http.StatusNotImplemented:      codes.Unimplemented,
+	http.StatusServiceUnavailable:  codes.Unavailable,
+	http.StatusGatewayTimeout:      codes.DeadlineExceeded,
+	http.StatusInternalServerError: codes.Internal,
+}
+
+/*
+Code() returns the gRPC code for the error. It is, in this order: the
+"grpc_code" field of its chain, the code of a status it wraps, the code
+matching its "http_status" field, Canceled or DeadlineExceeded for
+context errors and timeouts, Unavailable for retryable errors, and
+Unknown otherwise.
+*/
+func Code(err error) codes.Code {
+	if code, ok := errstack.Field(err, "grpc_code"); ok {
+		if code, ok := code.(codes.Code); ok {
+			return code
+		}
+	}
+	var st interface{ GRPCStatus() *status.Status }
+	if errors.As(err, &st) {
+		return st.GRPCStatus().Code()
+	}
+	if httpStatus, ok := errstack.Field(err, "http_status"); ok {
+		if code, ok := httpCodes[toInt(httpStatus)]; ok {
+			return code
+		}
+	}
+	switch {
+	case errors.Is(err, context.Canceled):
+		return codes.Canceled
+	case errors.Is(err, context.DeadlineExceeded), errstack.IsTimeout(err):
+		return codes.DeadlineExceeded
+	case errstack.IsRetryable(err):
+		return codes.Unavailable
+	}
+	return codes.Unknown
+}
+
+func toInt(value any) int {
+	if i, ok := value.(int); ok {
+		return i
+	}
+	return 0
+}
+
+/*
+ToStatus() converts the error to a status with the code returned by
+Code(), the public message of the error (or InternalMessage), and its
+ErrorInfo() as a detail, so that FromStatus() can re-hydrate it on the
+client side. In Debug mode, the errproto.ToProto() of the error is
+attached as well.
+*/
+func ToStatus(err error) *status.Status {
+	if err == nil {
+		return status.New(codes.OK, "")
+	}
+	err = errstack.Identify(err)
+	msg := errstack.PublicMessage(err)
+	if msg == "" {
+		msg = InternalMessage
+	}
+	st := status.New(Code(err), msg)
+	details := []protoadapt.MessageV1{ErrorInfo(err)}
+	if Debug {
+		details = append(details, errproto.ToProto(err))
+	}
+	if detailed, detailsErr := st.WithDetails(details...); detailsErr == nil {
+		return detailed
+	}
+	return st
+}
+
+/*
+ErrorInfo() returns an errdetails.ErrorInfo whose reason is the code of
+the error, and whose metadata holds its correlation ID (as
+"correlation_id", see errstack.Identify()). In Debug mode, the metadata also
+holds the fields of the error and its whole chain.
+*/
+func ErrorInfo(err error) *errdetails.ErrorInfo {
+	err = errstack.Identify(err)
+	info := &errdetails.ErrorInfo{
+		Reason:   errstack.Code(err),
+		Domain:   Domain,
+		Metadata: map[string]string{},
+	}
+	if id, ok := errstack.Field(err, "correlation_id"); ok {
+		info.Metadata["correlation_id"] = fmt.Sprint(id)
+	} else if id := errstack.ID(err); id != "" {
+		info.Metadata["correlation_id"] = id
+	}
+	if !Debug {
+		return info
+	}
+	for _, field := range errstack.Fields(err) {
+		info.Metadata[field.Key] = fmt.Sprint(field.Value)
+	}
+	var se errstack.Error
+	if errors.As(err, &se) {
+		if chain, jsonErr := se.MarshalJSON(); jsonErr == nil {
+			info.Metadata[chainKey] = string(chain)
+		}
+	}
+	return info
+}
+
+/*
+FromErrorInfo() decodes the chain held by an errdetails.ErrorInfo built
+with ErrorInfo(), and returns false if it holds none.
+*/
+func FromErrorInfo(info *errdetails.ErrorInfo) (error, bool) {
+	chain, ok := info.GetMetadata()[chainKey]
+	if !ok {
+		return nil, false
+	}
+	decoded, decodeErr := errstack.Decode([]byte(chain))
+	if decodeErr != nil {
+		return nil, false
+	}
+	return decoded, true
+}
+
+/*
+FromStatus() converts a status received from a server back into an
+error. If the server used ToStatus(), the error carries the code of the
+server's error, and wraps its whole chain in Debug mode, so that
+errors.Is(), errstack.RootCode() and the like work across the call. The
+error still carries the status, for status.FromError() and
+status.Code().
+*/
+func FromStatus(st *status.Status) error {
+	if st == nil || st.Code() == codes.OK {
+		return nil
+	}
+	var cause error = errors.New(st.Message())
+	for _, detail := range st.Details() {
+		if info, ok := detail.(*errdetails.ErrorInfo); ok {
+			if decoded, ok := FromErrorInfo(info); ok {
+				cause = decoded
+			} else if info.GetReason() != "" {
+				cause = errstack.NewCode(info.GetReason(), st.Message())
+			}
+		}
+	}
+	// the full-fidelity chain prevails over the one of the ErrorInfo
+	for _, detail := range st.Details() {
+		if msg, ok := detail.(*errproto.Error); ok {
+			cause = errproto.FromProto(msg)
+		}
+	}
+	return remoteError{cause: cause, status: st}
+}
+
+// remoteError is an error re-hydrated from a status.
+type remoteError struct {
+	cause  error
+	status *status.Status
+}
+
+func (e remoteError) Error() string {
+	return e.cause.Error()
+}
+
+func (e remoteError) Unwrap() error {
+	return e.cause
+}
+
+func (e remoteError) GRPCStatus() *status.Status {
+	return e.status
+}
+
+/*
+UnaryServerInterceptor() returns an interceptor that recovers what the
+handlers throw (and any other panic), and converts the errors with
+ToStatus().
+
+Example:
+
+	server := grpc.NewServer(
+		grpc.ChainUnaryInterceptor(errhandlinggrpc.UnaryServerInterceptor()),
+		grpc.ChainStreamInterceptor(errhandlinggrpc.StreamServerInterceptor()),
+	)
+*/
+func UnaryServerInterceptor() grpc.UnaryServerInterceptor {
+	return func(ctx context.Context, req any, info *grpc.UnaryServerInfo, handler grpc.UnaryHandler) (any, error) {
+		resp, err := unary(ctx, req, handler)
+		if err != nil {
+			return nil, ToStatus(err).Err()
+		}
+		return resp, nil
+	}
+}
+
+func unary(ctx context.Context, req any, handler grpc.UnaryHandler) (resp any, err error) {
+	defer errhandling.CatchPanic(&resp, &err)
+	return handler(ctx, req)
+}
+
+// StreamServerInterceptor() is the streaming UnaryServerInterceptor().
+func StreamServerInterceptor() grpc.StreamServerInterceptor {
+	return func(srv any, ss grpc.ServerStream, info *grpc.StreamServerInfo, handler grpc.StreamHandler) error {
+		if err := stream(srv, ss, handler); err != nil {
+			return ToStatus(err).Err()
+		}
+		return nil
+	}
+}
+
+func stream(srv any, ss grpc.ServerStream, handler grpc.StreamHandler) (err error) {
+	defer errhandling.CatchPanic_(&err)
+	return handler(srv, ss)
+}
+
+/*
+UnaryClientInterceptor() returns an interceptor that converts the errors
+of the calls with FromStatus(), and stacks them under the name of the
+method.
+
+Example:
+
+	conn, err := grpc.NewClient(target,
+		grpc.WithChainUnaryInterceptor(errhandlinggrpc.UnaryClientInterceptor()),
+		grpc.WithChainStreamInterceptor(errhandlinggrpc.StreamClientInterceptor()),
+	)
+*/
+func UnaryClientInterceptor() grpc.UnaryClientInterceptor {
+	return func(ctx context.Context, method string, req, reply any, cc *grpc.ClientConn, invoker grpc.UnaryInvoker, opts ...grpc.CallOption) error {
+		return rehydrate(method, invoker(ctx, method, req, reply, cc, opts...))
+	}
+}
+
+// StreamClientInterceptor() is the streaming UnaryClientInterceptor().
+func StreamClientInterceptor() grpc.StreamClientInterceptor {
+	return func(ctx context.Context, desc *grpc.StreamDesc, cc *grpc.ClientConn, method string, streamer grpc.Streamer, opts ...grpc.CallOption) (grpc.ClientStream, error) {
+		cs, err := streamer(ctx, desc, cc, method, opts...)
+		if err != nil {
+			return nil, rehydrate(method, err)
+		}
+		return clientStream{ClientStream: cs, method: method}, nil
+	}
+}
+
+type clientStream struct {
+	grpc.ClientStream
+	method string
+}
+
+func (s clientStream) SendMsg(m any) error {
+	return rehydrate(s.method, s.ClientStream.SendMsg(m))
+}
+
+func (s clientStream) RecvMsg(m any) error {
+	return rehydrate(s.method, s.ClientStream.RecvMsg(m))
+}
+
+// rehydrate() converts the error of a call, leaving io.EOF untouched.
+func rehydrate(method string, err error) error {
+	if err == nil || err == io.EOF {
+		return err
+	}
+	st, ok := status.FromError(err)
+	if !ok {
+		return errstack.New("call "+method, err)
+	}
+	return errstack.New("call "+method, FromStatus(st))
+}
@@ -0,0 +1,77 @@
+package errhandlinggrpc_test
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"testing"
+
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+	"google.golang.org/genproto/googleapis/rpc/errdetails"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+
+	. "github.com/the-zucc/errhandling"
+	errproto "github.com/the-zucc/errhandling/err-proto"
+	errstack "github.com/the-zucc/errhandling/err-stack"
+	errhandlinggrpc "github.com/the-zucc/errhandling/errhandling-grpc"
+)
+
+func TestGrpc(t *testing.T) {
+	RegisterFailHandler(Fail)
+	RunSpecs(t, "errhandlinggrpc tests")
+}
+
+var _ = Describe("errhandlinggrpc tests", func() {
+	It("should carry a thrown error from the server to the client in Debug mode", func() {
+		errhandlinggrpc.Debug = true
+		defer func() { errhandlinggrpc.Debug = false }()
+		server := errhandlinggrpc.UnaryServerInterceptor()
+		client := errhandlinggrpc.UnaryClientInterceptor()
+		errNoStock := errors.New("no stock")
+		_, serverErr := server(context.Background(), nil, &grpc.UnaryServerInfo{}, func(ctx context.Context, req any) (any, error) {
+			Throw_(errstack.NewCode("OUT_OF_STOCK", "reserve items", errNoStock).
+				With("http_status", http.StatusConflict).
+				With("sku", "A-42"))
+			return nil, nil
+		})
+		st, _ := status.FromError(serverErr)
+		Expect(st.Code()).To(Equal(codes.AlreadyExists))
+		// go through the wire format
+		received := status.FromProto(st.Proto()).Err()
+		err := client(context.Background(), "/shop.Orders/Place", nil, nil, nil,
+			func(ctx context.Context, method string, req, reply any, cc *grpc.ClientConn, opts ...grpc.CallOption) error {
+				return received
+			})
+		Expect(status.Code(err)).To(Equal(codes.AlreadyExists))
+		Expect(errstack.RootCode(err)).To(Equal("OUT_OF_STOCK"))
+		sku, ok := errstack.Field(err, "sku")
+		Expect(ok).To(BeTrue())
+		Expect(sku).To(Equal("A-42"))
+		Expect(err.Error()).To(Equal("no stock -> reserve items -> call /shop.Orders/Place"))
+	})
+	It("should only send the code and the public message of the errors by default", func() {
+		st := errhandlinggrpc.ToStatus(errstack.NewCode("OUT_OF_STOCK", "SELECT stock FROM items", errors.New("no stock")).
+			With("sku", "A-42"))
+		Expect(st.Message()).To(Equal(errhandlinggrpc.InternalMessage))
+		Expect(st.Details()).To(HaveLen(1))
+		info := st.Details()[0].(*errdetails.ErrorInfo)
+		Expect(info.GetReason()).To(Equal("OUT_OF_STOCK"))
+		Expect(info.GetMetadata()).To(HaveLen(1))
+		Expect(info.GetMetadata()).To(HaveKey("correlation_id"))
+		err := errhandlinggrpc.FromStatus(errhandlinggrpc.ToStatus(errstack.New("SELECT stock FROM items").
+			WithCode("OUT_OF_STOCK").
+			WithPublicMessage("This item is out of stock.")))
+		Expect(errstack.RootCode(err)).To(Equal("OUT_OF_STOCK"))
+		Expect(err.Error()).To(Equal("This item is out of stock."))
+	})
+	It("should only attach the full-fidelity chain in Debug mode", func() {
+		err := errstack.NewCode("OUT_OF_STOCK", "reserve items", errors.New("no stock"))
+		Expect(errhandlinggrpc.ToStatus(err).Details()).NotTo(ContainElement(BeAssignableToTypeOf(&errproto.Error{})))
+		errhandlinggrpc.Debug = true
+		defer func() { errhandlinggrpc.Debug = false }()
+		Expect(errhandlinggrpc.ToStatus(err).Details()).To(ContainElement(BeAssignableToTypeOf(&errproto.Error{})))
+	})
+})
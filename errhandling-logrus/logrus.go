@@ -0,0 +1,65 @@
+/*
+Package errhandlinglogrus adds the details of stacked errors to logrus
+entries, so that services on logrus get the same structured output as
+the slog and zap integrations.
+*/
+package errhandlinglogrus
+
+import (
+	"errors"
+	"log/slog"
+
+	"github.com/sirupsen/logrus"
+	errstack "github.com/the-zucc/errhandling/err-stack"
+)
+
+/*
+Hook is a logrus hook that, when the error of an entry (set with
+WithError()) is or wraps a stacked error, attaches its root cause, code,
+severity, fields and trace to the entry, as "<error key>.<name>" fields.
+
+Example:
+
+	logrus.AddHook(errhandlinglogrus.Hook{})
+	logrus.WithError(err).Error("request failed")
+*/
+type Hook struct {
+	// Levels of the entries handled by the hook, all of them if empty.
+	LogLevels []logrus.Level
+}
+
+func (h Hook) Levels() []logrus.Level {
+	if len(h.LogLevels) == 0 {
+		return logrus.AllLevels
+	}
+	return h.LogLevels
+}
+
+func (h Hook) Fire(entry *logrus.Entry) error {
+	err, ok := entry.Data[logrus.ErrorKey].(error)
+	if !ok {
+		return nil
+	}
+	var se errstack.Error
+	if !errors.As(err, &se) {
+		return nil
+	}
+	for _, attr := range se.LogValue().Group() {
+		switch attr.Key {
+		case "msg", "error":
+			continue
+		case "fields":
+			for _, field := range attr.Value.Group() {
+				entry.Data[logrus.ErrorKey+".fields."+field.Key] = field.Value.Any()
+			}
+		default:
+			entry.Data[logrus.ErrorKey+"."+attr.Key] = value(attr.Value)
+		}
+	}
+	return nil
+}
+
+// this returns the Go value of a slog value
+func value(v slog.Value) any {
+	return v.Resolve().Any()
+}
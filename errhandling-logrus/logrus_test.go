@@ -0,0 +1,33 @@
+package errhandlinglogrus_test
+
+import (
+	"bytes"
+	"testing"
+
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+
+	"github.com/sirupsen/logrus"
+	errstack "github.com/the-zucc/errhandling/err-stack"
+	errhandlinglogrus "github.com/the-zucc/errhandling/errhandling-logrus"
+)
+
+func TestErrHandlingLogrus(t *testing.T) {
+	RegisterFailHandler(Fail)
+	RunSpecs(t, "errhandlinglogrus tests")
+}
+
+var _ = Describe("errhandlinglogrus tests", func() {
+	It("Hook should attach the root cause, code and trace of the error", func() {
+		var buf bytes.Buffer
+		logger := logrus.New()
+		logger.SetOutput(&buf)
+		logger.SetFormatter(&logrus.JSONFormatter{})
+		logger.AddHook(errhandlinglogrus.Hook{})
+		err := errstack.NewCode("CONFIG", "open config", errstack.New("read file"))
+		logger.WithError(err).Error("request failed")
+		Expect(buf.String()).To(ContainSubstring(`"error.code":"CONFIG"`))
+		Expect(buf.String()).To(ContainSubstring(`"error.root_cause":"read file"`))
+		Expect(buf.String()).To(ContainSubstring(`"error.trace":["open config","read file"]`))
+	})
+})
@@ -0,0 +1,86 @@
+/*
+Package errhandlingmultierr integrates the errhandling package with the
+aggregated errors of go.uber.org/multierr and
+github.com/hashicorp/go-multierror: once installed, the aggregates
+wrapped by stacked errors are unpacked into separate causes, and stacked
+errors with several causes can be converted back into aggregates.
+*/
+package errhandlingmultierr
+
+import (
+	"sync"
+
+	"github.com/hashicorp/go-multierror"
+	errstack "github.com/the-zucc/errhandling/err-stack"
+	"go.uber.org/multierr"
+)
+
+var install sync.Once
+
+/*
+Install() registers the aggregates of both libraries with
+errstack.RegisterAggregate(), so that their errors become the branches
+of the stacked errors wrapping them. It may be called several times.
+
+Example:
+
+	errhandlingmultierr.Install()
+	err := errstack.New("could not close the files", multierr.Combine(errs...))
+*/
+func Install() {
+	install.Do(func() {
+		errstack.RegisterAggregate(unpack)
+	})
+}
+
+// this unpacks the aggregates of both libraries, returning nil for other errors
+func unpack(err error) []error {
+	if agg, ok := err.(*multierror.Error); ok {
+		return agg.WrappedErrors()
+	}
+	// multierr.Errors() returns other errors alone
+	if errs := multierr.Errors(err); len(errs) > 1 || len(errs) == 1 && errs[0] != err {
+		return errs
+	}
+	return nil
+}
+
+/*
+this returns the branches of the chain of any error: the causes of its
+last error if it has several, or the error alone
+*/
+func branches(err error) []error {
+	if se, ok := errstack.Root(err).(errstack.Error); ok {
+		if causes := se.Unwrap(); len(causes) > 1 {
+			return causes
+		}
+	}
+	return []error{err}
+}
+
+/*
+ToMultierr() converts an error whose chain branches out (such as the
+errors returned by errstack.Join()) into a go.uber.org/multierr
+aggregate of its branches, leaving out the errors above the branches,
+or returns the error as is if its chain does not branch out. It returns
+nil if the error is nil.
+*/
+func ToMultierr(err error) error {
+	if err == nil {
+		return nil
+	}
+	return multierr.Combine(branches(err)...)
+}
+
+/*
+ToMultierror() converts an error whose chain branches out (such as the
+errors returned by errstack.Join()) into a github.com/hashicorp/go-multierror
+aggregate of its branches, like ToMultierr(). It returns nil if the
+error is nil.
+*/
+func ToMultierror(err error) *multierror.Error {
+	if err == nil {
+		return nil
+	}
+	return multierror.Append(nil, branches(err)...)
+}
@@ -0,0 +1,44 @@
+package errhandlingmultierr_test
+
+import (
+	"errors"
+	"testing"
+
+	"github.com/hashicorp/go-multierror"
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+	errstack "github.com/the-zucc/errhandling/err-stack"
+	errhandlingmultierr "github.com/the-zucc/errhandling/errhandling-multierr"
+	"go.uber.org/multierr"
+)
+
+func TestMultierr(t *testing.T) {
+	RegisterFailHandler(Fail)
+	RunSpecs(t, "errhandlingmultierr tests")
+}
+
+var _ = Describe("errhandlingmultierr tests", func() {
+	BeforeEach(func() {
+		errhandlingmultierr.Install()
+	})
+
+	It("should unpack the aggregates into separate causes", func() {
+		diskFull, timeout := errors.New("disk full"), errors.New("timeout")
+		err := errstack.New("sync", multierr.Combine(diskFull, timeout))
+		Expect(err.Unwrap()).To(Equal([]error{diskFull, timeout}))
+		Expect(err.PrintableError()).To(ContainSubstring("Root causes:\n\tdisk full\n\ttimeout"))
+		joined := errstack.Join(multierror.Append(nil, diskFull), timeout)
+		Expect(joined.(errstack.Error).Unwrap()).To(Equal([]error{diskFull, timeout}))
+		Expect(errstack.New("load", diskFull).Cause).To(Equal(diskFull))
+	})
+
+	It("should convert the branches back into aggregates", func() {
+		diskFull, timeout := errors.New("disk full"), errors.New("timeout")
+		err := errstack.Join(diskFull, timeout)
+		Expect(multierr.Errors(errhandlingmultierr.ToMultierr(err))).To(Equal([]error{diskFull, timeout}))
+		Expect(errhandlingmultierr.ToMultierror(err).Errors).To(Equal([]error{diskFull, timeout}))
+		single := errstack.New("load", diskFull)
+		Expect(errhandlingmultierr.ToMultierr(single)).To(Equal(single))
+		Expect(errhandlingmultierr.ToMultierr(nil)).To(BeNil())
+	})
+})
@@ -0,0 +1,120 @@
+/*
+Package errhandlingprometheus exposes Prometheus metrics on the errors
+thrown with the errhandling package: a counter of thrown errors by code
+and severity, a counter of thrown errors by fingerprint, and a histogram
+of the depth of their chains.
+*/
+package errhandlingprometheus
+
+import (
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/the-zucc/errhandling"
+	errstack "github.com/the-zucc/errhandling/err-stack"
+)
+
+// Metrics holds the collectors updated for every thrown error.
+type Metrics struct {
+	Thrown       *prometheus.CounterVec
+	Fingerprints *prometheus.CounterVec
+	Depth        prometheus.Histogram
+	uninstall    func()
+}
+
+/*
+NewMetrics() returns the collectors, named
+<namespace>_errors_thrown_total (labelled by code and severity),
+<namespace>_errors_thrown_by_fingerprint_total (labelled by fingerprint,
+see errstack.Fingerprint(), and code) and <namespace>_error_chain_depth.
+*/
+func NewMetrics(namespace string) *Metrics {
+	return &Metrics{
+		Thrown: prometheus.NewCounterVec(prometheus.CounterOpts{
+			Namespace: namespace,
+			Name:      "errors_thrown_total",
+			Help:      "Number of errors passed up the call stack with Throw() or Return().",
+		}, []string{"code", "severity"}),
+		Fingerprints: prometheus.NewCounterVec(prometheus.CounterOpts{
+			Namespace: namespace,
+			Name:      "errors_thrown_by_fingerprint_total",
+			Help:      "Number of errors passed up the call stack, grouped by failure.",
+		}, []string{"fingerprint", "code"}),
+		Depth: prometheus.NewHistogram(prometheus.HistogramOpts{
+			Namespace: namespace,
+			Name:      "error_chain_depth",
+			Help:      "Depth of the cause chain of thrown errors.",
+			Buckets:   []float64{1, 2, 3, 4, 6, 8, 12, 16},
+		}),
+	}
+}
+
+/*
+Install() registers the metrics with the registerer, and installs a
+throw hook (see errhandling.OnThrow()) that updates them for every
+thrown error, until Uninstall() is called.
+
+Example:
+
+	errhandlingprometheus.Install(prometheus.DefaultRegisterer, "myapp")
+*/
+func Install(reg prometheus.Registerer, namespace string) (*Metrics, error) {
+	m := NewMetrics(namespace)
+	if err := reg.Register(m.Thrown); err != nil {
+		return nil, errstack.New("could not register the thrown errors counter", err)
+	}
+	if err := reg.Register(m.Fingerprints); err != nil {
+		return nil, errstack.New("could not register the thrown errors by fingerprint counter", err)
+	}
+	if err := reg.Register(m.Depth); err != nil {
+		return nil, errstack.New("could not register the error chain depth histogram", err)
+	}
+	remove := errhandling.OnThrow(func(ev errhandling.Event) {
+		m.Observe(ev.Err)
+	})
+	m.uninstall = func() {
+		remove()
+		reg.Unregister(m.Thrown)
+		reg.Unregister(m.Fingerprints)
+		reg.Unregister(m.Depth)
+	}
+	return m, nil
+}
+
+/*
+Uninstall() removes the throw hook installed by Install(), and
+unregisters the metrics from the registerer. It does nothing for the
+metrics returned by NewMetrics().
+*/
+func (m *Metrics) Uninstall() {
+	if m.uninstall != nil {
+		m.uninstall()
+	}
+}
+
+// Observe() updates the metrics for the error.
+func (m *Metrics) Observe(err error) {
+	if err == nil {
+		return
+	}
+	m.Thrown.WithLabelValues(errstack.Code(err), errstack.Severity(err).String()).Inc()
+	m.Fingerprints.WithLabelValues(errstack.Fingerprint(err), errstack.Code(err)).Inc()
+	m.Depth.Observe(float64(depth(err)))
+}
+
+// this returns the length of the longest branch of the chain
+func depth(err error) int {
+	if err == nil {
+		return 0
+	}
+	deepest := 0
+	switch e := err.(type) {
+	case interface{ Unwrap() []error }:
+		for _, cause := range e.Unwrap() {
+			if d := depth(cause); d > deepest {
+				deepest = d
+			}
+		}
+	case interface{ Unwrap() error }:
+		deepest = depth(e.Unwrap())
+	}
+	return deepest + 1
+}
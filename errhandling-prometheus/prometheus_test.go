@@ -0,0 +1,46 @@
+package errhandlingprometheus_test
+
+import (
+	"errors"
+	"testing"
+
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/testutil"
+	. "github.com/the-zucc/errhandling"
+	errstack "github.com/the-zucc/errhandling/err-stack"
+	errhandlingprometheus "github.com/the-zucc/errhandling/errhandling-prometheus"
+)
+
+func TestErrHandlingPrometheus(t *testing.T) {
+	RegisterFailHandler(Fail)
+	RunSpecs(t, "errhandlingprometheus tests")
+}
+
+var _ = Describe("errhandlingprometheus tests", func() {
+	It("Install() should count thrown errors by code and severity", func() {
+		m, err := errhandlingprometheus.Install(prometheus.NewRegistry(), "test")
+		Expect(err).To(BeNil())
+		_ = func() (e error) {
+			defer Catch_(&e)
+			Throw_(errstack.NewCode("CONFIG", "open config", errors.New("ENOENT")))
+			return nil
+		}()
+		Expect(testutil.ToFloat64(m.Thrown.WithLabelValues("CONFIG", "error"))).To(Equal(1.0))
+		Expect(testutil.CollectAndCount(m.Fingerprints)).To(Equal(1))
+		Expect(testutil.CollectAndCount(m.Depth)).To(Equal(1))
+	})
+	It("Uninstall() should remove the throw hook and the metrics", func() {
+		reg := prometheus.NewRegistry()
+		m, err := errhandlingprometheus.Install(reg, "test")
+		Expect(err).To(BeNil())
+		m.Uninstall()
+		_ = Try(func() { Throw_(errstack.NewCode("CONFIG", "open config")) }).Err()
+		Expect(testutil.ToFloat64(m.Thrown.WithLabelValues("CONFIG", "error"))).To(Equal(0.0))
+		reinstalled, err := errhandlingprometheus.Install(reg, "test")
+		Expect(err).To(BeNil())
+		reinstalled.Uninstall()
+	})
+})
@@ -0,0 +1,184 @@
+/*
+Package errhandlingsentry reports stacked errors to Sentry, converting
+their chain into an exception chain, their code and root cause into a
+fingerprint, and their fields into tags.
+*/
+package errhandlingsentry
+
+import (
+	"fmt"
+	"time"
+
+	"github.com/getsentry/sentry-go"
+	"github.com/the-zucc/errhandling"
+	errstack "github.com/the-zucc/errhandling/err-stack"
+)
+
+// FlushTimeout is how long Recover() waits for events to be sent.
+var FlushTimeout = 2 * time.Second
+
+/*
+Event() converts the error into a Sentry event. Every error of the chain
+becomes an exception, the root cause first as Sentry expects; stacked
+errors carry the call stack captured when they were created. Events of
+errors with the same errstack.Fingerprint() are grouped together. The
+correlation ID of the error, given by errstack.Identify() if it has
+none, is the "correlation_id" tag. The metadata
+set with errstack.SetMetadata() gives the release, the server name and
+the "service", "commit" and "region" tags.
+*/
+func Event(err error) *sentry.Event {
+	event := sentry.NewEvent()
+	event.Level = level(errstack.Severity(err))
+	event.Message = errstack.Redact(err.Error())
+	chain := unwrap(err)
+	for i := len(chain) - 1; i >= 0; i-- {
+		event.Exception = append(event.Exception, exception(chain[i]))
+	}
+	event.Fingerprint = []string{errstack.Fingerprint(err)}
+	if code := errstack.Code(err); code != "" {
+		event.Tags["code"] = code
+	}
+	if id := errstack.ID(errstack.Identify(err)); id != "" {
+		event.Tags["correlation_id"] = id
+	}
+	meta := errstack.CurrentMetadata()
+	event.Release, event.ServerName = meta.Version, meta.Hostname
+	for key, value := range map[string]string{"service": meta.Service, "commit": meta.Commit, "region": meta.Region} {
+		if value != "" {
+			event.Tags[key] = value
+		}
+	}
+	for _, field := range errstack.Fields(err) {
+		event.Tags[field.Key] = fmt.Sprint(field.Value)
+	}
+	return event
+}
+
+/*
+Report() sends the error to Sentry through the current hub, and returns
+the ID of the event, or nil if it was not sent. It does nothing if the
+error is nil.
+*/
+func Report(err error) *sentry.EventID {
+	return ReportTo(sentry.CurrentHub(), err)
+}
+
+// ReportTo() behaves like Report(), through the provided hub.
+func ReportTo(hub *sentry.Hub, err error) *sentry.EventID {
+	if err == nil {
+		return nil
+	}
+	return hub.CaptureEvent(Event(err))
+}
+
+/*
+Install() registers Report() as a reporter of the errhandling package,
+so that the errors passed to errhandling.Report(), like the failures of
+the shutdown hooks, are sent to Sentry. The returned function removes
+it.
+*/
+func Install() (remove func()) {
+	return errhandling.OnReport(func(ev errhandling.Event) {
+		Report(ev.Err)
+	})
+}
+
+/*
+Catch_() reports the error pointed to by errAddr, if it is not nil. It
+is meant to be deferred right before the enclosing function's Catch(),
+so that it runs after Catch() has set the returned error.
+
+Example:
+
+	func Checkout(cart Cart) (e error) {
+		defer errhandlingsentry.Catch_(&e)
+		defer Catch_(&e)
+		Throw_(charge(cart))
+		return nil
+	}
+*/
+func Catch_(errAddr *error) {
+	if errAddr != nil {
+		Report(*errAddr)
+	}
+}
+
+/*
+Recover() reports any panic that reaches it, including errors that were
+thrown without being caught, then panics again. It is meant to be
+deferred at the top of main() or of a goroutine.
+*/
+func Recover() {
+	if panicInfo := recover(); panicInfo != nil {
+		err, ok := panicInfo.(error)
+		if !ok {
+			err = errstack.New(fmt.Sprintf("panic: %v", panicInfo))
+		}
+		Report(err)
+		sentry.Flush(FlushTimeout)
+		panic(panicInfo)
+	}
+}
+
+// this returns the errors of the chain, outermost first
+func unwrap(err error) []error {
+	chain := []error{}
+	for err != nil {
+		chain = append(chain, err)
+		switch e := err.(type) {
+		case interface{ Unwrap() []error }:
+			causes := e.Unwrap()
+			if len(causes) == 0 {
+				return chain
+			}
+			err = causes[0]
+		case interface{ Unwrap() error }:
+			err = e.Unwrap()
+		default:
+			return chain
+		}
+	}
+	return chain
+}
+
+// this converts an error of the chain into a Sentry exception
+func exception(err error) sentry.Exception {
+	se, ok := err.(errstack.Error)
+	if !ok {
+		return sentry.Exception{Type: fmt.Sprintf("%T", err), Value: errstack.Redact(err.Error())}
+	}
+	exc := sentry.Exception{Type: "errstack.Error", Value: se.Msg()}
+	if se.Code() != "" {
+		exc.Type = se.Code()
+	}
+	frames := se.StackFrames()
+	if len(frames) > 0 {
+		exc.Stacktrace = &sentry.Stacktrace{}
+		// Sentry expects the frames from the outermost caller inwards
+		for i := len(frames) - 1; i >= 0; i-- {
+			exc.Stacktrace.Frames = append(exc.Stacktrace.Frames, sentry.Frame{
+				Function: frames[i].Function,
+				AbsPath:  frames[i].File,
+				Lineno:   frames[i].Line,
+				InApp:    true,
+			})
+		}
+	}
+	return exc
+}
+
+// this converts a severity into a Sentry level
+func level(severity errstack.Level) sentry.Level {
+	switch severity {
+	case errstack.SeverityDebug:
+		return sentry.LevelDebug
+	case errstack.SeverityInfo:
+		return sentry.LevelInfo
+	case errstack.SeverityWarn:
+		return sentry.LevelWarning
+	case errstack.SeverityFatal:
+		return sentry.LevelFatal
+	}
+	return sentry.LevelError
+}
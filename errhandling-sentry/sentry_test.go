@@ -0,0 +1,38 @@
+package errhandlingsentry_test
+
+import (
+	"errors"
+	"testing"
+
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+
+	errstack "github.com/the-zucc/errhandling/err-stack"
+	errhandlingsentry "github.com/the-zucc/errhandling/errhandling-sentry"
+)
+
+func TestErrHandlingSentry(t *testing.T) {
+	RegisterFailHandler(Fail)
+	RunSpecs(t, "errhandlingsentry tests")
+}
+
+var _ = Describe("errhandlingsentry tests", func() {
+	It("Event() should convert the chain into exceptions, fingerprint and tags", func() {
+		err := errstack.NewCode("CONFIG", "open config", errors.New("ENOENT")).With("path", "app.yaml")
+		event := errhandlingsentry.Event(err)
+		Expect(event.Exception).To(HaveLen(2))
+		Expect(event.Exception[0].Value).To(Equal("ENOENT"))
+		Expect(event.Exception[1].Type).To(Equal("CONFIG"))
+		Expect(event.Exception[1].Stacktrace.Frames).NotTo(BeEmpty())
+		Expect(event.Fingerprint).To(Equal([]string{errstack.Fingerprint(err)}))
+		Expect(event.Tags).To(HaveKeyWithValue("path", "app.yaml"))
+
+		errstack.SetMetadata(errstack.Metadata{Service: "billing", Version: "v1.2.0", Hostname: "billing-0"})
+		defer errstack.SetMetadata(errstack.Metadata{})
+		event = errhandlingsentry.Event(err)
+		Expect(event.Release).To(Equal("v1.2.0"))
+		Expect(event.ServerName).To(Equal("billing-0"))
+		Expect(event.Tags).To(HaveKeyWithValue("service", "billing"))
+		Expect(event.Tags).NotTo(HaveKey("region"))
+	})
+})
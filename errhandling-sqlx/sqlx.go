@@ -0,0 +1,73 @@
+/*
+Package errhandlingsqlx wraps sqlx so that the errors of the queries are
+translated into errstack errors with the codes of the errsql package.
+*/
+package errhandlingsqlx
+
+import (
+	"context"
+	"database/sql"
+
+	"github.com/jmoiron/sqlx"
+	errsql "github.com/the-zucc/errhandling/err-sql"
+)
+
+/*
+DB wraps a *sqlx.DB, translating the errors of its query methods with
+errsql.TranslateQuery(), so that they carry the code of the failure and
+the "query" field. The other methods of sqlx are still available, and
+return untranslated errors.
+
+Example:
+
+	db := errhandlingsqlx.NewDB(Must(sqlx.Connect("postgres", dsn)))
+	var user User
+	err := db.GetContext(ctx, &user, "SELECT * FROM users WHERE id = $1", id)
+	if errstack.Code(err) == errsql.CODE_NO_ROWS {
+		...
+	}
+*/
+type DB struct {
+	*sqlx.DB
+}
+
+// NewDB() wraps the database.
+func NewDB(db *sqlx.DB) *DB {
+	return &DB{DB: db}
+}
+
+func (db *DB) Get(dest any, query string, args ...any) error {
+	return errsql.TranslateQuery(db.DB.Get(dest, query, args...), query)
+}
+
+func (db *DB) GetContext(ctx context.Context, dest any, query string, args ...any) error {
+	return errsql.TranslateQuery(db.DB.GetContext(ctx, dest, query, args...), query)
+}
+
+func (db *DB) Select(dest any, query string, args ...any) error {
+	return errsql.TranslateQuery(db.DB.Select(dest, query, args...), query)
+}
+
+func (db *DB) SelectContext(ctx context.Context, dest any, query string, args ...any) error {
+	return errsql.TranslateQuery(db.DB.SelectContext(ctx, dest, query, args...), query)
+}
+
+func (db *DB) NamedExec(query string, arg any) (sql.Result, error) {
+	result, err := db.DB.NamedExec(query, arg)
+	return result, errsql.TranslateQuery(err, query)
+}
+
+func (db *DB) NamedExecContext(ctx context.Context, query string, arg any) (sql.Result, error) {
+	result, err := db.DB.NamedExecContext(ctx, query, arg)
+	return result, errsql.TranslateQuery(err, query)
+}
+
+func (db *DB) Exec(query string, args ...any) (sql.Result, error) {
+	result, err := db.DB.Exec(query, args...)
+	return result, errsql.TranslateQuery(err, query)
+}
+
+func (db *DB) ExecContext(ctx context.Context, query string, args ...any) (sql.Result, error) {
+	result, err := db.DB.ExecContext(ctx, query, args...)
+	return result, errsql.TranslateQuery(err, query)
+}
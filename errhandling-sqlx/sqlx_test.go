@@ -0,0 +1,57 @@
+package errhandlingsqlx_test
+
+import (
+	"context"
+	"database/sql"
+	"database/sql/driver"
+	"errors"
+	"io"
+	"testing"
+
+	"github.com/jmoiron/sqlx"
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+
+	errsql "github.com/the-zucc/errhandling/err-sql"
+	errstack "github.com/the-zucc/errhandling/err-stack"
+	errhandlingsqlx "github.com/the-zucc/errhandling/errhandling-sqlx"
+)
+
+func TestSqlx(t *testing.T) {
+	RegisterFailHandler(Fail)
+	RunSpecs(t, "errhandlingsqlx tests")
+}
+
+var _ = Describe("errhandlingsqlx tests", func() {
+	It("DB should translate the errors of the queries", func() {
+		db := errhandlingsqlx.NewDB(sqlx.NewDb(sql.OpenDB(connector{}), "postgres"))
+		var id int
+		err := db.Get(&id, "SELECT id FROM users WHERE email = $1", "jane@example.com")
+		Expect(errstack.Code(err)).To(Equal(errsql.CODE_NO_ROWS))
+		Expect(errors.Is(err, sql.ErrNoRows)).To(BeTrue())
+		query, _ := errstack.Field(err, "query")
+		Expect(query).To(Equal("SELECT id FROM users WHERE email = $1"))
+	})
+})
+
+// this is a database/sql driver whose queries return no rows
+type connector struct{}
+
+func (connector) Connect(context.Context) (driver.Conn, error) { return conn{}, nil }
+func (connector) Driver() driver.Driver                        { return nil }
+
+type conn struct{}
+
+func (conn) Prepare(string) (driver.Stmt, error) { return nil, errors.New("not implemented") }
+func (conn) Close() error                        { return nil }
+func (conn) Begin() (driver.Tx, error)           { return nil, errors.New("not implemented") }
+
+func (conn) QueryContext(context.Context, string, []driver.NamedValue) (driver.Rows, error) {
+	return noRows{}, nil
+}
+
+type noRows struct{}
+
+func (noRows) Columns() []string         { return []string{"id"} }
+func (noRows) Close() error              { return nil }
+func (noRows) Next([]driver.Value) error { return io.EOF }
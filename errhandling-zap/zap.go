@@ -0,0 +1,145 @@
+/*
+Package errhandlingzap encodes stacked errors as structured zap fields,
+instead of the flattened string zap.Error() produces.
+*/
+package errhandlingzap
+
+import (
+	"errors"
+	"log/slog"
+
+	errstack "github.com/the-zucc/errhandling/err-stack"
+	"go.uber.org/zap"
+	"go.uber.org/zap/zapcore"
+)
+
+/*
+Field() returns a zap field named "error" encoding the chain of the
+error: its message, code, severity, root cause, fields and the messages
+of the whole chain. Errors that are not (and do not wrap) stacked errors
+are encoded as with zap.Error().
+
+Example:
+
+	logger.Error("request failed", errhandlingzap.Field(err))
+*/
+func Field(err error) zap.Field {
+	return NamedField("error", err)
+}
+
+// NamedField() behaves like Field(), with the provided field name.
+func NamedField(key string, err error) zap.Field {
+	var se errstack.Error
+	if err == nil || !errors.As(err, &se) {
+		return zap.NamedError(key, err)
+	}
+	return zap.Object(key, chain{err: err, se: se})
+}
+
+// chain encodes a stacked error, possibly wrapped by an outside error.
+type chain struct {
+	err error
+	se  errstack.Error
+}
+
+func (c chain) MarshalLogObject(enc zapcore.ObjectEncoder) error {
+	for _, attr := range c.se.LogValue().Group() {
+		if attr.Key == "error" {
+			enc.AddString("error", errstack.Redact(c.err.Error()))
+			continue
+		}
+		if err := encodeAttr(enc, attr); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// this encodes a slog attribute of the error's log value as a zap field
+func encodeAttr(enc zapcore.ObjectEncoder, attr slog.Attr) error {
+	value := attr.Value.Resolve()
+	switch value.Kind() {
+	case slog.KindString:
+		enc.AddString(attr.Key, value.String())
+	case slog.KindInt64:
+		enc.AddInt64(attr.Key, value.Int64())
+	case slog.KindUint64:
+		enc.AddUint64(attr.Key, value.Uint64())
+	case slog.KindFloat64:
+		enc.AddFloat64(attr.Key, value.Float64())
+	case slog.KindBool:
+		enc.AddBool(attr.Key, value.Bool())
+	case slog.KindDuration:
+		enc.AddDuration(attr.Key, value.Duration())
+	case slog.KindTime:
+		enc.AddTime(attr.Key, value.Time())
+	case slog.KindGroup:
+		return enc.AddObject(attr.Key, zapcore.ObjectMarshalerFunc(func(enc zapcore.ObjectEncoder) error {
+			for _, attr := range value.Group() {
+				if err := encodeAttr(enc, attr); err != nil {
+					return err
+				}
+			}
+			return nil
+		}))
+	default:
+		if trace, ok := value.Any().([]string); ok {
+			return enc.AddArray(attr.Key, zapcore.ArrayMarshalerFunc(func(enc zapcore.ArrayEncoder) error {
+				for _, msg := range trace {
+					enc.AppendString(msg)
+				}
+				return nil
+			}))
+		}
+		return enc.AddReflected(attr.Key, value.Any())
+	}
+	return nil
+}
+
+/*
+NewCore() wraps a zapcore.Core so that every error field holding a
+stacked error (such as those added with zap.Error()) is expanded as
+with Field().
+
+Example:
+
+	logger := zap.New(errhandlingzap.NewCore(core))
+	logger.Error("request failed", zap.Error(err)) // expanded
+*/
+func NewCore(next zapcore.Core) zapcore.Core {
+	return &expandingCore{Core: next}
+}
+
+type expandingCore struct {
+	zapcore.Core
+}
+
+func (c *expandingCore) With(fields []zapcore.Field) zapcore.Core {
+	return &expandingCore{Core: c.Core.With(expand(fields))}
+}
+
+func (c *expandingCore) Check(entry zapcore.Entry, checked *zapcore.CheckedEntry) *zapcore.CheckedEntry {
+	if c.Enabled(entry.Level) {
+		return checked.AddCore(entry, c)
+	}
+	return checked
+}
+
+func (c *expandingCore) Write(entry zapcore.Entry, fields []zapcore.Field) error {
+	return c.Core.Write(entry, expand(fields))
+}
+
+// this expands the error fields holding stacked errors
+func expand(fields []zapcore.Field) []zapcore.Field {
+	expanded := make([]zapcore.Field, len(fields))
+	for i, field := range fields {
+		expanded[i] = field
+		if field.Type != zapcore.ErrorType {
+			continue
+		}
+		if err, ok := field.Interface.(error); ok {
+			expanded[i] = NamedField(field.Key, err)
+		}
+	}
+	return expanded
+}
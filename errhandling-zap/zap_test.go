@@ -0,0 +1,37 @@
+package errhandlingzap_test
+
+import (
+	"bytes"
+	"errors"
+	"testing"
+
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+
+	errstack "github.com/the-zucc/errhandling/err-stack"
+	errhandlingzap "github.com/the-zucc/errhandling/errhandling-zap"
+	"go.uber.org/zap"
+	"go.uber.org/zap/zapcore"
+)
+
+func TestErrHandlingZap(t *testing.T) {
+	RegisterFailHandler(Fail)
+	RunSpecs(t, "errhandlingzap tests")
+}
+
+var _ = Describe("errhandlingzap tests", func() {
+	It("NewCore() should expand stacked errors passed to zap.Error()", func() {
+		var buf bytes.Buffer
+		core := zapcore.NewCore(
+			zapcore.NewJSONEncoder(zap.NewProductionEncoderConfig()),
+			zapcore.AddSync(&buf),
+			zap.DebugLevel,
+		)
+		logger := zap.New(errhandlingzap.NewCore(core))
+		err := errstack.NewCode("CONFIG", "read file", errors.New("ENOENT")).With("path", "app.yaml")
+		logger.Error("request failed", zap.Error(err))
+		Expect(buf.String()).To(ContainSubstring(`"code":"CONFIG"`))
+		Expect(buf.String()).To(ContainSubstring(`"fields":{"path":"app.yaml"}`))
+		Expect(buf.String()).To(ContainSubstring(`"trace":["read file","ENOENT"]`))
+	})
+})
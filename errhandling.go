@@ -2,6 +2,8 @@ package errhandling
 
 import (
 	"errors"
+	"fmt"
+	"sync"
 
 	errstack "github.com/the-zucc/errhandling/err-stack"
 )
@@ -23,6 +25,77 @@ type _err struct {
 	err error
 }
 
+/*
+thrown is implemented by every value this library panics on, so
+that the cleanup operations can retrieve the error regardless of
+the value type that was thrown alongside it.
+*/
+type thrown interface {
+	thrownErr() error
+	// thrownVal() returns the value thrown alongside the error, if any
+	thrownVal() any
+	// withErr() returns a copy of the value carrying another error
+	withErr(err error) thrown
+	// release() recycles the value, once its error was caught
+	release()
+}
+
+func (ve *valErr[T]) thrownErr() error { return ve.err }
+
+func (ve *valErr[T]) thrownVal() any { return ve.val }
+
+func (ve *valErr[T]) withErr(err error) thrown { return &valErr[T]{val: ve.val, err: err} }
+
+func (ve *valErr[T]) release() {
+	*ve = valErr[T]{}
+	valErrPool[T]().Put(ve)
+}
+
+func (e *_err) thrownErr() error { return e.err }
+
+func (e *_err) thrownVal() any { return nil }
+
+func (e *_err) withErr(err error) thrown { return &_err{err: err} }
+
+func (e *_err) release() {
+	e.err = nil
+	errPool.Put(e)
+}
+
+/*
+The values thrown are recycled once their error was caught, so that
+Throw() and Catch() do not allocate: this is the pool of the values
+thrown along with errors only, and valErrPools holds the pool of the
+value-error pairs of each type of value (by a nil *T).
+*/
+var (
+	errPool     = sync.Pool{New: func() any { return new(_err) }}
+	valErrPools sync.Map
+)
+
+// this returns the pool of the value-error pairs of T
+func valErrPool[T any]() *sync.Pool {
+	if pool, ok := valErrPools.Load((*T)(nil)); ok {
+		return pool.(*sync.Pool)
+	}
+	pool, _ := valErrPools.LoadOrStore((*T)(nil), &sync.Pool{New: func() any { return new(valErr[T]) }})
+	return pool.(*sync.Pool)
+}
+
+// this returns a value-error pair from its pool
+func newValErr[T any](val T, err error) *valErr[T] {
+	ve := valErrPool[T]().Get().(*valErr[T])
+	ve.val, ve.err = val, err
+	return ve
+}
+
+// this returns a thrown error from its pool
+func newErr(err error) *_err {
+	e := errPool.Get().(*_err)
+	e.err = err
+	return e
+}
+
 var ERROR_IN_CATCH = errstack.New("Catch() and CatchVal() must be called with a non-nil pointer")
 
 /*
@@ -54,23 +127,7 @@ func Catch[T any](valAddr *T, errAddr *error) {
 		panic(ERROR_IN_CATCH)
 	}
 	if panicInfo := recover(); panicInfo != nil {
-		// in the case of a Return[T any](T, error) we need this type check
-		if ve, ok := panicInfo.(valErr[T]); ok {
-			*valAddr = ve.val
-			*errAddr = ve.err
-			return
-		}
-		// in the case of a Throw(error) we need this type check
-		if err_, ok := panicInfo.(_err); ok {
-			*errAddr = err_.err
-			return
-		}
-		// if we panicked on a stacked error we need to print it out
-		if err, ok := panicInfo.(errstack.Error); ok {
-			panic(errors.New(err.PrintableError()))
-		}
-		// otherwise any other panic will panic
-		panic(panicInfo)
+		recovered(panicInfo, valAddr, errAddr)
 	}
 }
 
@@ -84,6 +141,8 @@ address of the returned value and/or returned error.
 
 In the case of a function that only returns an error, a deferred
 call to Catch_() should appear as the function's first statement.
+Catch_() used to re-panic the thrown errors instead of returning them
+(see CHANGELOG.md).
 
 example:
 
@@ -102,13 +161,44 @@ func Catch_(errAddr *error) {
 		panic(ERROR_IN_CATCH)
 	}
 	if panicInfo := recover(); panicInfo != nil {
-		if err, ok := panicInfo.(errstack.Error); ok {
-			*errAddr = errors.New(err.PrintableError())
-		}
-		panic(panicInfo)
+		recovered[any](panicInfo, nil, errAddr)
 	}
 }
 
+/*
+recovered() handles a value obtained from recover() on behalf of the
+Catch functions. valAddr may be nil when the function only returns an
+error. The recovered error goes through the middleware, the global one
+first.
+*/
+func recovered[T any](panicInfo any, valAddr *T, errAddr *error, mws ...Middleware) {
+	// in the case of a Return[T any](T, error) we need this type check
+	if ve, ok := panicInfo.(*valErr[T]); ok && valAddr != nil {
+		val, err := ve.val, ve.err
+		ve.release()
+		*valAddr = val
+		*errAddr = transform(err, mws)
+		fireCatch(*errAddr)
+		return
+	}
+	// in the case of a Throw(error), or a Return() whose value type does
+	// not match the caught one, only the error is returned
+	if t, ok := panicInfo.(thrown); ok {
+		err := t.thrownErr()
+		t.release()
+		*errAddr = transform(err, mws)
+		fireCatch(*errAddr)
+		return
+	}
+	// if we panicked on a stacked error we need to print it out
+	if err, ok := panicInfo.(errstack.Error); ok {
+		panic(errors.New(err.PrintableError()))
+	}
+	// otherwise any other panic will panic unchanged (e.g. http.ErrAbortHandler),
+	// the crash output still showing the original stack
+	panic(panicInfo)
+}
+
 /*
 WithCauseVal() returns a function that takes a format as parameter. That
 function, when called, will:
@@ -185,6 +275,72 @@ func WithCause_(err error) func(errMsg string) (e error) {
 	}
 }
 
+/*
+WithCausef() and WithCausef_() behave like WithCause() and WithCause_(),
+except that the returned function takes a format and arguments, which
+are formatted with fmt.Sprintf() to build the error message.
+
+example:
+
+	func SomeFunction(id string) (User, error) // this returns an error
+	var _, err = WithCausef(SomeFunction(id))("could not load user %s", id)
+*/
+func WithCausef[T any](val T, err error) func(format string, args ...any) (v T, e error) {
+	return func(format string, args ...any) (T, error) {
+		return WithCause(val, err)(fmt.Sprintf(format, args...))
+	}
+}
+
+/*
+WithCausef() and WithCausef_() behave like WithCause() and WithCause_(),
+except that the returned function takes a format and arguments, which
+are formatted with fmt.Sprintf() to build the error message.
+
+example:
+
+	func SomeFunction(path string) error // this returns an error
+	var err = WithCausef_(SomeFunction(path))("could not remove %s", path)
+*/
+func WithCausef_(err error) func(format string, args ...any) (e error) {
+	return func(format string, args ...any) error {
+		return WithCause_(err)(fmt.Sprintf(format, args...))
+	}
+}
+
+/*
+Annotate() decorates the error pointed to by errAddr, if it is not nil,
+with the provided message as an errstack.Error. It is meant to be
+deferred with the address of the function's returned error, giving the
+benefits of cause chaining to functions that do not use Throw() at all.
+The message is formatted with fmt.Sprintf() when args are provided.
+
+When the function also uses Catch(), Annotate() should be deferred
+first, so that it runs after Catch() has set the returned error.
+
+example:
+
+	func LoadConfig(path string) (c Config, e error) {
+		defer Annotate(&e, "loading config %s", path)
+		data, err := os.ReadFile(path)
+		if err != nil {
+			return Config{}, err
+		}
+		return parse(data)
+	}
+*/
+func Annotate(errAddr *error, msg string, args ...any) {
+	if errAddr == nil {
+		panic(ERROR_IN_CATCH)
+	}
+	if *errAddr == nil {
+		return
+	}
+	if len(args) > 0 {
+		msg = fmt.Sprintf(msg, args...)
+	}
+	*errAddr = errstack.New(msg, *errAddr)
+}
+
 /*
 Throw() needs to be paired with a deferred call to Catch().
 
@@ -202,21 +358,80 @@ example:
 		someStringVar := ReturnErr(SomeOtherFunction())(&e) //
 		return nil
 	}
+
+Throw() does not allocate: the values it throws are recycled once caught.
+When no error is thrown, it only costs the deferred Catch(), about 20ns;
+a Throw() caught by Catch() costs about 750ns (see the benchmarks of the
+package), i.e. the cost of a panic and a recover, against less than 1ns
+for a plain return of the error. It is meant for the error paths, not
+for control flow in hot loops.
 */
 func Throw[T any](val T, err error) T {
 	if err != nil {
-		panic(valErr[T]{
-			val: val,
-			err: err,
-		})
+		fireThrow(err)
+		panic(newValErr(val, err))
 	}
 	return val
 }
 
 func Throw_(err error) {
 	if err != nil {
-		panic(_err{err: err})
+		fireThrow(err)
+		panic(newErr(err))
+	}
+}
+
+/*
+ERROR_THROW_IF_NIL is the cause of the error thrown by ThrowIf() when
+the condition is true but the error is nil.
+*/
+var ERROR_THROW_IF_NIL = errstack.New("ThrowIf() must be called with a non-nil error")
+
+/*
+ThrowIf() throws the error up the call stack if the condition is true,
+and needs to be paired with a deferred call to Catch(), like Throw().
+A true condition always throws: if the error is nil, an error caused by
+ERROR_THROW_IF_NIL is thrown instead.
+
+Example:
+
+	ThrowIf(qty <= 0, errstack.NewCode("INVALID_QTY", "the quantity must be positive"))
+
+errlint:throws
+*/
+func ThrowIf(cond bool, err error) {
+	if !cond {
+		return
+	}
+	if err == nil {
+		err = errstack.New("the condition of ThrowIf() holds", ERROR_THROW_IF_NIL)
 	}
+	Throw_(err)
+}
+
+/*
+ThrowUnless() throws the error up the call stack, unless it matches one
+of the ignored errors (as per errors.Is()), such as io.EOF or
+sql.ErrNoRows, which are swallowed. It needs to be paired with a
+deferred call to Catch(), like Throw().
+
+Example:
+
+	name := "anonymous"
+	ThrowUnless(db.QueryRow(query, id).Scan(&name), sql.ErrNoRows)
+
+errlint:throws
+*/
+func ThrowUnless(err error, ignore ...error) {
+	if err == nil {
+		return
+	}
+	for _, ignored := range ignore {
+		if errors.Is(err, ignored) {
+			return
+		}
+	}
+	Throw_(err)
 }
 
 /*
@@ -238,7 +453,8 @@ Return_() Example:
 	var _ = SomeFunction() // this returns an error with "oops!" as message.
 */
 func Return_(err error) {
-	panic(_err{err: err})
+	fireThrow(err)
+	panic(newErr(err))
 }
 
 /*
@@ -260,31 +476,23 @@ Return() Example:
 	var str, _ = SomeFunction() // this returns "Hello world!" and a nil error
 */
 func Return[T any](val T, err error) {
-	if _, ok := err.(errstack.Error); ok {
-		panic(valErr[T]{
-			val: val,
-			err: err,
-		})
-	}
-	panic(valErr[T]{
-		val: val,
-		err: err,
-	})
+	fireThrow(err)
+	panic(newValErr(val, err))
 }
 
-// TODO check those two
 /*
 Must() and Must_() will panic on the provided error if not nil.
 This is useful for critical operations during application execution,
 and statements which's failure would prevent the application from
-running at all.
+running at all. Deferring HandleMain() at the top of main() prints the
+trace of the error and exits cleanly instead of crashing.
 
 Must() Example:
 
 	func someCriticalFunction() (string, error)
 
 	func main() {
-		// TODO add deferred call to finalization function here
+		defer HandleMain()
 		str := Must(SomeCriticalFunction()) // this will panic on error
 	}
 */
@@ -299,14 +507,15 @@ func Must[T any](val T, err error) T {
 Must() and Must_() will panic on the provided error if not nil.
 This is useful for critical operations during application execution,
 and statements which's failure would prevent the application from
-running at all.
+running at all. Deferring HandleMain() at the top of main() prints the
+trace of the error and exits cleanly instead of crashing.
 
 Must_() Example:
 
 	func someCriticalFunction() (error)
 
 	func main() {
-		// TODO add deferred call to finalization function here
+		defer HandleMain()
 		Must_(SomeCriticalFunction()) // this will panic on error
 	}
 */
@@ -408,3 +617,49 @@ func OnSuccess_(err error) func(f func()) {
 		f()
 	}
 }
+
+/*
+OrElse() returns the value if the error is nil, and the provided
+fallback value otherwise.
+
+OrElse() Example:
+
+	func loadPort() (int, error)
+
+	func main() {
+		port := OrElse(loadPort())(8080)
+	}
+*/
+func OrElse[T any](val T, err error) func(fallback T) T {
+	return func(fallback T) T {
+		if err != nil {
+			return fallback
+		}
+		return val
+	}
+}
+
+/*
+OrElseGet() returns the value-error pair if the error is nil, and the
+result of the provided function otherwise. Calls can be chained to try
+several alternatives in order.
+
+OrElseGet() Example:
+
+	func fromCache(key string) (string, error)
+	func fromDB(key string) (string, error)
+
+	func main() {
+		str := OrElse(OrElseGet(fromCache(key))(func() (string, error) {
+			return fromDB(key)
+		}))("default")
+	}
+*/
+func OrElseGet[T any](val T, err error) func(f func() (T, error)) (T, error) {
+	return func(f func() (T, error)) (T, error) {
+		if err != nil {
+			return f()
+		}
+		return val, err
+	}
+}
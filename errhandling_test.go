@@ -1,11 +1,26 @@
 package errhandling_test
 
 import (
+	"bytes"
+	"context"
+	"encoding/gob"
+	"encoding/json"
 	"errors"
+	"fmt"
+	"io"
+	"log/slog"
+	"net/http"
+	"os"
+	"regexp"
+	"strings"
+	"sync/atomic"
+	"syscall"
 	"testing"
+	"time"
 
 	. "github.com/onsi/ginkgo"
 	. "github.com/onsi/gomega"
+	pkgerrors "github.com/pkg/errors"
 
 	. "github.com/the-zucc/errhandling"
 	errstack "github.com/the-zucc/errhandling/err-stack"
@@ -80,4 +95,1306 @@ var _ = Describe("errhandling2 tests", func() {
 		_, ok := err.(errstack.Error)
 		Expect(ok).To(BeTrue())
 	})
+	It("CatchWithFinally() should run the finally function after a Throw()", func() {
+		ran := false
+		str, err := func() (s string, e error) {
+			defer CatchWithFinally(&s, &e, func() {
+				ran = true
+			})
+			Throw("", errors.New("oopsie"))
+			return SAMPLE_STRING, nil
+		}()
+		Expect(ran).To(BeTrue())
+		Expect(str).To(Equal(""))
+		Expect(err.Error()).To(Equal("oopsie"))
+	})
+	It("CatchWithFinally_() should run the finally function on a normal return", func() {
+		ran := false
+		err := func() (e error) {
+			defer CatchWithFinally_(&e, func() {
+				ran = true
+			})
+			return nil
+		}()
+		Expect(ran).To(BeTrue())
+		Expect(err).To(BeNil())
+	})
+	It("Catch_() should return the error passed to Throw_()", func() {
+		err := func() (e error) {
+			defer Catch_(&e)
+			Throw_(errors.New("oopsie"))
+			return nil
+		}()
+		Expect(err.Error()).To(Equal("oopsie"))
+	})
+	It("Try() should pass the thrown error to Catch() and run Finally()", func() {
+		var caught error
+		ran := false
+		err := Try(func() {
+			Throw_(errors.New("oopsie"))
+		}).Catch(func(err error) {
+			caught = err
+		}).Finally(func() {
+			ran = true
+		})
+		Expect(caught).To(Equal(err))
+		Expect(err.Error()).To(Equal("oopsie"))
+		Expect(ran).To(BeTrue())
+	})
+	It("CatchPanic() should return any other panic as an error", func() {
+		_, err := func() (i int, e error) {
+			defer CatchPanic(&i, &e)
+			var s []int
+			return s[1], nil
+		}()
+		Expect(err).NotTo(BeNil())
+		se, ok := err.(errstack.Error)
+		Expect(ok).To(BeTrue())
+		pe, ok := se.Cause.(*PanicError)
+		Expect(ok).To(BeTrue())
+		Expect(pe.Stack).NotTo(BeEmpty())
+	})
+	It("CatchPanic() errors should stay comparable", func() {
+		recovered := func(value any) (e error) {
+			defer CatchPanic_(&e)
+			panic(value)
+		}
+		first, second := recovered([]int{1}), recovered([]int{1})
+		Expect(first == first).To(BeTrue())
+		Expect(first == second).To(BeFalse())
+		Expect(errors.Is(first, second)).To(BeFalse())
+		Expect(errors.Is(first, first)).To(BeTrue())
+		var pe *PanicError
+		Expect(errors.As(second, &pe)).To(BeTrue())
+		Expect(pe.Value).To(Equal([]int{1}))
+	})
+	It("Catch() should re-panic foreign panics unchanged", func() {
+		repanicked := func(value any) (r any) {
+			defer func() {
+				r = recover()
+			}()
+			func() (e error) {
+				defer Catch_(&e)
+				panic(value)
+			}()
+			return nil
+		}
+		Expect(repanicked("boom")).To(Equal("boom"))
+		Expect(repanicked(http.ErrAbortHandler)).To(BeIdenticalTo(http.ErrAbortHandler))
+	})
+	It("Go() should surface a Throw() from the child goroutine", func() {
+		h := Go(func() {
+			Throw(0, errors.New("oopsie"))
+		})
+		err := h.Wait()
+		Expect(err).NotTo(BeNil())
+		Expect(err.Error()).To(Equal("oopsie"))
+		Expect(h.Err()).To(Equal(err))
+	})
+	It("Group.Wait() should report a Throw() and cancel the other tasks", func() {
+		g, _ := NewGroup(context.Background())
+		g.Go(func(ctx context.Context) {
+			Throw_(errors.New("oopsie"))
+		})
+		g.Go(func(ctx context.Context) {
+			<-ctx.Done()
+		})
+		err := g.Wait()
+		Expect(err).NotTo(BeNil())
+		Expect(err.Error()).To(ContainSubstring("oopsie"))
+	})
+	It("All() should return the values in order and report failures", func() {
+		vals, err := All(
+			func() int { return 1 },
+			func() int { return Throw(0, errors.New("oopsie")) },
+		)
+		Expect(vals).To(Equal([]int{1, 0}))
+		Expect(err).NotTo(BeNil())
+		Expect(err.Error()).To(ContainSubstring("oopsie"))
+	})
+	It("Any() should return the first success", func() {
+		val, err := Any(
+			func() string { return Throw("", errors.New("oopsie")) },
+			func() string { return SAMPLE_STRING },
+		)
+		Expect(err).To(BeNil())
+		Expect(val).To(Equal(SAMPLE_STRING))
+	})
+	It("Race() should return the outcome of the first task to complete", func() {
+		release := make(chan struct{})
+		defer close(release)
+		val, err := Race(
+			func() string { <-release; return "slow" },
+			func() string { return SAMPLE_STRING },
+		)
+		Expect(err).To(BeNil())
+		Expect(val).To(Equal(SAMPLE_STRING))
+		_, err = Race(
+			func() string { <-release; return "slow" },
+			func() string { return Throw("", errors.New("oopsie")) },
+		)
+		Expect(err).To(MatchError("oopsie"))
+		_, err = Race[string]()
+		Expect(err).To(Equal(ERROR_NO_TASKS))
+	})
+	It("Race() should return the first failure when all tasks fail", func() {
+		second := make(chan struct{})
+		_, err := Race(
+			func() int { return Throw(0, errors.New("first")) },
+			func() int { <-second; return Throw(0, errors.New("second")) },
+		)
+		close(second)
+		Expect(err).To(MatchError("first"))
+	})
+	It("Race() should let the losers be cancelled through their context", func() {
+		ctx, cancel := context.WithCancel(context.Background())
+		stopped := make(chan error, 1)
+		val, err := Race(
+			func() int {
+				<-ctx.Done()
+				stopped <- ctx.Err()
+				return 0
+			},
+			func() int { return 42 },
+		)
+		cancel()
+		Expect(err).To(BeNil())
+		Expect(val).To(Equal(42))
+		Eventually(stopped).Should(Receive(Equal(context.Canceled)))
+		_, err = Race(func() int {
+			ThrowIfDone(ctx)
+			return 0
+		})
+		Expect(errors.Is(err, context.Canceled)).To(BeTrue())
+	})
+	It("Pool.Wait() should report the failures of the submitted tasks", func() {
+		pool := NewPool(2, Drain)
+		for i := 0; i < 4; i++ {
+			i := i
+			Expect(pool.Submit(func() {
+				if i%2 == 0 {
+					Throw_(errors.New("oopsie"))
+				}
+			})).To(Succeed())
+		}
+		err := pool.Wait()
+		Expect(err).NotTo(BeNil())
+		Expect(err.Error()).To(ContainSubstring("2 of 4 tasks failed"))
+		Expect(pool.Errors()).To(HaveLen(2))
+		Expect(pool.Submit(func() {})).To(Equal(ERROR_POOL_CLOSED))
+	})
+	It("Pool.Wait() should not wait for the tasks blocked in Submit()", func() {
+		pool := NewPool(1, Drain)
+		release := make(chan struct{})
+		Expect(pool.Submit(func() { <-release })).To(Succeed())
+		submitted := make(chan error)
+		go func() { submitted <- pool.Submit(func() {}) }()
+		waited := make(chan error)
+		go func() { waited <- pool.Wait() }()
+		Eventually(submitted).Should(Receive(Equal(ERROR_POOL_CLOSED)))
+		close(release)
+		Eventually(waited).Should(Receive(BeNil()))
+	})
+	It("Future.AwaitTimeout() should throw a timeout caused by context.DeadlineExceeded", func() {
+		release := make(chan struct{})
+		defer close(release)
+		_, err := func() (s string, e error) {
+			defer Catch(&s, &e)
+			fut := Async(func() (string, error) {
+				<-release
+				return SAMPLE_STRING, nil
+			})
+			return fut.AwaitTimeout(time.Millisecond), nil
+		}()
+		Expect(errors.Is(err, context.DeadlineExceeded)).To(BeTrue())
+		Expect(errstack.IsTimeout(err)).To(BeTrue())
+	})
+	It("Future.Await() should throw the error of the function", func() {
+		_, err := func() (s string, e error) {
+			defer Catch(&s, &e)
+			fut := Async(func() (string, error) {
+				return "", errors.New("oopsie")
+			})
+			return fut.Await(), nil
+		}()
+		Expect(err).NotTo(BeNil())
+		Expect(err.Error()).To(Equal("oopsie"))
+	})
+	It("Scope.Catch_() should wait for the children and return the first error", func() {
+		finished := false
+		err := func() (e error) {
+			scope := NewScope(context.Background())
+			defer scope.Catch_(&e)
+			scope.Go(func(ctx context.Context) {
+				Throw_(errors.New("oopsie"))
+			})
+			scope.Go(func(ctx context.Context) {
+				<-ctx.Done()
+				finished = true
+			})
+			return nil
+		}()
+		Expect(finished).To(BeTrue())
+		Expect(err.Error()).To(Equal("oopsie"))
+	})
+	It("Supervisor should restart a failing service until it gives up", func() {
+		runs := 0
+		s := NewSupervisor(context.Background())
+		s.Add("flaky", RestartPolicy{Mode: OnError, MaxRetries: 2}, func(ctx context.Context) {
+			runs++
+			Throw_(errors.New("oopsie"))
+		})
+		err := s.Wait()
+		Expect(runs).To(Equal(3))
+		Expect(err).NotTo(BeNil())
+		Expect(err.Error()).To(ContainSubstring("gave up after 2 restarts"))
+	})
+	It("Supervisor should back unlimited restarts off, and report the last failure when stopped", func() {
+		defer func(backoff time.Duration) { DefaultRestartBackoff = backoff }(DefaultRestartBackoff)
+		DefaultRestartBackoff = 20 * time.Millisecond
+		runs := atomic.Int32{}
+		s := NewSupervisor(context.Background())
+		s.Add("flaky", RestartPolicy{Mode: Always}, func(ctx context.Context) {
+			runs.Add(1)
+			Throw_(errors.New("oopsie"))
+		})
+		time.Sleep(50 * time.Millisecond)
+		s.Stop()
+		err := s.Wait()
+		Expect(runs.Load()).To(BeNumerically("<=", 4))
+		Expect(err).To(MatchError(ContainSubstring("oopsie")))
+		Expect(errors.Is(err, context.Canceled)).To(BeTrue())
+	})
+	It("Retry() should record every failed attempt in the error chain", func() {
+		calls := 0
+		_, err := Retry(context.Background(), RetryPolicy{MaxAttempts: 3}, func() (int, error) {
+			calls++
+			return 0, errors.New("oopsie")
+		})
+		Expect(calls).To(Equal(3))
+		se, ok := err.(errstack.Error)
+		Expect(ok).To(BeTrue())
+		Expect(se.PrintableError()).To(ContainSubstring("attempt 2 failed: oopsie"))
+	})
+	It("Retry() should stop on errors that are not retryable", func() {
+		calls := 0
+		policy := RetryPolicy{MaxAttempts: 3, RetryIf: errstack.IsRetryable}
+		err := Retry_(context.Background(), policy, func() error {
+			calls++
+			if calls == 1 {
+				return errstack.New("oopsie").Retryable()
+			}
+			return errstack.New("oopsie").TimedOut()
+		})
+		Expect(calls).To(Equal(2))
+		Expect(errstack.IsRetryable(err)).To(BeTrue())
+		Expect(errstack.IsTimeout(err)).To(BeTrue())
+	})
+	It("Breaker should reject calls with ErrCircuitOpen once open", func() {
+		b := NewBreaker(BreakerConfig{Window: 2})
+		call := func() (e error) {
+			defer Catch_(&e)
+			Execute_(b, func() {
+				Throw_(errors.New("oopsie"))
+			})
+			return nil
+		}
+		Expect(call().Error()).To(Equal("oopsie"))
+		Expect(call().Error()).To(Equal("oopsie"))
+		Expect(b.State()).To(Equal(Open))
+		err := call()
+		var open ErrCircuitOpen
+		Expect(errors.As(err, &open)).To(BeTrue())
+		Expect(open.LastCause.Error()).To(Equal("oopsie"))
+	})
+	It("Breaker should report its state changes once unlocked", func() {
+		var b *Breaker
+		changes := []string{}
+		b = NewBreaker(BreakerConfig{Window: 1, Cooldown: time.Millisecond, OnStateChange: func(from, to BreakerState) {
+			changes = append(changes, fmt.Sprintf("%s -> %s (%s)", from, to, b.State()))
+		}})
+		_ = Try(func() { Execute_(b, func() { Throw_(errors.New("oopsie")) }) }).Err()
+		time.Sleep(2 * time.Millisecond)
+		Expect(Try(func() { Execute_(b, func() {}) }).Err()).To(BeNil())
+		Expect(changes).To(Equal([]string{"closed -> open (open)", "open -> half-open (half-open)", "half-open -> closed (closed)"}))
+	})
+	It("OrElse() and OrElseGet() should fall back on error", func() {
+		fail := func() (string, error) { return "", errors.New("oopsie") }
+		Expect(OrElse(fail())("default")).To(Equal("default"))
+		str, err := OrElseGet(fail())(func() (string, error) {
+			return SAMPLE_STRING, nil
+		})
+		Expect(err).To(BeNil())
+		Expect(str).To(Equal(SAMPLE_STRING))
+	})
+	It("WithTimeout() should throw a timeout when the deadline expires", func() {
+		err := func() (e error) {
+			defer Catch_(&e)
+			WithTimeout_(context.Background(), time.Millisecond, func(ctx context.Context) error {
+				<-ctx.Done()
+				time.Sleep(10 * time.Millisecond)
+				return nil
+			})
+			return nil
+		}()
+		Expect(errstack.IsTimeout(err)).To(BeTrue())
+		Expect(errors.Is(err.(errstack.Error).Cause, context.DeadlineExceeded)).To(BeTrue())
+	})
+	It("WithTimeout() should throw the cancellation of the parent context", func() {
+		parent, cancel := context.WithCancelCause(context.Background())
+		shutdown := errors.New("shutting down")
+		err := func() (e error) {
+			defer Catch_(&e)
+			WithTimeout_(parent, time.Hour, func(ctx context.Context) error {
+				cancel(shutdown)
+				<-ctx.Done()
+				time.Sleep(10 * time.Millisecond)
+				return nil
+			})
+			return nil
+		}()
+		Expect(errstack.IsTimeout(err)).To(BeFalse())
+		Expect(err.Error()).NotTo(ContainSubstring("timed out"))
+		Expect(errors.Is(err, context.Canceled)).To(BeTrue())
+		Expect(errors.Is(err, shutdown)).To(BeTrue())
+	})
+	It("ThrowIfDone() should throw the error of a cancelled context", func() {
+		ctx, cancel := context.WithCancel(context.Background())
+		cancel()
+		err := func() (e error) {
+			defer Catch_(&e)
+			ThrowIfDone(ctx)
+			return nil
+		}()
+		Expect(errors.Is(err.(errstack.Error).Cause, context.Canceled)).To(BeTrue())
+	})
+	It("CollectInto() should accumulate errors in the context's Collector", func() {
+		ctx, errs := WithCollector(context.Background())
+		Expect(CollectInto(ctx, errors.New("oopsie"))).To(BeTrue())
+		Expect(CollectInto(ctx, nil)).To(BeFalse())
+		Expect(errs.Errors()).To(HaveLen(1))
+	})
+	It("errstack.Join() should keep every cause as a branch", func() {
+		err := errstack.Join(
+			errstack.New("read a", errors.New("ENOENT")),
+			nil,
+			context.Canceled,
+		)
+		Expect(errors.Is(err, context.Canceled)).To(BeTrue())
+		Expect(err.Error()).To(Equal("[ENOENT -> read a; context canceled] -> 2 errors occurred"))
+		trace := err.(errstack.Error).PrintableError()
+		Expect(trace).To(ContainSubstring("\tcaused by [1 of 2]:\n\t\tread a\n\t\tcaused by: ENOENT"))
+		Expect(errstack.Join(nil, nil)).To(BeNil())
+	})
+	It("Collector.Flush() should join the collected errors into the returned error", func() {
+		err := func() (e error) {
+			var errs Collector
+			defer errs.Flush(&e)
+			defer Catch_(&e)
+			errs.Collect(errors.New("first"))
+			errs.Collect(nil)
+			errs.Collect(errors.New("second"))
+			Throw_(errors.New("thrown"))
+			return nil
+		}()
+		Expect(err.Error()).To(Equal("[thrown; first; second] -> 3 errors occurred"))
+	})
+	It("Annotate() should decorate the returned error", func() {
+		err := func() (e error) {
+			defer Annotate(&e, "loading config %s", "app.yaml")
+			return errors.New(ROOT_ERROR)
+		}()
+		Expect(err.Error()).To(Equal(ROOT_ERROR + " -> loading config app.yaml"))
+		Expect(func() (e error) {
+			defer Annotate(&e, "loading config")
+			return nil
+		}()).To(BeNil())
+	})
+	It("WithCausef() should format the error message", func() {
+		_, err := WithCausef(0, errors.New(ROOT_ERROR))("could not load user %d", 42)
+		Expect(err.Error()).To(Equal(ROOT_ERROR + " -> could not load user 42"))
+		Expect(WithCausef_(nil)("could not remove %s", "file")).To(BeNil())
+	})
+	It("errstack fields should survive wrapping", func() {
+		err := errstack.New("lookup failed", errstack.New("user not found").With("user_id", 42)).
+			With("attempt", 1)
+		id, ok := errstack.Field(err, "user_id")
+		Expect(ok).To(BeTrue())
+		Expect(id).To(Equal(42))
+		_, ok = errstack.Field(err, "missing")
+		Expect(ok).To(BeFalse())
+		Expect(errstack.Fields(err)).To(HaveLen(2))
+	})
+	It("errstack.Code() should find the nearest code of the chain", func() {
+		code := errstack.RegisterCode("TEST_USER_NOT_FOUND")
+		Expect(func() { errstack.RegisterCode(code) }).To(Panic())
+		err := errstack.NewCode("TEST_API", "request failed", errstack.NewCode(code, "user not found"))
+		Expect(errstack.Code(err)).To(Equal("TEST_API"))
+		Expect(errstack.RootCode(err)).To(Equal(code))
+		Expect(errstack.Code(errors.New(ROOT_ERROR))).To(Equal(""))
+	})
+	It("errstack.Severity() should escalate through wrapping", func() {
+		inner := errstack.New("disk full").WithSeverity(errstack.SeverityFatal)
+		err := errstack.New("write failed", inner).WithSeverity(errstack.SeverityWarn)
+		Expect(errstack.Severity(err)).To(Equal(errstack.SeverityFatal))
+		Expect(errstack.Severity(errors.New(ROOT_ERROR))).To(Equal(errstack.SeverityError))
+	})
+	It("errstack.PublicMessage() should find the public message of the chain", func() {
+		inner := errstack.New("INSERT failed").WithPublicMessage("Could not save your order.")
+		err := errstack.New("checkout failed", inner)
+		Expect(errstack.PublicMessage(err)).To(Equal("Could not save your order."))
+		Expect(err.Error()).NotTo(ContainSubstring("Could not save"))
+	})
+	It("errstack.Localize() should translate keyed messages", func() {
+		Expect(errstack.RegisterBundle("fr", map[string]string{
+			"order.exists":        "la commande {{.id}} existe déjà",
+			"order.exists.public": "Cette commande a déjà été passée.",
+		})).To(Succeed())
+		err := errstack.New("checkout failed", errstack.New("order already exists").
+			WithKey("order.exists", map[string]any{"id": 42}))
+		localized := errstack.Localize(err, "fr-CA")
+		Expect(localized.Error()).To(Equal("la commande 42 existe déjà -> checkout failed"))
+		Expect(errstack.PublicMessage(localized)).To(Equal("Cette commande a déjà été passée."))
+		Expect(localized.(errstack.Error).PrintableError()).To(ContainSubstring("Root cause:\n\tla commande 42"))
+		Expect(err.Error()).To(Equal("order already exists -> checkout failed"))
+	})
+	It("errstack.Newf() should map %w to the cause", func() {
+		err := errstack.Newf("could not open %s: %w", "app.yaml", context.Canceled)
+		Expect(errors.Is(err, context.Canceled)).To(BeTrue())
+		Expect(err.Msg()).To(Equal("could not open app.yaml"))
+		Expect(err.Error()).To(Equal("context canceled -> could not open app.yaml"))
+		err = errstack.Newf("100%% of %d attempts failed: %w, %w", 3, io.EOF, context.Canceled)
+		Expect(err.Msg()).To(Equal("100% of 3 attempts failed"))
+		Expect(errors.Is(err, io.EOF)).To(BeTrue())
+		err = errstack.NewfCause(context.Canceled, "could not open %s", "app.yaml")
+		Expect(err.Error()).To(Equal("context canceled -> could not open app.yaml"))
+	})
+	It("errstack.Error should format %s, %v and %+v with increasing detail", func() {
+		err := errstack.New("open config", errstack.New("read file", errors.New("ENOENT")))
+		Expect(fmt.Sprintf("%s", err)).To(Equal("open config: read file: ENOENT"))
+		Expect(fmt.Sprintf("%v", err)).To(Equal("ENOENT -> read file -> open config"))
+		detailed := fmt.Sprintf("%+v", err)
+		Expect(detailed).To(HavePrefix(err.PrintableError()))
+		if errstack.CaptureStacks {
+			Expect(detailed).To(ContainSubstring("errhandling_test.go"))
+		}
+	})
+	It("errstack.Error should round-trip through JSON", func() {
+		err := errstack.NewCode("CONFIG", "open config", errstack.New("read file", errors.New("ENOENT"))).
+			With("path", "app.yaml")
+		data, jsonErr := json.Marshal(err)
+		Expect(jsonErr).To(BeNil())
+		Expect(string(data)).To(ContainSubstring(`"cause":{"message":"ENOENT","external":true}`))
+		var decoded errstack.Error
+		Expect(json.Unmarshal(data, &decoded)).To(Succeed())
+		Expect(decoded.Error()).To(Equal(err.Error()))
+		Expect(decoded.PrintableError()).To(Equal(err.PrintableError()))
+		Expect(errstack.Code(decoded)).To(Equal("CONFIG"))
+		path, _ := errstack.Field(decoded, "path")
+		Expect(path).To(Equal("app.yaml"))
+		Expect(decoded.StackFrames()).To(Equal(err.StackFrames()))
+	})
+	It("errstack.SlogHandler should expand wrapped stacked errors", func() {
+		var buf bytes.Buffer
+		logger := slog.New(errstack.NewSlogHandler(slog.NewJSONHandler(&buf, nil)))
+		inner := errstack.NewCode("CONFIG", "read file", errors.New("ENOENT"))
+		logger.Error("request failed", "err", fmt.Errorf("serving: %w", inner))
+		Expect(buf.String()).To(ContainSubstring(`"code":"CONFIG"`))
+		Expect(buf.String()).To(ContainSubstring(`"trace":["read file","ENOENT"]`))
+		Expect(buf.String()).To(ContainSubstring(`"error":"serving: ENOENT -> read file"`))
+	})
+	It("OnThrow() and OnCatch() hooks should receive the error and its caller", func() {
+		var thrown, caught Event
+		removeThrow := OnThrow(func(ev Event) { thrown = ev })
+		removeCatch := OnCatch(func(ev Event) { caught = ev })
+		_ = func() (e error) {
+			defer Catch_(&e)
+			Throw_(errors.New("oopsie"))
+			return nil
+		}()
+		removeThrow()
+		removeCatch()
+		Expect(thrown.Err.Error()).To(Equal("oopsie"))
+		if errstack.CaptureStacks {
+			Expect(thrown.Caller.File).To(HaveSuffix("errhandling_test.go"))
+		}
+		Expect(caught.Err).To(Equal(thrown.Err))
+		_ = func() (e error) {
+			defer Catch_(&e)
+			Throw_(errors.New("ignored"))
+			return nil
+		}()
+		Expect(thrown.Err.Error()).To(Equal("oopsie"))
+	})
+	It("CatchWith() should apply the global then the per-Catch middleware", func() {
+		remove := Use(func(err error) error {
+			return errstack.New("global", err)
+		})
+		defer remove()
+		_, err := func() (s string, e error) {
+			defer CatchWith(&s, &e, func(err error) error {
+				return errstack.New("local", err)
+			})
+			Throw_(errors.New("oopsie"))
+			return "", nil
+		}()
+		Expect(err.Error()).To(Equal("oopsie -> global -> local"))
+	})
+
+	It("ExitCode() should map codes, sentinels and types to exit codes", func() {
+		errUsage := errors.New("bad usage")
+		MapExitCode("CONFIG_UNREADABLE", 78)
+		MapExitError(errUsage, 64)
+		MapExitType[*json.SyntaxError](65)
+		Expect(ExitCode(nil)).To(Equal(0))
+		Expect(ExitCode(errstack.New("load config", errstack.NewCode("CONFIG_UNREADABLE", "open app.yaml")))).To(Equal(78))
+		Expect(ExitCode(errstack.New("parse flags", errUsage))).To(Equal(64))
+		Expect(ExitCode(errstack.New("parse input", json.Unmarshal([]byte("{"), &struct{}{})))).To(Equal(65))
+		Expect(ExitCode(errors.New(SAMPLE_STRING))).To(Equal(1))
+	})
+
+	It("Shutdown() should run the hooks in reverse order and join their failures", func() {
+		order := []string{}
+		OnShutdown(func() error {
+			order = append(order, "db")
+			return errors.New("db close failed")
+		})
+		OnShutdown(func() error {
+			order = append(order, "cache")
+			Throw_(errors.New("cache flush failed"))
+			return nil
+		})
+		var reported error
+		remove := OnReport(func(ev Event) { reported = ev.Err })
+		defer remove()
+		err := Shutdown()
+		Expect(reported).To(Equal(err))
+		Expect(order).To(Equal([]string{"cache", "db"}))
+		Expect(err.Error()).To(Equal("[cache flush failed; db close failed] -> 2 errors occurred"))
+		Expect(Shutdown()).To(BeNil())
+	})
+
+	It("NotifySignals() should cancel the context with the signal as cause", func() {
+		ctx, stop := NotifySignals(context.Background(), syscall.SIGUSR1)
+		defer stop()
+		Expect(syscall.Kill(os.Getpid(), syscall.SIGUSR1)).To(Succeed())
+		err := Retry_(ctx, RetryPolicy{MaxAttempts: 100, Backoff: ConstantBackoff(time.Second)}, func() error {
+			return errors.New("connection refused")
+		})
+		var sig SignalError
+		Expect(errors.As(err, &sig)).To(BeTrue())
+		Expect(sig.Signal).To(Equal(syscall.SIGUSR1))
+		err = func() (e error) {
+			defer Catch_(&e)
+			ThrowIfDone(ctx)
+			return nil
+		}()
+		Expect(errors.As(err, &sig)).To(BeTrue())
+	})
+
+	It("CloseAppend() and CloseThrow() should not swallow close errors", func() {
+		failing := closerFunc(func() error { return errors.New("disk full") })
+		err := func() (e error) {
+			defer CloseAppend(&e, failing)
+			return nil
+		}()
+		Expect(err.Error()).To(Equal("disk full -> close failed"))
+		err = func() (e error) {
+			defer Catch_(&e)
+			defer CloseThrow(failing)
+			Throw_(errors.New(SAMPLE_STRING))
+			return nil
+		}()
+		Expect(err.Error()).To(Equal("[" + SAMPLE_STRING + "; disk full -> close failed] -> 2 errors occurred"))
+	})
+	It("CaptureThrow() should return the thrown value and error", func() {
+		val, err := CaptureThrow(func() {
+			Return(SAMPLE_STRING, errors.New(ROOT_ERROR))
+		})
+		Expect(val).To(Equal(SAMPLE_STRING))
+		Expect(err.Error()).To(Equal(ROOT_ERROR))
+		val, err = CaptureThrow(func() {
+			Throw_(errors.New(ROOT_ERROR))
+		})
+		Expect(val).To(BeNil())
+		Expect(err.Error()).To(Equal(ROOT_ERROR))
+		val, err = CaptureThrow(func() {})
+		Expect(val).To(BeNil())
+		Expect(err).To(BeNil())
+	})
+	It("PrintableError() should not panic on the zero errstack.Error", func() {
+		Expect(errstack.Error{}.PrintableError()).To(ContainSubstring("Root cause:"))
+		wrapped := errstack.New("wrapped", errstack.Error{})
+		Expect(wrapped.PrintableError()).To(ContainSubstring("Full error trace:"))
+	})
+	It("errstack.Error should hold its causes by value", func() {
+		root := errors.New(ROOT_ERROR)
+		err := errstack.New("outer", errstack.New("inner", root))
+		Expect(err.RootCause()).To(Equal(root))
+		Expect(err.PrintableError()).To(ContainSubstring("Root cause:\n\t" + ROOT_ERROR))
+		derived := err.With("key", "value")
+		Expect(err.Fields()).To(BeEmpty())
+		Expect(errors.Is(fmt.Errorf("wrapped: %w", derived), err)).To(BeTrue())
+		Expect(errors.Is(derived, errstack.New("outer"))).To(BeFalse())
+		Expect(errors.Is(errstack.New("submit", ERROR_POOL_CLOSED.With("task", 1)), ERROR_POOL_CLOSED)).To(BeTrue())
+	})
+	It("PrintableError() should truncate the traces deeper than errstack.MaxTraceDepth", func() {
+		err := errstack.New("root")
+		for i := 1; i < 10000; i++ {
+			err = errstack.New(fmt.Sprintf("level %d", i), err)
+		}
+		trace := err.PrintableError()
+		Expect(strings.Count(trace, "caused by: ")).To(Equal(errstack.MaxTraceDepth - 1))
+		Expect(trace).To(HaveSuffix(fmt.Sprintf("\t… %d more causes", 10000-errstack.MaxTraceDepth)))
+		defer func(depth int) { errstack.MaxTraceDepth = depth }(errstack.MaxTraceDepth)
+		errstack.MaxTraceDepth = 1
+		Expect(errstack.New("outer", errors.New(ROOT_ERROR)).PrintableError()).To(HaveSuffix("\touter\n\t… 1 more cause"))
+	})
+	It("errstack.Chain() should list the errors of the chain down to the root", func() {
+		root := errors.New(ROOT_ERROR)
+		inner := errstack.New("inner", fmt.Errorf("wrapped: %w", root))
+		err := fmt.Errorf("outer: %w", inner)
+		chain := errstack.Chain(err)
+		Expect(chain).To(HaveLen(4))
+		Expect(chain[0]).To(Equal(err))
+		Expect(chain[1].(errstack.Error).Msg()).To(Equal("inner"))
+		Expect(errstack.Root(err)).To(Equal(root))
+		Expect(errstack.Root(nil)).To(BeNil())
+		Expect(errstack.Frames(err)).To(Equal(inner.StackFrames()))
+		Expect(errstack.Frames(root)).To(BeNil())
+		joined := errstack.Join(root, inner)
+		Expect(errstack.Chain(joined)).To(Equal([]error{joined}))
+	})
+	It("errstack.FindCause() should return the first cause of the type", func() {
+		timeout := &os.PathError{Op: "open", Path: "config.yaml", Err: os.ErrDeadlineExceeded}
+		err := errstack.New("load", errstack.Join(errors.New(ROOT_ERROR), fmt.Errorf("read: %w", timeout)))
+		found, ok := errstack.FindCause[*os.PathError](err)
+		Expect(ok).To(BeTrue())
+		Expect(found).To(Equal(timeout))
+		se, ok := errstack.FindCause[errstack.Error](err)
+		Expect(ok).To(BeTrue())
+		Expect(se.Msg()).To(Equal("load"))
+		_, ok = errstack.FindCause[interface{ Timeout() bool }](errors.New(ROOT_ERROR))
+		Expect(ok).To(BeFalse())
+	})
+	It("errstack.TraceFormat should configure the rendering of the trace", func() {
+		err := errstack.New("checkout", errstack.New("charge card", errors.New("card declined")))
+		format := errstack.TraceFormat{Indent: "  ", CausedBy: "wrapped by", RootFirst: true, OmitRootCause: true, OmitID: true}
+		Expect(format.Render(err)).To(Equal("error:\n  checkout\n\nFull error trace:\n  card declined\n  wrapped by: charge card\n  wrapped by: checkout"))
+		joined := errstack.New("sync", errstack.Join(errors.New("disk full"), errors.New("timeout")))
+		Expect(format.Render(joined)).To(Equal("error:\n  sync\n\nFull error trace:\n  [1 of 2]:\n    disk full\n  [2 of 2]:\n    timeout\n  wrapped by: 2 errors occurred\n  wrapped by: sync"))
+		narrow := errstack.TraceFormat{MaxWidth: 16, OmitRootCause: true, OmitID: true}
+		Expect(narrow.Render(errstack.New("could not load the configuration"))).
+			To(Equal("error:\n\tcould not load\n\t\tthe\n\t\tconfiguration\n\nFull error trace:\n\tcould not load\n\t\tthe\n\t\tconfiguration"))
+		Expect(errstack.TraceFormat{}.Render(err)).To(Equal(err.PrintableError()))
+	})
+	It("errstack.Fprint() should print the colored trace to terminals only", func() {
+		err := errstack.New("checkout", errors.New("card declined"))
+		buf := &bytes.Buffer{}
+		Expect(errstack.Fprint(buf, err)).To(Succeed())
+		Expect(buf.String()).To(Equal(fmt.Sprintf("%+v\n", err)))
+		Expect(errstack.Colored(buf)).To(BeFalse())
+		colored := errstack.TraceFormat{Color: true}.Render(err)
+		Expect(colored).To(HavePrefix("error:\n\t\x1b[31mcheckout\x1b[0m\n\nRoot cause:\n\t\x1b[33mcard declined\x1b[0m\n\n"))
+	})
+	It("errstack.TraceFormat should render the trace as a tree", func() {
+		err := errstack.New("sync", errstack.Join(errstack.New("disk full", errors.New("ENOSPC")), errors.New("timeout")))
+		tree := errstack.TraceFormat{Tree: true, OmitRootCause: true, OmitID: true}
+		Expect(tree.Render(err)).To(Equal("error:\n\tsync\n\nFull error trace:\n" +
+			"\tsync\n" +
+			"\t└─ 2 errors occurred\n" +
+			"\t   ├─ disk full\n" +
+			"\t   │  └─ ENOSPC\n" +
+			"\t   └─ timeout"))
+		tree.ASCII = true
+		Expect(tree.Render(err)).To(HaveSuffix("\t   |- disk full\n\t   |  `- ENOSPC\n\t   `- timeout"))
+	})
+	It("errstack.RenderMarkdown() and RenderHTML() should render collapsible traces", func() {
+		if !errstack.CaptureStacks {
+			Skip("the errors capture no call stack")
+		}
+		err := errstack.New("charge <card>", errors.New("card_declined")).WithID("req-42")
+		defer func() { errstack.SourceURL, errstack.SourceRoot = "", "" }()
+		frame := err.StackFrames()[0]
+		errstack.SourceURL = "https://example.com/{file}#L{line}"
+		errstack.SourceRoot = frame.File[:strings.LastIndex(frame.File, "/")+1]
+		markdown := errstack.RenderMarkdown(err)
+		Expect(markdown).To(HavePrefix("**charge &lt;card>**\n\nRoot cause: `card_declined`  \nError ID: `req-42`  \n\n<details>\n<summary>Full error trace</summary>\n\n```\n\tcharge <card>\n\tcaused by: card_declined\n```"))
+		Expect(markdown).To(ContainSubstring(fmt.Sprintf("(https://example.com/errhandling_test.go#L%d)", frame.Line)))
+		page := errstack.RenderHTML(err)
+		Expect(page).To(HavePrefix("<div class=\"errstack\">\n<p><strong>charge &lt;card&gt;</strong></p>\n<p>Root cause: <code>card_declined</code></p>\n"))
+		Expect(page).To(ContainSubstring(fmt.Sprintf("<a href=\"https://example.com/errhandling_test.go#L%d\">", frame.Line)))
+	})
+	It("errstack.Error.WriteTo() should stream the trace", func() {
+		err := errstack.New("sync", errstack.Join(errors.New("disk full"), errstack.New("timeout", errors.New(ROOT_ERROR))))
+		buf := &bytes.Buffer{}
+		n, werr := err.WriteTo(buf)
+		Expect(werr).To(BeNil())
+		Expect(n).To(Equal(int64(buf.Len())))
+		Expect(buf.String()).To(Equal(err.PrintableError()))
+		_, werr = errstack.TraceFormat{Tree: true}.WriteTrace(failingWriter{}, err)
+		Expect(werr).To(MatchError("write failed"))
+	})
+	It("errstack.RegisterTemplate() should let templates render the traces", func() {
+		Expect(errstack.RegisterTemplate("oneline",
+			`{{.Code}} {{.Message}} (root: {{.Root}}; chain: {{join .Chain " < "}}){{range $k, $v := .Fields}} {{$k}}={{$v}}{{end}}`)).To(Succeed())
+		Expect(errstack.RegisterTemplate("broken", "{{.Message")).NotTo(Succeed())
+		err := errstack.NewCode("CONFIG_INVALID", "load config", errstack.New("parse", errors.New(ROOT_ERROR)).With("line", 12))
+		format := errstack.TraceFormat{Template: "oneline"}
+		Expect(format.Render(err)).To(Equal("CONFIG_INVALID load config (root: " + ROOT_ERROR + "; chain: load config < parse < " + ROOT_ERROR + ") line=12"))
+		_, werr := errstack.TraceFormat{Template: "missing"}.WriteTrace(&bytes.Buffer{}, err)
+		Expect(werr).NotTo(BeNil())
+		Expect(errstack.TraceFormat{Template: "missing"}.Render(err)).To(Equal(err.PrintableError()))
+	})
+	It("errstack.RedactPattern() and errstack.RedactField() should mask sensitive data", func() {
+		defer errstack.RedactPattern(regexp.MustCompile(`tok_[0-9a-f]+`))()
+		unregister := errstack.RedactField("Password")
+		err := errstack.New("charge with tok_c0ffee failed", errors.New("gateway rejected tok_c0ffee")).
+			With("password", "hunter2").With("api_key", errstack.Secret("s3cr3t")).With("user", "tok_beef").
+			With("card", []string{"tok_feed"}).With("attempts", 3)
+		Expect(err.Error()).To(Equal("gateway rejected [REDACTED] -> charge with [REDACTED] failed"))
+		Expect(err.PrintableError()).NotTo(ContainSubstring("tok_"))
+		Expect(fmt.Sprintf("%s", err)).NotTo(ContainSubstring("tok_"))
+		Expect(err.Fields()).To(Equal([]errstack.KeyValue{
+			{Key: "password", Value: errstack.Redacted},
+			{Key: "api_key", Value: errstack.Redacted},
+			{Key: "user", Value: errstack.Redacted},
+			{Key: "card", Value: "[" + errstack.Redacted + "]"},
+			{Key: "attempts", Value: 3},
+		}))
+		value, _ := errstack.Field(err, "api_key")
+		Expect(value).To(Equal(errstack.Redacted))
+		data, jsonErr := json.Marshal(err)
+		Expect(jsonErr).To(BeNil())
+		Expect(string(data)).NotTo(ContainSubstring("tok_"))
+		Expect(string(data)).NotTo(ContainSubstring("hunter2"))
+		Expect(string(data)).NotTo(ContainSubstring("s3cr3t"))
+		Expect(fmt.Sprint(errstack.Secret("s3cr3t"))).To(Equal(errstack.Redacted))
+		Expect(string(errstack.Secret("s3cr3t"))).To(Equal("s3cr3t"))
+		unregister()
+		password, _ := errstack.Field(err, "password")
+		Expect(password).To(Equal("hunter2"))
+	})
+	It("errstack.ID() should return the correlation ID of the outermost stacked error", func() {
+		root := errstack.Identify(errstack.New("charge card", errors.New("card declined"))).(errstack.Error)
+		Expect(root.ID()).To(MatchRegexp("^[0-9a-f]{16}$"))
+		Expect(errstack.Identify(root)).To(Equal(root))
+		Expect(errstack.New("charge card").ID()).To(Equal(""))
+		outside := errors.New(ROOT_ERROR)
+		Expect(errstack.ID(errstack.Identify(outside))).To(MatchRegexp("^[0-9a-f]{16}$"))
+		Expect(errstack.Identify(outside)).To(MatchError(outside))
+		Expect(errstack.Identify(nil)).To(BeNil())
+		sentinel := errstack.New("not found")
+		first, second := errstack.Identify(sentinel), errstack.Identify(sentinel)
+		Expect(errstack.ID(first)).NotTo(Equal(errstack.ID(second)))
+		Expect(errors.Is(first, sentinel)).To(BeTrue())
+		Expect(sentinel.ID()).To(Equal(""))
+		err := fmt.Errorf("request failed: %w", errstack.New("checkout", root).WithID("req-42"))
+		Expect(errstack.ID(err)).To(Equal("req-42"))
+		Expect(errstack.ID(errors.New(ROOT_ERROR))).To(Equal(""))
+		Expect(errstack.New("checkout", root).PrintableError()).To(ContainSubstring("\n\nError ID:\n\t"))
+		Expect(errstack.TraceFormat{OmitID: true}.Render(root)).NotTo(ContainSubstring("Error ID:"))
+		Expect(errstack.New("charge card").PrintableError()).NotTo(ContainSubstring("Error ID:"))
+		data, jsonErr := json.Marshal(errstack.New("checkout").WithID("req-42"))
+		Expect(jsonErr).To(BeNil())
+		decoded, decodeErr := errstack.Decode(data)
+		Expect(decodeErr).To(BeNil())
+		Expect(errstack.ID(decoded)).To(Equal("req-42"))
+	})
+	It("errstack.Fingerprint() should identify the failure rather than the occurrence", func() {
+		load := func(id string) error {
+			return errstack.NewCode("ORDER_MISSING", "load order", errors.New("no order "+id))
+		}
+		first, second := load("42"), load("9f86d081-884c-7d65-9a2f-0e1c3b5d7a91")
+		Expect(errstack.Fingerprint(first)).To(MatchRegexp("^[0-9a-f]{16}$"))
+		Expect(errstack.Fingerprint(first)).To(Equal(errstack.Fingerprint(second)))
+		Expect(errstack.Fingerprint(fmt.Errorf("request: %w", first))).To(Equal(errstack.Fingerprint(first)))
+		Expect(errstack.Fingerprint(errstack.NewCode("ORDER_LOCKED", "load order", errors.New("no order 42")))).
+			NotTo(Equal(errstack.Fingerprint(first)))
+		Expect(errstack.Fingerprint(nil)).To(Equal(""))
+	})
+	It("errstack.Summary() should render a bounded one-liner", func() {
+		err := fmt.Errorf("request failed: %w", errstack.New("open config", errstack.New("read file", errors.New("ENOENT"))))
+		Expect(errstack.Summary(err)).To(Equal("request failed -> open config -> read file -> ENOENT"))
+		defer func(length int) { errstack.MaxSummaryLength = length }(errstack.MaxSummaryLength)
+		errstack.MaxSummaryLength = 41
+		Expect(errstack.Summary(err)).To(Equal("request failed -> open config (+2 causes)"))
+		joined := errstack.New("sync", errstack.Join(errors.New("disk full"), errors.New("timeout")))
+		Expect(errstack.Summary(joined)).To(Equal("sync -> 2 errors occurred (+2 causes)"))
+		errstack.MaxSummaryLength = 10
+		Expect(errstack.Summary(errstack.New("could not load the configuration"))).To(Equal("could not…"))
+		Expect(errstack.Summary(nil)).To(Equal(""))
+	})
+	It("errstack.Error's builder methods should set the cause, code, fields and severity", func() {
+		cause := errors.New(ROOT_ERROR)
+		err := errstack.New("load user").
+			WithCause(cause).
+			WithCode("USER_MISSING").
+			WithField("user_id", 42).
+			WithSeverity(errstack.SeverityWarn)
+		Expect(err.Cause).To(Equal(cause))
+		Expect(err.Error()).To(Equal(ROOT_ERROR + " -> load user"))
+		Expect(err.Code()).To(Equal("USER_MISSING"))
+		userID, _ := errstack.Field(err, "user_id")
+		Expect(userID).To(Equal(42))
+		Expect(errstack.Severity(err)).To(Equal(errstack.SeverityWarn))
+		Expect(err.WithCause(nil).Cause).To(Equal(cause))
+		branched := err.WithCause(errors.New("timeout"))
+		Expect(branched.Unwrap()).To(HaveLen(2))
+		Expect(err.Unwrap()).To(HaveLen(1))
+	})
+	It("errstack.Error should round-trip through its text encoding", func() {
+		err := errstack.NewCode("ORDER_MISSING", "load\norder", errstack.Join(errors.New("no rows"), errstack.New("timeout").Retryable())).
+			With("order id", 42).
+			WithSeverity(errstack.SeverityWarn).
+			WithPublicMessage(`This order does not "exist".`).
+			WithID("req-42")
+		text, textErr := err.MarshalText()
+		Expect(textErr).To(BeNil())
+		Expect(string(text)).To(HavePrefix(`"load\norder" code="ORDER_MISSING" id="req-42" public="This order does not \"exist\"." severity=warn "order id"="42"` + "\n"))
+		decoded := errstack.Error{}
+		Expect(decoded.UnmarshalText(text)).To(Succeed())
+		Expect(decoded.Error()).To(Equal(err.Error()))
+		Expect(decoded.PrintableError()).To(Equal(err.PrintableError()))
+		Expect(errstack.Code(decoded)).To(Equal("ORDER_MISSING"))
+		Expect(errstack.IsRetryable(decoded)).To(BeTrue())
+		Expect(decoded.StackFrames()).To(BeEmpty())
+		withFrames, parseErr := errstack.ParseText(errstack.EncodeText(err, true))
+		Expect(parseErr).To(BeNil())
+		Expect(errstack.Frames(withFrames)).To(Equal(err.StackFrames()))
+		external, parseErr := errstack.ParseText(errstack.EncodeText(errors.New(ROOT_ERROR), false))
+		Expect(parseErr).To(BeNil())
+		Expect(external).To(MatchError(ROOT_ERROR))
+		_, parseErr = errstack.ParseText([]byte("\"load order\"\n\t\t\"no rows\"\n"))
+		Expect(parseErr).NotTo(BeNil())
+		Expect(decoded.UnmarshalText([]byte("load order"))).NotTo(Succeed())
+	})
+	It("errstack.Error should round-trip through gob, keeping the types of its fields", func() {
+		type request struct{ Path string }
+		err := errstack.NewCode("ORDER_MISSING", "load order", errstack.Join(errors.New("no rows"), errstack.New("timeout").TimedOut())).
+			With("order_id", 42).
+			With("request", request{Path: "/orders/42"}).
+			WithSeverity(errstack.SeverityWarn)
+		buf := &bytes.Buffer{}
+		var sent error = err
+		Expect(gob.NewEncoder(buf).Encode(&sent)).To(Succeed())
+		var received error
+		Expect(gob.NewDecoder(buf).Decode(&received)).To(Succeed())
+		decoded, ok := received.(errstack.Error)
+		Expect(ok).To(BeTrue())
+		Expect(decoded.PrintableError()).To(Equal(err.PrintableError()))
+		Expect(decoded.Fields()).To(Equal([]errstack.KeyValue{
+			{Key: "order_id", Value: 42},
+			{Key: "request", Value: "{/orders/42}"},
+		}))
+		Expect(errstack.Code(decoded)).To(Equal("ORDER_MISSING"))
+		Expect(errstack.Severity(decoded)).To(Equal(errstack.SeverityWarn))
+		Expect(errstack.IsTimeout(decoded)).To(BeTrue())
+		Expect(decoded.StackFrames()).To(Equal(err.StackFrames()))
+	})
+	It("errstack should interoperate with the stack traces of github.com/pkg/errors", func() {
+		if !errstack.CaptureStacks {
+			Skip("the errors capture no call stack")
+		}
+		origin := func() error { return pkgerrors.New(ROOT_ERROR) }
+		err := errstack.New("load", pkgerrors.Wrap(origin(), "read"))
+		frames := errstack.Frames(err)
+		Expect(frames).NotTo(BeEmpty())
+		Expect(frames[0].Function).To(ContainSubstring("func"))
+		Expect(fmt.Sprintf("%+v", err)).To(ContainSubstring(frames[0].Function))
+		Expect(err.StackFrames()[0].Function).NotTo(Equal(frames[0].Function))
+		var tracer interface{ StackTrace() []uintptr } = err
+		Expect(tracer.StackTrace()).To(HaveLen(len(err.StackFrames())))
+	})
+	It("Throw() and Catch() should not allocate", func() {
+		Expect(testing.AllocsPerRun(100, func() { throwCatch(true) })).To(BeZero())
+		Expect(testing.AllocsPerRun(100, func() { throwCatch_(true) })).To(BeZero())
+		s, err := throwCatch(true)
+		Expect(s).To(BeEmpty())
+		Expect(err).To(Equal(errBenchmark))
+	})
+	It("WithHandler() should return the first error passed to Check()", func() {
+		thrown, caught := []error{}, []error{}
+		defer OnThrow(func(ev Event) { thrown = append(thrown, ev.Err) })()
+		defer OnCatch(func(ev Event) { caught = append(caught, ev.Err) })()
+		defer Use(func(err error) error { return errstack.New("wrapped", err) })()
+		n, err := WithHandler(func(h *Handler) int {
+			n := 0
+			for _, fail := range []bool{false, false, true, true} {
+				s, ok := Check(returnPlain(fail))(h)
+				if !ok {
+					Expect(h.Check(nil)).To(BeFalse())
+					return n
+				}
+				Expect(s).To(Equal(SAMPLE_STRING))
+				n++
+			}
+			return n
+		})
+		Expect(n).To(Equal(2))
+		Expect(err).To(MatchError(errBenchmark))
+		Expect(err.(errstack.Error).Msg()).To(Equal("wrapped"))
+		Expect(thrown).To(Equal([]error{errBenchmark}))
+		Expect(caught).To(Equal([]error{err}))
+		Expect(WithHandler_(func(h *Handler) { h.Check(nil) })).To(Succeed())
+		Expect(WithHandler_(func(h *Handler) { Throw_(errBenchmark) })).To(MatchError(errBenchmark))
+	})
+	It("errstack.Error should cache its rendered forms until it or the settings change", func() {
+		err := errstack.New("load cached order", errstack.New(ROOT_ERROR))
+		Expect(err.Error()).To(Equal(ROOT_ERROR + " -> load cached order"))
+		Expect(err.PrintableError()).To(Equal(err.PrintableError()))
+		Expect(err.PrintableError()).NotTo(ContainSubstring("req-42"))
+		Expect(err.WithID("req-42").PrintableError()).To(ContainSubstring("req-42"))
+		Expect(err.WithCause(errors.New("timeout")).Error()).To(ContainSubstring("timeout"))
+		Expect(err.Error()).NotTo(ContainSubstring("timeout"))
+		defer func(format errstack.TraceFormat) { errstack.DefaultTraceFormat = format }(errstack.DefaultTraceFormat)
+		errstack.DefaultTraceFormat.CausedBy = "because of"
+		Expect(err.PrintableError()).To(ContainSubstring("because of"))
+		unregister := errstack.RedactPattern(regexp.MustCompile(`cached order`))
+		Expect(err.Error()).To(Equal(ROOT_ERROR + " -> load " + errstack.Redacted))
+		unregister()
+		Expect(err.Error()).To(Equal(ROOT_ERROR + " -> load cached order"))
+	})
+	It("samplers should limit stack capture and reports", func() {
+		sampled := func(s errstack.Sampler, keys ...string) []bool {
+			result := []bool{}
+			for _, key := range keys {
+				result = append(result, s(key))
+			}
+			return result
+		}
+		Expect(sampled(errstack.EveryNth(2), "a", "a", "a")).To(Equal([]bool{true, false, true}))
+		Expect(sampled(errstack.PerSecond(2), "a", "a", "a")).To(Equal([]bool{true, true, false}))
+		perKey := errstack.PerKey(func() errstack.Sampler { return errstack.EveryNth(2) })
+		Expect(sampled(perKey, "a", "b", "a", "b", "a")).To(Equal([]bool{true, true, false, false, true}))
+		dedup := errstack.Dedup(10000)
+		Expect(dedup("hot")).To(BeTrue())
+		for i := 0; i < 5000; i++ {
+			dedup(fmt.Sprint("cold ", i))
+			Expect(dedup("hot")).To(BeFalse())
+		}
+		Expect(dedup("cold 0")).To(BeTrue())
+
+		errstack.SampleStacks(errstack.EveryNth(2))
+		first, second := errstack.New(ROOT_ERROR), errstack.New(ROOT_ERROR)
+		errstack.SampleStacks(nil)
+		if errstack.CaptureStacks {
+			Expect(first.StackFrames()).NotTo(BeEmpty())
+		}
+		Expect(second.StackFrames()).To(BeEmpty())
+
+		reported := []error{}
+		defer OnReport(func(ev Event) { reported = append(reported, ev.Err) })()
+		SampleReports(errstack.PerKey(func() errstack.Sampler { return errstack.EveryNth(100) }))
+		defer SampleReports(nil)
+		for i := 0; i < 3; i++ {
+			Report(errstack.New(fmt.Sprintf("order %d not found", i)))
+			Report(errstack.New("no connection"))
+		}
+		Expect(reported).To(HaveLen(2))
+	})
+	It("Recent() should return the last failures caught, with their counts", func() {
+		Expect(Recent()).To(BeNil())
+		stop := KeepRecent(2)
+		fail := func(msg string) error {
+			return Try(func() { Throw_(errstack.New(msg)) }).Err()
+		}
+		fail("query failed")
+		fail("order 1 not found")
+		last := fail("order 2 not found")
+		fail("no connection")
+		recent := Recent()
+		Expect(recent).To(HaveLen(2))
+		Expect(recent[0].Err).To(MatchError("no connection"))
+		Expect(recent[0].Count).To(Equal(1))
+		Expect(recent[1].Err).To(Equal(last))
+		Expect(recent[1].Count).To(Equal(2))
+		Expect(recent[1].Fingerprint).To(Equal(errstack.Fingerprint(last)))
+		Expect(recent[1].Last).To(BeTemporally(">", recent[1].First))
+		stop()
+		Expect(Recent()).To(BeNil())
+	})
+	It("WriteCrashReport() should write the error, the goroutines, the build and the redacted environment", func() {
+		os.Setenv("ERRHANDLING_TEST_API_TOKEN", "tok_123")
+		defer os.Unsetenv("ERRHANDLING_TEST_API_TOKEN")
+		os.Setenv("ERRHANDLING_TEST_REGION", "eu-west-1")
+		defer os.Unsetenv("ERRHANDLING_TEST_REGION")
+		args := os.Args
+		os.Args = []string{"billing", "--api-token=tok_123", "--password", "hunter2", "--region", "eu-west-1"}
+		defer func() { os.Args = args }()
+		dir, err := os.MkdirTemp("", "crash")
+		Expect(err).To(BeNil())
+		defer os.RemoveAll(dir)
+		path, err := WriteCrashReport(dir, errstack.NewCode("CONFIG", "open config", errors.New(ROOT_ERROR)))
+		Expect(err).To(BeNil())
+		Expect(path).To(HavePrefix(dir))
+		data, err := os.ReadFile(path)
+		Expect(err).To(BeNil())
+		report := CrashReport{}
+		Expect(json.Unmarshal(data, &report)).To(Succeed())
+		Expect(report.PID).To(Equal(os.Getpid()))
+		Expect(report.Trace).To(ContainSubstring(ROOT_ERROR))
+		Expect(string(report.Error)).To(MatchRegexp(`"code":\s*"CONFIG"`))
+		Expect(report.Goroutines).To(ContainSubstring("goroutine"))
+		Expect(report.GoVersion).NotTo(BeEmpty())
+		Expect(report.Env).To(HaveKeyWithValue("ERRHANDLING_TEST_API_TOKEN", errstack.Redacted))
+		Expect(report.Env).To(HaveKeyWithValue("ERRHANDLING_TEST_REGION", "eu-west-1"))
+		Expect(report.Args).To(Equal([]string{"billing", "--api-token=" + errstack.Redacted, "--password", errstack.Redacted, "--region", "eu-west-1"}))
+	})
+	It("errstack.SetMetadata() should attribute the JSON documents of the errors", func() {
+		err := errstack.New("open config", errstack.New(ROOT_ERROR))
+		data, _ := json.Marshal(err)
+		Expect(string(data)).NotTo(ContainSubstring("metadata"))
+		Expect(errstack.BuildMetadata().Hostname).NotTo(BeEmpty())
+		errstack.SetMetadata(errstack.Metadata{Service: "billing", Region: "eu-west-1"})
+		defer errstack.SetMetadata(errstack.Metadata{})
+		Expect(errstack.CurrentMetadata().Service).To(Equal("billing"))
+		data, _ = json.Marshal(err)
+		doc := map[string]any{}
+		Expect(json.Unmarshal(data, &doc)).To(Succeed())
+		Expect(doc["metadata"]).To(Equal(map[string]any{"service": "billing", "region": "eu-west-1"}))
+		Expect(doc["cause"]).NotTo(HaveKey("metadata"))
+		decoded := errstack.Error{}
+		Expect(json.Unmarshal(data, &decoded)).To(Succeed())
+		Expect(decoded.Error()).To(Equal(err.Error()))
+	})
+	It("Throttle() and ThrottleErr() should deduplicate and rate limit the errors by fingerprint", func() {
+		caught := []error{}
+		defer OnCatch(Throttle(errstack.Dedup(3), func(ev Event) { caught = append(caught, ev.Err) }))()
+		logged := []error{}
+		limit := errstack.RateLimit(1)
+		for i := 0; i < 5; i++ {
+			Try(func() { Throw_(errstack.New(fmt.Sprintf("order %d not found", i))) })
+			OnErr_(errstack.New("no connection"))(ThrottleErr(limit, func(err error) { logged = append(logged, err) }))
+		}
+		Expect(caught).To(HaveLen(2))
+		Expect(caught[1]).To(MatchError("order 3 not found"))
+		Expect(logged).To(HaveLen(1))
+	})
+	It("ThrowIf() should throw when the condition holds, and ThrowUnless() should swallow the ignored errors", func() {
+		Expect(Try(func() { ThrowIf(false, errBenchmark) }).Err()).To(BeNil())
+		Expect(Try(func() { ThrowIf(true, errBenchmark) }).Err()).To(Equal(errBenchmark))
+		Expect(Try(func() { ThrowIf(true, nil) }).Err()).To(MatchError(ERROR_THROW_IF_NIL))
+		Expect(Try(func() { ThrowIf(false, nil) }).Err()).To(BeNil())
+		Expect(Try(func() { ThrowUnless(nil, io.EOF) }).Err()).To(BeNil())
+		Expect(Try(func() { ThrowUnless(errstack.New("read", io.EOF), os.ErrNotExist, io.EOF) }).Err()).To(BeNil())
+		Expect(Try(func() { ThrowUnless(errBenchmark, io.EOF) }).Err()).To(Equal(errBenchmark))
+		Expect(Try(func() { ThrowUnless(errBenchmark) }).Err()).To(Equal(errBenchmark))
+	})
+	It("Assert() and DebugAssert() should throw a stacked error with the call site when the condition is false", func() {
+		Expect(Try(func() { Assert(true, "unreachable") }).Err()).To(BeNil())
+		err := Try(func() { Assert(1 > 2, "%d is not over %d", 1, 2) }).Err()
+		Expect(err).To(MatchError("assertion failed: 1 is not over 2"))
+		Expect(errstack.Code(err)).To(Equal(CODE_ASSERTION_FAILED))
+		caller, _ := errstack.Field(err, "caller")
+		Expect(caller).To(MatchRegexp(`errhandling_test\.go:\d+$`))
+		err = Try(func() { DebugAssert(false, "the index is not sorted") }).Err()
+		Expect(err != nil).To(Equal(DebugAsserts))
+	})
+	It("errstack.Error should be comparable, through wrappers too", func() {
+		sentinel := errstack.New("not found").With("table", "orders")
+		var err error = sentinel
+		Expect(err == sentinel).To(BeTrue())
+		Expect(err == errstack.New("not found")).To(BeFalse())
+		Expect(errors.Is(wrapperError{sentinel}, sentinel)).To(BeTrue())
+		Expect(errors.Is(wrapperError{sentinel}, wrapperError{sentinel})).To(BeTrue())
+		Expect(errors.Is(fmt.Errorf("load: %w", wrapperError{sentinel}), wrapperError{errstack.New("not found")})).To(BeFalse())
+	})
+	It("errstack sentinels should compare equal once returned or thrown", func() {
+		var sentinel error = errstack.New("not found")
+		typed := errstack.New("gone")
+		err := Try(func() { Throw_(sentinel) }).Err()
+		Expect(err == sentinel).To(BeTrue())
+		err = Try(func() { Throw_(typed) }).Err()
+		Expect(err == typed).To(BeTrue())
+		Expect(err == typed.WithCode("GONE")).To(BeFalse())
+		Expect(errors.Is(typed.WithCode("GONE"), typed)).To(BeTrue())
+	})
+	It("errstack.Chain() should walk the comparable errors holding stacked errors, and stop at cycles", func() {
+		err := fmt.Errorf("call: %w", ErrCircuitOpen{LastCause: errstack.New(ROOT_ERROR).With("attempt", 3)})
+		Expect(errstack.Chain(err)).To(HaveLen(3))
+		Expect(errstack.Root(err)).To(MatchError(ROOT_ERROR))
+		Expect(errstack.Summary(err)).To(ContainSubstring(ROOT_ERROR))
+		Expect(errstack.Fingerprint(err)).NotTo(BeEmpty())
+		cyclic := &cyclicError{}
+		cyclic.next = cyclic
+		Expect(errstack.Chain(cyclic)).To(HaveLen(1))
+	})
+	It("errstack.FindCause() should search the comparable errors holding stacked errors", func() {
+		err := fmt.Errorf("call: %w", wrapperError{errstack.New(ROOT_ERROR).With("attempt", 3)})
+		cause, ok := errstack.FindCause[errstack.Error](err)
+		Expect(ok).To(BeTrue())
+		Expect(cause).To(MatchError(ROOT_ERROR))
+		cyclic := &cyclicError{}
+		cyclic.next = cyclic
+		_, ok = errstack.FindCause[errstack.Error](cyclic)
+		Expect(ok).To(BeFalse())
+	})
+	It("errstack.Error should render its outside causes every time", func() {
+		cause := &mutableError{msg: "connection reset"}
+		err := errstack.New(ROOT_ERROR, cause)
+		Expect(err.Error()).To(Equal("connection reset -> " + ROOT_ERROR))
+		cause.msg = "connection refused"
+		Expect(err.Error()).To(Equal("connection refused -> " + ROOT_ERROR))
+		Expect(err.PrintableError()).To(ContainSubstring("connection refused"))
+	})
+	It("Try() should run Finally() before re-panicking the other panics", func() {
+		ran, caught := false, false
+		Expect(func() {
+			Try(func() {
+				panic("boom")
+			}).Catch(func(err error) {
+				caught = true
+			}).Finally(func() {
+				ran = true
+			})
+		}).To(PanicWith("boom"))
+		Expect(ran).To(BeTrue())
+		Expect(caught).To(BeFalse())
+	})
+	It("ThrowIfDone() and Retry() should keep the error of the context along with its cause", func() {
+		errShutdown := errors.New("shutting down")
+		ctx, cancel := context.WithCancelCause(context.Background())
+		cancel(errShutdown)
+		err := func() (e error) {
+			defer Catch_(&e)
+			ThrowIfDone(ctx)
+			return nil
+		}()
+		Expect(errors.Is(err, context.Canceled)).To(BeTrue())
+		Expect(errors.Is(err, errShutdown)).To(BeTrue())
+		err = Retry_(ctx, RetryPolicy{MaxAttempts: 3}, func() error { return io.EOF })
+		Expect(errors.Is(err, context.Canceled)).To(BeTrue())
+		Expect(errors.Is(err, errShutdown)).To(BeTrue())
+		Expect(errors.Is(err, io.EOF)).To(BeTrue())
+	})
 })
+
+type closerFunc func() error
+
+func (f closerFunc) Close() error {
+	return f()
+}
+
+type failingWriter struct{}
+
+func (failingWriter) Write(p []byte) (int, error) {
+	return 0, errors.New("write failed")
+}
+
+var errBenchmark = errors.New(ROOT_ERROR)
+
+func returnPlain(fail bool) (string, error) {
+	if fail {
+		return "", errBenchmark
+	}
+	return SAMPLE_STRING, nil
+}
+
+func throwCatch(fail bool) (s string, err error) {
+	defer Catch(&s, &err)
+	return Throw(returnPlain(fail)), nil
+}
+
+func throwCatch_(fail bool) (err error) {
+	defer Catch_(&err)
+	_, err = returnPlain(fail)
+	Throw_(err)
+	return nil
+}
+
+func BenchmarkReturn(b *testing.B) {
+	b.ReportAllocs()
+	for i := 0; i < b.N; i++ {
+		if _, err := returnPlain(true); err == nil {
+			b.Fatal("no error returned")
+		}
+	}
+}
+
+func BenchmarkThrowCatch(b *testing.B) {
+	b.ReportAllocs()
+	for i := 0; i < b.N; i++ {
+		if _, err := throwCatch(true); err == nil {
+			b.Fatal("no error caught")
+		}
+	}
+}
+
+func BenchmarkThrowCatch_(b *testing.B) {
+	b.ReportAllocs()
+	for i := 0; i < b.N; i++ {
+		if err := throwCatch_(true); err == nil {
+			b.Fatal("no error caught")
+		}
+	}
+}
+
+func BenchmarkThrowNoError(b *testing.B) {
+	b.ReportAllocs()
+	for i := 0; i < b.N; i++ {
+		if _, err := throwCatch(false); err != nil {
+			b.Fatal(err)
+		}
+	}
+}
+
+func BenchmarkCheckWithHandler(b *testing.B) {
+	b.ReportAllocs()
+	for i := 0; i < b.N; i++ {
+		_, err := WithHandler(func(h *Handler) string {
+			s, _ := Check(returnPlain(true))(h)
+			return s
+		})
+		if err == nil {
+			b.Fatal("no error handled")
+		}
+	}
+}
+
+func BenchmarkPrintableError(b *testing.B) {
+	err := errstack.New(ROOT_ERROR)
+	for i := 0; i < 10; i++ {
+		err = errstack.New(SAMPLE_STRING, err).With("attempt", i)
+	}
+	b.ReportAllocs()
+	for i := 0; i < b.N; i++ {
+		_ = err.Error()
+		_ = err.PrintableError()
+	}
+}
+
+// wrapperError is a comparable outside error wrapping another one
+type wrapperError struct {
+	err error
+}
+
+func (w wrapperError) Error() string {
+	return "wrapped: " + w.err.Error()
+}
+
+func (w wrapperError) Unwrap() error {
+	return w.err
+}
+
+// cyclicError is an outside error unwrapping to itself
+type cyclicError struct {
+	next error
+}
+
+func (c *cyclicError) Error() string {
+	return "cyclic"
+}
+
+func (c *cyclicError) Unwrap() error {
+	return c.next
+}
+
+// mutableError is an outside error whose message changes
+type mutableError struct {
+	msg string
+}
+
+func (e *mutableError) Error() string {
+	return e.msg
+}
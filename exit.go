@@ -0,0 +1,149 @@
+package errhandling
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"sync"
+
+	errstack "github.com/the-zucc/errhandling/err-stack"
+)
+
+var (
+	exitMu    sync.RWMutex
+	exitCodes = map[string]int{}
+	// exitRules holds the sentinel and type rules, in registration order
+	exitRules []exitRule
+)
+
+type exitRule struct {
+	match    func(err error) bool
+	exitCode int
+}
+
+/*
+Verbose makes Exit() print the whole trace of the errors, with their
+call stack. It is set if the ERRHANDLING_VERBOSE environment variable
+is set to a non-empty value, and CLIs usually set it from a --verbose
+flag (errcli reads its VerboseFlag as well).
+*/
+var Verbose = os.Getenv("ERRHANDLING_VERBOSE") != ""
+
+/*
+MapExitCode() maps the errors with the code to a process exit code, for
+ExitCode().
+
+Example:
+
+	MapExitCode("CONFIG_INVALID", 78) // EX_CONFIG
+*/
+func MapExitCode(code string, exitCode int) {
+	exitMu.Lock()
+	defer exitMu.Unlock()
+	exitCodes[code] = exitCode
+}
+
+/*
+MapExitError() maps the errors matching the sentinel error (as per
+errors.Is()) to a process exit code, for ExitCode().
+
+Example:
+
+	MapExitError(context.Canceled, 130)
+*/
+func MapExitError(target error, exitCode int) {
+	exitMu.Lock()
+	defer exitMu.Unlock()
+	exitRules = append(exitRules, exitRule{
+		match:    func(err error) bool { return errors.Is(err, target) },
+		exitCode: exitCode,
+	})
+}
+
+/*
+MapExitType() maps the errors whose chain holds an error of type T (as
+per errors.As()) to a process exit code, for ExitCode().
+
+Example:
+
+	MapExitType[*fs.PathError](66) // EX_NOINPUT
+*/
+func MapExitType[T error](exitCode int) {
+	exitMu.Lock()
+	defer exitMu.Unlock()
+	exitRules = append(exitRules, exitRule{
+		match: func(err error) bool {
+			var target T
+			return errors.As(err, &target)
+		},
+		exitCode: exitCode,
+	})
+}
+
+/*
+ExitCode() returns the process exit code for the error. It is, in this
+order: 0 if the error is nil, the exit code mapped to its code, or to
+its root code, with MapExitCode(), the exit code of the first matching
+MapExitError() or MapExitType() rule, and 1 otherwise.
+*/
+func ExitCode(err error) int {
+	if err == nil {
+		return 0
+	}
+	exitMu.RLock()
+	defer exitMu.RUnlock()
+	for _, code := range []string{errstack.Code(err), errstack.RootCode(err)} {
+		if exitCode, ok := exitCodes[code]; ok && code != "" {
+			return exitCode
+		}
+	}
+	for _, rule := range exitRules {
+		if rule.match(err) {
+			return rule.exitCode
+		}
+	}
+	return 1
+}
+
+/*
+Exit() prints the error to the standard error output, and exits the
+process with the code ExitCode() maps to it. The error is printed as its
+public message, or its short chain ("outer: inner: root") if it has
+none, or its whole trace if Verbose is set. It exits with 0 without
+printing anything if the error is nil.
+
+Example:
+
+	func main() {
+		Exit(run(os.Args[1:]))
+	}
+*/
+func Exit(err error) {
+	if err != nil {
+		PrintError(os.Stderr, err, Verbose)
+	}
+	os.Exit(ExitCode(err))
+}
+
+/*
+PrintError() writes the error for humans, as Exit() does: its public
+message, or its short chain ("outer: inner: root") if it has none, or
+its whole trace with the call stack if verbose is set. "Error:" is
+printed in red if the writer is a terminal and the NO_COLOR environment
+variable is not set.
+*/
+func PrintError(w io.Writer, err error, verbose bool) {
+	prefix := "Error:"
+	if errstack.Colored(w) {
+		prefix = "\x1b[1;31mError:\x1b[0m"
+	}
+	switch msg := errstack.PublicMessage(err); {
+	case verbose:
+		fmt.Fprintf(w, "%s %+v\n", prefix, err)
+	case msg != "":
+		fmt.Fprintln(w, prefix, msg)
+	default:
+		fmt.Fprintf(w, "%s %s\n", prefix, err)
+	}
+}
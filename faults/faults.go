@@ -0,0 +1,134 @@
+/*
+Package faults provides named fault injection points, which tests arm to
+make the code under test throw specific errors, so that its Catch, retry
+and fallback paths can be exercised deterministically.
+*/
+package faults
+
+import (
+	"math/rand"
+	"sync"
+	"sync/atomic"
+
+	"github.com/the-zucc/errhandling"
+)
+
+/*
+Fault configures an armed injection point. Err is the error to throw.
+The first After calls are let through, then at most Times calls fail (no
+limit if 0), each with the given Probability (always if 0).
+*/
+type Fault struct {
+	Err         error
+	Probability float64
+	After       int
+	Times       int
+}
+
+type point struct {
+	fault  Fault
+	calls  int
+	failed int
+}
+
+var (
+	mu     sync.Mutex
+	armed  atomic.Bool
+	points = map[string]*point{}
+	calls  = map[string]int{}
+)
+
+/*
+Maybe() throws the error of the fault armed at the injection point, if
+any, so it needs to be paired with a deferred call to Catch(). It costs
+a single atomic load when no point is armed.
+
+Example:
+
+	func Charge(ctx context.Context, order Order) (e error) {
+		defer Catch_(&e)
+		faults.Maybe("payment.charge")
+		...
+	}
+
+errlint:throws
+*/
+func Maybe(name string) {
+	errhandling.Throw_(MaybeErr(name))
+}
+
+/*
+MaybeErr() behaves like Maybe(), but returns the error instead of
+throwing it.
+*/
+func MaybeErr(name string) error {
+	if !armed.Load() {
+		return nil
+	}
+	mu.Lock()
+	defer mu.Unlock()
+	calls[name]++
+	p, ok := points[name]
+	if !ok {
+		return nil
+	}
+	p.calls++
+	if p.calls <= p.fault.After {
+		return nil
+	}
+	if p.fault.Times > 0 && p.failed >= p.fault.Times {
+		return nil
+	}
+	if p.fault.Probability > 0 && rand.Float64() >= p.fault.Probability {
+		return nil
+	}
+	p.failed++
+	return p.fault.Err
+}
+
+/*
+Arm() arms the injection point with the fault, replacing the previous
+one, and returns a function disarming it.
+
+Example:
+
+	disarm := faults.Arm("payment.charge", faults.Fault{
+		Err:   errstack.New("gateway unavailable").Retryable(),
+		Times: 2,
+	})
+	defer disarm()
+*/
+func Arm(name string, fault Fault) (disarm func()) {
+	mu.Lock()
+	defer mu.Unlock()
+	p := &point{fault: fault}
+	points[name] = p
+	armed.Store(true)
+	return func() {
+		mu.Lock()
+		defer mu.Unlock()
+		if points[name] == p {
+			delete(points, name)
+		}
+		armed.Store(len(points) > 0)
+	}
+}
+
+/*
+Calls() returns how many times the injection point was reached while any
+point was armed.
+*/
+func Calls(name string) int {
+	mu.Lock()
+	defer mu.Unlock()
+	return calls[name]
+}
+
+// Reset() disarms all the injection points, and resets the call counts.
+func Reset() {
+	mu.Lock()
+	defer mu.Unlock()
+	points = map[string]*point{}
+	calls = map[string]int{}
+	armed.Store(false)
+}
@@ -0,0 +1,52 @@
+package faults_test
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+
+	. "github.com/the-zucc/errhandling"
+	errstack "github.com/the-zucc/errhandling/err-stack"
+	"github.com/the-zucc/errhandling/faults"
+)
+
+func TestFaults(t *testing.T) {
+	RegisterFailHandler(Fail)
+	RunSpecs(t, "faults tests")
+}
+
+func charge() (e error) {
+	defer Catch_(&e)
+	faults.Maybe("payment.charge")
+	return nil
+}
+
+var _ = Describe("faults tests", func() {
+	AfterEach(faults.Reset)
+
+	It("Maybe() should throw the armed fault by call count", func() {
+		Expect(charge()).To(Succeed())
+		disarm := faults.Arm("payment.charge", faults.Fault{
+			Err:   errstack.New("gateway unavailable").Retryable(),
+			After: 1,
+			Times: 2,
+		})
+		err := Retry_(context.Background(), RetryPolicy{MaxAttempts: 5, RetryIf: errstack.IsRetryable}, charge)
+		Expect(err).To(BeNil())
+		Expect(faults.Calls("payment.charge")).To(Equal(1))
+		Expect(charge()).NotTo(Succeed())
+		Expect(charge()).NotTo(Succeed())
+		Expect(charge()).To(Succeed())
+		disarm()
+		Expect(faults.MaybeErr("payment.charge")).To(BeNil())
+	})
+
+	It("Arm() should replace the fault of the point", func() {
+		faults.Arm("payment.charge", faults.Fault{Err: errors.New("first")})
+		faults.Arm("payment.charge", faults.Fault{Err: errors.New("second")})
+		Expect(charge()).To(MatchError("second"))
+	})
+})
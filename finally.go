@@ -0,0 +1,64 @@
+package errhandling
+
+/*
+CatchWithFinally() and CatchWithFinally_() behave like Catch() and
+Catch_(), but also run the provided function once the function has
+returned, whether it exited normally, via Throw()/Return(), or via
+an unrelated panic. This is useful for cleanup code (closing files,
+releasing locks) that would otherwise require stacking several defers
+in the right order.
+
+The finally function runs after the returned values have been set,
+so it may inspect or modify them through the same pointers.
+
+Example:
+
+	func ReadConfig(path string) (c Config, e error) {
+		var f *os.File
+		defer CatchWithFinally(&c, &e, func() {
+			if f != nil {
+				f.Close()
+			}
+		})
+		f = Throw(os.Open(path))
+		return parse(f), nil
+	}
+*/
+func CatchWithFinally[T any](valAddr *T, errAddr *error, finally func()) {
+	if errAddr == nil {
+		panic(ERROR_IN_CATCH)
+	}
+	if finally != nil {
+		defer finally()
+	}
+	if panicInfo := recover(); panicInfo != nil {
+		recovered(panicInfo, valAddr, errAddr)
+	}
+}
+
+/*
+CatchWithFinally() and CatchWithFinally_() behave like Catch() and
+Catch_(), but also run the provided function once the function has
+returned, whether it exited normally, via Throw_()/Return_(), or via
+an unrelated panic.
+
+Example:
+
+	func UpdateShared(mu *sync.Mutex) (e error) {
+		mu.Lock()
+		defer CatchWithFinally_(&e, mu.Unlock)
+		Throw_(doCriticalWork())
+		return nil
+	}
+*/
+func CatchWithFinally_(errAddr *error, finally func()) {
+	if errAddr == nil {
+		panic(ERROR_IN_CATCH)
+	}
+	if finally != nil {
+		defer finally()
+	}
+	if panicInfo := recover(); panicInfo != nil {
+		recovered[any](panicInfo, nil, errAddr)
+	}
+}
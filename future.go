@@ -0,0 +1,88 @@
+package errhandling
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	errstack "github.com/the-zucc/errhandling/err-stack"
+)
+
+/*
+Future holds the value-error pair of a function running asynchronously,
+started with Async().
+*/
+type Future[T any] struct {
+	done chan struct{}
+	val  T
+	err  error
+}
+
+/*
+Async() runs the provided function in a new goroutine and returns a
+Future for its outcome. The function may fail by returning an error,
+or with Throw()/Return(); any other panic is also turned into an error.
+
+Example:
+
+	func Dashboard() (d Dashboard, e error) {
+		defer Catch(&d, &e)
+		user := Async(fetchUser)
+		orders := Async(fetchOrders)
+		return Dashboard{user.Await(), orders.Await()}, nil
+	}
+*/
+func Async[T any](f func() (T, error)) *Future[T] {
+	fut := &Future[T]{done: make(chan struct{})}
+	go func() {
+		defer close(fut.done)
+		defer CatchPanic(&fut.val, &fut.err)
+		fut.val, fut.err = f()
+	}()
+	return fut
+}
+
+/*
+Result() blocks until the function returns, and returns its value-error
+pair.
+*/
+func (f *Future[T]) Result() (T, error) {
+	<-f.done
+	return f.val, f.err
+}
+
+/*
+Await() blocks until the function returns, and returns its value. If it
+failed, the error is thrown up the call stack, so Await() needs to be
+paired with a deferred call to Catch().
+
+errlint:throws
+*/
+func (f *Future[T]) Await() T {
+	return Throw(f.Result())
+}
+
+/*
+AwaitTimeout() behaves like Await(), but throws an error if the function
+has not returned within the provided duration. That error is marked as
+a timeout and caused by context.DeadlineExceeded, like the ones of
+WithTimeout().
+
+errlint:throws
+*/
+func (f *Future[T]) AwaitTimeout(d time.Duration) T {
+	timer := time.NewTimer(d)
+	defer timer.Stop()
+	select {
+	case <-f.done:
+		return Throw(f.val, f.err)
+	case <-timer.C:
+		var zero T
+		return Throw(zero, errstack.New(fmt.Sprintf("timed out after %s waiting for future", d), context.DeadlineExceeded).TimedOut())
+	}
+}
+
+// Done() returns a channel that is closed when the function returns.
+func (f *Future[T]) Done() <-chan struct{} {
+	return f.done
+}
@@ -0,0 +1,62 @@
+package errhandling
+
+/*
+Handle is returned by Go() and allows the caller to retrieve the
+outcome of the goroutine it started.
+*/
+type Handle struct {
+	done chan struct{}
+	err  error
+}
+
+/*
+Go() runs the provided function in a new goroutine. Since a deferred
+Catch() in the parent cannot recover a Throw() happening in another
+goroutine, Go() installs its own: any error passed up with Throw() or
+Return(), as well as any other panic, is made available through the
+returned Handle instead of crashing the process.
+
+Example:
+
+	func FetchAll() (e error) {
+		defer Catch_(&e)
+		h := Go(func() {
+			Throw(fetch("https://example.com"))
+		})
+		Throw_(h.Wait()) // the child's error is returned by FetchAll()
+		return nil
+	}
+*/
+func Go(f func()) *Handle {
+	h := &Handle{done: make(chan struct{})}
+	go func() {
+		defer close(h.done)
+		defer CatchPanic_(&h.err)
+		f()
+	}()
+	return h
+}
+
+// Wait() blocks until the goroutine returns, and returns its error.
+func (h *Handle) Wait() error {
+	<-h.done
+	return h.err
+}
+
+// Done() returns a channel that is closed when the goroutine returns.
+func (h *Handle) Done() <-chan struct{} {
+	return h.done
+}
+
+/*
+Err() returns the error of the goroutine if it has returned, and nil
+otherwise. It does not block.
+*/
+func (h *Handle) Err() error {
+	select {
+	case <-h.done:
+		return h.err
+	default:
+		return nil
+	}
+}
@@ -0,0 +1,101 @@
+package errhandling
+
+import (
+	"context"
+	"fmt"
+	"sync"
+
+	errstack "github.com/the-zucc/errhandling/err-stack"
+)
+
+/*
+Group runs tasks concurrently, in the style of errgroup. Tasks may
+use Throw() and Return() to fail; the first failure cancels the
+context shared by the tasks, and Wait() reports what happened.
+*/
+type Group struct {
+	ctx    context.Context
+	cancel context.CancelFunc
+	wg     sync.WaitGroup
+	mu     sync.Mutex
+	tasks  int
+	errs   []error
+}
+
+/*
+NewGroup() returns a new Group, along with the context derived from
+ctx that is passed to its tasks. The context is cancelled as soon as
+a task fails, or when Wait() returns.
+
+Example:
+
+	func FetchAll(ctx context.Context, urls []string) (e error) {
+		defer Catch_(&e)
+		g, ctx := NewGroup(ctx)
+		for _, url := range urls {
+			url := url
+			g.Go(func(ctx context.Context) {
+				Throw(fetch(ctx, url))
+			})
+		}
+		Throw_(g.Wait())
+		return nil
+	}
+*/
+func NewGroup(ctx context.Context) (*Group, context.Context) {
+	ctx, cancel := context.WithCancel(ctx)
+	return &Group{ctx: ctx, cancel: cancel}, ctx
+}
+
+/*
+Go() runs the provided task in a new goroutine. Errors passed up with
+Throw() or Return(), as well as any other panic, are recorded by the
+Group.
+*/
+func (g *Group) Go(f func(ctx context.Context)) {
+	g.mu.Lock()
+	g.tasks++
+	g.mu.Unlock()
+	g.wg.Add(1)
+	go func() {
+		defer g.wg.Done()
+		var err error
+		func() {
+			defer CatchPanic_(&err)
+			f(g.ctx)
+		}()
+		if err != nil {
+			g.mu.Lock()
+			g.errs = append(g.errs, err)
+			g.mu.Unlock()
+			g.cancel()
+		}
+	}()
+}
+
+/*
+Wait() blocks until all tasks have returned. If any of them failed, it
+returns an errstack.Error reporting how many tasks failed, with every
+failure as a cause.
+*/
+func (g *Group) Wait() error {
+	g.wg.Wait()
+	g.cancel()
+	g.mu.Lock()
+	defer g.mu.Unlock()
+	return failures(g.errs, g.tasks)
+}
+
+/*
+failures() reports how many of the tasks failed, with every failure as
+a cause. It returns nil if there were no failures.
+*/
+func failures(errs []error, tasks int) error {
+	if len(errs) == 0 {
+		return nil
+	}
+	return errstack.New(
+		fmt.Sprintf("%d of %d tasks failed", len(errs), tasks),
+		errs...,
+	)
+}
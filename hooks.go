@@ -0,0 +1,186 @@
+package errhandling
+
+import (
+	"runtime"
+	"strings"
+	"sync"
+	"sync/atomic"
+
+	errstack "github.com/the-zucc/errhandling/err-stack"
+)
+
+/*
+Event is passed to the hooks registered with OnThrow(), OnCatch() and
+OnReport(). Caller is the call site of the Throw() (or Return(), or
+panic) that passed the error up the call stack, or of the Report(),
+outside of this package. It is left empty if the program is built
+without stacks (see errstack.CaptureStacks).
+*/
+type Event struct {
+	Err    error
+	Caller runtime.Frame
+}
+
+// registered is a registered hook, identified so that it can be removed.
+type registered[F any] struct {
+	id int
+	f  F
+}
+
+var (
+	hooksMu     sync.RWMutex
+	hooksID     int
+	throwHooks  []registered[func(Event)]
+	catchHooks  []registered[func(Event)]
+	reportHooks []registered[func(Event)]
+)
+
+/*
+OnThrow() registers a function that is called with every non-nil error
+passed up the call stack with Throw(), Throw_(), Return() or Return_().
+It is the foundation for integrations such as metrics, tracing or error
+reporting, and is safe to call concurrently with throws. The returned
+function removes the hook.
+
+Example:
+
+	remove := errhandling.OnThrow(func(ev errhandling.Event) {
+		log.Printf("%s:%d threw %v", ev.Caller.File, ev.Caller.Line, ev.Err)
+	})
+	defer remove()
+*/
+func OnThrow(f func(Event)) (remove func()) {
+	return register(&throwHooks, f)
+}
+
+/*
+OnCatch() registers a function that is called with every error recovered
+by the Catch functions, including panics converted by CatchPanic(). The
+returned function removes the hook.
+*/
+func OnCatch(f func(Event)) (remove func()) {
+	return register(&catchHooks, f)
+}
+
+/*
+OnReport() registers a reporter, a function that is called with every
+error passed to Report(), typically to send it to an error tracker. The
+returned function removes the reporter.
+
+Example:
+
+	remove := errhandling.OnReport(func(ev errhandling.Event) {
+		tracker.Send(ev.Err)
+	})
+	defer remove()
+*/
+func OnReport(f func(Event)) (remove func()) {
+	return register(&reportHooks, f)
+}
+
+/*
+Report() passes the error to the reporters registered with OnReport(),
+for the errors that are handled without being returned or thrown, like
+the failures of the shutdown hooks. It does nothing if the error is nil,
+or if it is not sampled (see SampleReports()).
+*/
+func Report(err error) {
+	if err == nil {
+		return
+	}
+	if s := reportSampler.Load(); s != nil && !(*s)(errstack.Fingerprint(err)) {
+		return
+	}
+	fire(&reportHooks, err)
+}
+
+// the sampler of the reports, nil to report every error
+var reportSampler atomic.Pointer[errstack.Sampler]
+
+/*
+SampleReports() sets the sampler deciding whether the errors passed to
+Report() are sent to the reporters, the key being the fingerprint of the
+error (see errstack.Fingerprint()), so that storms of errors do not
+flood the error trackers. A nil sampler reports every error, which is
+the default.
+
+Example:
+
+	// the first occurrence of each failure, then at most one per minute
+	errhandling.SampleReports(errstack.RateLimit(1.0 / 60))
+*/
+func SampleReports(s errstack.Sampler) {
+	if s == nil {
+		reportSampler.Store(nil)
+		return
+	}
+	reportSampler.Store(&s)
+}
+
+// register() adds the hook to the list, and returns its removal function.
+func register[F any](hooks *[]registered[F], f F) func() {
+	hooksMu.Lock()
+	defer hooksMu.Unlock()
+	hooksID++
+	id := hooksID
+	*hooks = append(*hooks, registered[F]{id: id, f: f})
+	return func() {
+		hooksMu.Lock()
+		defer hooksMu.Unlock()
+		kept := make([]registered[F], 0, len(*hooks))
+		for _, h := range *hooks {
+			if h.id != id {
+				kept = append(kept, h)
+			}
+		}
+		*hooks = kept
+	}
+}
+
+// fireThrow() calls the registered throw hooks with the error.
+func fireThrow(err error) {
+	fire(&throwHooks, err)
+}
+
+// fireCatch() calls the registered catch hooks with the error.
+func fireCatch(err error) {
+	fire(&catchHooks, err)
+}
+
+func fire(list *[]registered[func(Event)], err error) {
+	if err == nil {
+		return
+	}
+	hooksMu.RLock()
+	hooks := *list
+	hooksMu.RUnlock()
+	if len(hooks) == 0 {
+		return
+	}
+	ev := Event{Err: err, Caller: caller()}
+	for _, h := range hooks {
+		h.f(ev)
+	}
+}
+
+/*
+caller() returns the first frame of the stack that belongs neither to
+this package nor to the runtime.
+*/
+func caller() runtime.Frame {
+	if !errstack.CaptureStacks {
+		return runtime.Frame{}
+	}
+	pcs := make([]uintptr, 32)
+	frames := runtime.CallersFrames(pcs[:runtime.Callers(3, pcs)])
+	for {
+		frame, more := frames.Next()
+		if !strings.HasPrefix(frame.Function, "github.com/the-zucc/errhandling.") &&
+			!strings.HasPrefix(frame.Function, "runtime.") {
+			return frame
+		}
+		if !more {
+			return frame
+		}
+	}
+}
@@ -0,0 +1,60 @@
+package errhandling
+
+import (
+	"fmt"
+	"os"
+
+	errstack "github.com/the-zucc/errhandling/err-stack"
+)
+
+/*
+HandleMain() is meant to be deferred at the top of main(). It recovers
+the errors thrown up to main(), Must() failures and any other panic,
+prints their whole trace to the standard error output (instead of a raw
+panic trace), writes a crash report if CrashReportDir is set, runs the
+Shutdown() hooks, and exits the process with the code ExitCode() maps to
+the error. If main() returns normally, only the hooks are run, and the
+process exits with 1 if any of them fails.
+
+Example:
+
+	func main() {
+		defer HandleMain()
+		cfg := Must(LoadConfig("app.yaml"))
+		Must_(Serve(cfg))
+	}
+*/
+func HandleMain() {
+	var err error
+	if panicInfo := recover(); panicInfo != nil {
+		err = mainError(panicInfo)
+		fmt.Fprintf(os.Stderr, "%+v\n", err)
+		if CrashReportDir != "" {
+			if path, reportErr := WriteCrashReport(CrashReportDir, err); reportErr != nil {
+				fmt.Fprintf(os.Stderr, "%+v\n", reportErr)
+			} else {
+				fmt.Fprintln(os.Stderr, "crash report written to", path)
+			}
+		}
+	}
+	if shutdownErr := Shutdown(); shutdownErr != nil {
+		fmt.Fprintf(os.Stderr, "%+v\n", errstack.New("shutdown failed", shutdownErr))
+		if err == nil {
+			err = shutdownErr
+		}
+	}
+	if err != nil {
+		os.Exit(ExitCode(err))
+	}
+}
+
+// mainError() converts a panic recovered by HandleMain() to an error.
+func mainError(panicInfo any) error {
+	switch p := panicInfo.(type) {
+	case thrown:
+		return p.thrownErr()
+	case errstack.Error:
+		return p
+	}
+	return panicError(panicInfo)
+}
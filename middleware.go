@@ -0,0 +1,87 @@
+package errhandling
+
+/*
+Middleware transforms the errors recovered by the Catch functions, e.g.
+to redact secrets or to map vendor errors to domain errors. It receives
+a non-nil error, and returns the error to pass on (nil to swallow it).
+*/
+type Middleware func(err error) error
+
+var middlewares []registered[Middleware]
+
+/*
+Use() registers a middleware applied to every error recovered by the
+Catch functions, in registration order, before the per-Catch middleware
+of CatchWith() and before the OnCatch() hooks. The returned function
+removes the middleware.
+
+Example:
+
+	errhandling.Use(func(err error) error {
+		if errors.Is(err, sql.ErrNoRows) {
+			return errstack.NewCode("NOT_FOUND", "not found", err)
+		}
+		return err
+	})
+*/
+func Use(mw Middleware) (remove func()) {
+	return register(&middlewares, mw)
+}
+
+/*
+CatchWith() and CatchWith_() behave like Catch() and Catch_(), but also
+apply the provided middleware to the recovered error, after the global
+middleware registered with Use().
+
+Example:
+
+	func Login(user, password string) (s Session, e error) {
+		defer CatchWith(&s, &e, redactPassword(password))
+		return Throw(auth.Login(user, password)), nil
+	}
+*/
+func CatchWith[T any](valAddr *T, errAddr *error, mws ...Middleware) {
+	if errAddr == nil {
+		panic(ERROR_IN_CATCH)
+	}
+	if panicInfo := recover(); panicInfo != nil {
+		recovered(panicInfo, valAddr, errAddr, mws...)
+	}
+}
+
+/*
+CatchWith() and CatchWith_() behave like Catch() and Catch_(), but also
+apply the provided middleware to the recovered error, after the global
+middleware registered with Use().
+*/
+func CatchWith_(errAddr *error, mws ...Middleware) {
+	if errAddr == nil {
+		panic(ERROR_IN_CATCH)
+	}
+	if panicInfo := recover(); panicInfo != nil {
+		recovered[any](panicInfo, nil, errAddr, mws...)
+	}
+}
+
+/*
+transform() applies the global middleware, then the provided one, to
+the error, stopping if one of them swallows it.
+*/
+func transform(err error, local []Middleware) error {
+	hooksMu.RLock()
+	global := middlewares
+	hooksMu.RUnlock()
+	for _, mw := range global {
+		if err == nil {
+			return nil
+		}
+		err = mw.f(err)
+	}
+	for _, mw := range local {
+		if err == nil {
+			return nil
+		}
+		err = mw(err)
+	}
+	return err
+}
@@ -0,0 +1,9 @@
+//go:build errhandling_nodebug
+
+package errhandling
+
+/*
+DebugAsserts reports whether DebugAssert() checks its condition. It is
+false, as the program is built with the errhandling_nodebug build tag.
+*/
+const DebugAsserts = false
@@ -0,0 +1,27 @@
+//go:build errhandling_nostack
+
+package errhandling_test
+
+import (
+	"errors"
+	"fmt"
+
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+
+	errstack "github.com/the-zucc/errhandling/err-stack"
+)
+
+var _ = Describe("errhandling_nostack tests", func() {
+	It("errstack.Error should capture no call stack, and still render", func() {
+		err := errstack.New("open config", errstack.New("read file", errors.New("ENOENT")))
+		Expect(errstack.CaptureStacks).To(BeFalse())
+		Expect(err.StackFrames()).To(BeEmpty())
+		Expect(errstack.Frames(err)).To(BeEmpty())
+		Expect(err.Error()).To(Equal("ENOENT -> read file -> open config"))
+		Expect(err.PrintableError()).To(ContainSubstring("caused by: ENOENT"))
+		Expect(fmt.Sprintf("%+v", err)).To(HavePrefix(err.PrintableError()))
+		Expect(errstack.RenderMarkdown(err)).To(ContainSubstring("Root cause: `ENOENT`"))
+		Expect(errstack.RenderHTML(err)).To(ContainSubstring("<p>Root cause: <code>ENOENT</code></p>"))
+	})
+})
@@ -0,0 +1,110 @@
+package errhandling
+
+import (
+	"fmt"
+	"runtime/debug"
+
+	errstack "github.com/the-zucc/errhandling/err-stack"
+)
+
+/*
+PanicError is the cause of the errors returned by CatchPanic() and
+CatchPanic_() when a panic that was not raised by this library is
+recovered. It carries the panic value and the stack of the goroutine
+at the time of the panic. The cause is a *PanicError, so that the errors
+stay comparable whatever the panic value, and is found with errors.As():
+
+	var pe *PanicError
+	if errors.As(err, &pe) {
+		log.Printf("plugin panicked: %v\n%s", pe.Value, pe.Stack)
+	}
+*/
+type PanicError struct {
+	Value any    // the value passed to panic()
+	Stack []byte // the goroutine stack, as formatted by debug.Stack()
+}
+
+func (p PanicError) Error() string {
+	return fmt.Sprintf("panic: %v", p.Value)
+}
+
+/*
+Unwrap() returns the panic value if it is an error, so that errors.Is()
+and errors.As() can see through the panic.
+*/
+func (p PanicError) Unwrap() error {
+	if err, ok := p.Value.(error); ok {
+		return err
+	}
+	return nil
+}
+
+/*
+CatchPanic() and CatchPanic_() behave like Catch() and Catch_(), except
+that any other panic (index out of range, nil dereference, ...) is
+converted into an errstack.Error caused by a *PanicError instead of
+being re-panicked. This is useful when wrapping plugin or user-supplied code,
+where any panic must become an error return.
+
+Example:
+
+	func RunPlugin(p Plugin) (s string, e error) {
+		defer CatchPanic(&s, &e)
+		return p.Run(), nil // a panic in p.Run() is returned as e
+	}
+*/
+func CatchPanic[T any](valAddr *T, errAddr *error) {
+	if errAddr == nil {
+		panic(ERROR_IN_CATCH)
+	}
+	if panicInfo := recover(); panicInfo != nil {
+		if _, ok := panicInfo.(thrown); !ok {
+			*errAddr = transform(panicError(panicInfo), nil)
+			fireCatch(*errAddr)
+			return
+		}
+		recovered(panicInfo, valAddr, errAddr)
+	}
+}
+
+/*
+CatchPanic() and CatchPanic_() behave like Catch() and Catch_(), except
+that any other panic is converted into an errstack.Error caused by a
+*PanicError instead of being re-panicked.
+
+Example:
+
+	func RunHook(hook func()) (e error) {
+		defer CatchPanic_(&e)
+		hook()
+		return nil
+	}
+*/
+func CatchPanic_(errAddr *error) {
+	if errAddr == nil {
+		panic(ERROR_IN_CATCH)
+	}
+	if panicInfo := recover(); panicInfo != nil {
+		if _, ok := panicInfo.(thrown); !ok {
+			*errAddr = transform(panicError(panicInfo), nil)
+			fireCatch(*errAddr)
+			return
+		}
+		recovered[any](panicInfo, nil, errAddr)
+	}
+}
+
+/*
+panicError() decorates a recovered panic value with the current stack,
+which still holds the frames of the panicking function, the deferred
+calls running on top of them.
+*/
+func panicError(panicInfo any) error {
+	return errstack.New(
+		"recovered from panic",
+		&PanicError{
+			Value: panicInfo,
+			Stack: debug.Stack(),
+		},
+	)
+}
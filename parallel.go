@@ -0,0 +1,113 @@
+package errhandling
+
+import (
+	errstack "github.com/the-zucc/errhandling/err-stack"
+)
+
+/*
+ERROR_NO_TASKS is returned by Any() and Race() when they are called
+without any task.
+*/
+var ERROR_NO_TASKS = errstack.New("Any() and Race() must be called with at least one task")
+
+// result holds the outcome of a task run by the parallel combinators.
+type result[T any] struct {
+	index int
+	val   T
+	err   error
+}
+
+// runTask() runs the task, intercepting its errors, and sends the result.
+func runTask[T any](index int, f func() T, results chan<- result[T]) {
+	r := result[T]{index: index}
+	func() {
+		defer CatchPanic(&r.val, &r.err)
+		r.val = f()
+	}()
+	results <- r
+}
+
+// start() runs all the tasks concurrently.
+func start[T any](fs []func() T) <-chan result[T] {
+	results := make(chan result[T], len(fs))
+	for i, f := range fs {
+		go runTask(i, f, results)
+	}
+	return results
+}
+
+/*
+All() runs the provided tasks concurrently and waits for all of them.
+The tasks may fail with Throw() or Return(). It returns the values of
+the tasks in order, and, if any of them failed, an error reporting how
+many tasks failed, with every failure as a cause.
+
+Example:
+
+	func LoadAll() (pages []string, e error) {
+		defer Catch(&pages, &e)
+		pages = Throw(All(
+			func() string { return Throw(fetch("a")) },
+			func() string { return Throw(fetch("b")) },
+		))
+		return pages, nil
+	}
+*/
+func All[T any](fs ...func() T) ([]T, error) {
+	vals := make([]T, len(fs))
+	errs := []error{}
+	results := start(fs)
+	for range fs {
+		r := <-results
+		vals[r.index] = r.val
+		if r.err != nil {
+			errs = append(errs, r.err)
+		}
+	}
+	return vals, failures(errs, len(fs))
+}
+
+/*
+Any() runs the provided tasks concurrently and returns the value of the
+first one that succeeds. If all of them fail, it returns an error
+reporting the failures, with every one of them as a cause.
+
+Example:
+
+	func Lookup(key string) (v string, e error) {
+		defer Catch(&v, &e)
+		return Throw(Any(
+			func() string { return Throw(primary.Get(key)) },
+			func() string { return Throw(replica.Get(key)) },
+		)), nil
+	}
+*/
+func Any[T any](fs ...func() T) (T, error) {
+	var zero T
+	if len(fs) == 0 {
+		return zero, ERROR_NO_TASKS
+	}
+	errs := []error{}
+	results := start(fs)
+	for range fs {
+		r := <-results
+		if r.err == nil {
+			return r.val, nil
+		}
+		errs = append(errs, r.err)
+	}
+	return zero, failures(errs, len(fs))
+}
+
+/*
+Race() runs the provided tasks concurrently and returns the outcome of
+the first one to complete, whether it succeeded or failed.
+*/
+func Race[T any](fs ...func() T) (T, error) {
+	if len(fs) == 0 {
+		var zero T
+		return zero, ERROR_NO_TASKS
+	}
+	r := <-start(fs)
+	return r.val, r.err
+}
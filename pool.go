@@ -0,0 +1,166 @@
+package errhandling
+
+import (
+	"sync"
+
+	errstack "github.com/the-zucc/errhandling/err-stack"
+)
+
+/*
+PoolPolicy determines what a Pool does once one of its tasks has
+failed.
+*/
+type PoolPolicy int
+
+const (
+	// Drain keeps running every submitted task, collecting all failures.
+	Drain PoolPolicy = iota
+	// StopOnFirstError skips the remaining tasks after the first failure.
+	StopOnFirstError
+)
+
+var ERROR_POOL_CLOSED = errstack.New("Submit() must not be called after Wait()")
+var ERROR_POOL_STOPPED = errstack.New("the pool was stopped by a failed task")
+
+/*
+Pool is a bounded pool of workers running tasks that may fail with
+Throw() or Return(). The failures are collected by the pool, and
+reported by Wait().
+*/
+type Pool struct {
+	policy     PoolPolicy
+	tasks      chan func()
+	done       chan struct{} // closed by Wait()
+	wg         sync.WaitGroup
+	submitting sync.WaitGroup // the calls to Submit() handing a task over
+	closeMu    sync.Mutex
+	closed     bool
+	mu         sync.Mutex
+	total      int
+	errs       []error
+}
+
+/*
+NewPool() starts a pool of n workers, which handles failures according
+to the provided policy.
+
+Example:
+
+	func ResizeAll(paths []string) (e error) {
+		defer Catch_(&e)
+		pool := NewPool(runtime.NumCPU(), Drain)
+		for _, path := range paths {
+			path := path
+			Throw_(pool.Submit(func() {
+				Throw_(resize(path))
+			}))
+		}
+		Throw_(pool.Wait())
+		return nil
+	}
+*/
+func NewPool(n int, policy PoolPolicy) *Pool {
+	if n < 1 {
+		n = 1
+	}
+	p := &Pool{
+		policy: policy,
+		tasks:  make(chan func()),
+		done:   make(chan struct{}),
+	}
+	p.wg.Add(n)
+	for i := 0; i < n; i++ {
+		go p.work()
+	}
+	return p
+}
+
+// work() runs the submitted tasks until the pool is closed.
+func (p *Pool) work() {
+	defer p.wg.Done()
+	for {
+		var task func()
+		select {
+		case task = <-p.tasks:
+		case <-p.done:
+			return
+		}
+		if p.stopped() {
+			continue
+		}
+		var err error
+		func() {
+			defer CatchPanic_(&err)
+			task()
+		}()
+		if err != nil {
+			p.mu.Lock()
+			p.errs = append(p.errs, err)
+			p.mu.Unlock()
+		}
+	}
+}
+
+// stopped() reports whether the remaining tasks should be skipped.
+func (p *Pool) stopped() bool {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	return p.policy == StopOnFirstError && len(p.errs) > 0
+}
+
+/*
+Submit() hands the task to the next available worker, blocking until
+one is available. It returns an error if the pool was closed, even
+while it was blocked, or if it was stopped by a failed task.
+*/
+func (p *Pool) Submit(task func()) error {
+	p.closeMu.Lock()
+	if p.closed {
+		p.closeMu.Unlock()
+		return ERROR_POOL_CLOSED
+	}
+	p.submitting.Add(1)
+	p.closeMu.Unlock()
+	defer p.submitting.Done()
+	if p.stopped() {
+		return ERROR_POOL_STOPPED
+	}
+	select {
+	case p.tasks <- task:
+	case <-p.done:
+		return ERROR_POOL_CLOSED
+	}
+	p.mu.Lock()
+	p.total++
+	p.mu.Unlock()
+	return nil
+}
+
+/*
+Errors() returns the failures collected so far. It is safe to call
+while tasks are running.
+*/
+func (p *Pool) Errors() []error {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	return append([]error{}, p.errs...)
+}
+
+/*
+Wait() closes the pool and blocks until the submitted tasks have
+returned. If any of them failed, it returns an error reporting how many
+tasks failed, with every failure as a cause.
+*/
+func (p *Pool) Wait() error {
+	p.closeMu.Lock()
+	if !p.closed {
+		p.closed = true
+		close(p.done)
+	}
+	p.closeMu.Unlock()
+	p.submitting.Wait()
+	p.wg.Wait()
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	return failures(p.errs, p.total)
+}
@@ -0,0 +1,101 @@
+package errhandling
+
+import (
+	"sync"
+	"sync/atomic"
+	"time"
+
+	errstack "github.com/the-zucc/errhandling/err-stack"
+)
+
+/*
+RecentError is an error kept by KeepRecent(). The occurrences of a
+failure (errors sharing a fingerprint, see errstack.Fingerprint()) are
+counted in a single entry, which holds the last of them.
+*/
+type RecentError struct {
+	Err         error
+	Fingerprint string
+	First       time.Time // when the failure first occurred since it was kept
+	Last        time.Time // when it last occurred
+	Count       int       // how many times it occurred since it was kept
+}
+
+/*
+This holds the failures kept by KeepRecent(), the most recent last. It
+is bounded, the oldest failures being dropped.
+*/
+type recentRing struct {
+	mu      sync.Mutex
+	limit   int
+	entries []RecentError
+}
+
+// the failures being kept, nil if KeepRecent() is not in use
+var recent atomic.Pointer[recentRing]
+
+/*
+KeepRecent() keeps the last n failures (at least one) among the errors
+caught by the Catch functions in memory, to be inspected with Recent(),
+e.g. by admin endpoints or tests, for the services whose logs are
+sampled away. The returned function stops keeping them, and forgets
+them.
+
+Example:
+
+	defer errhandling.KeepRecent(100)()
+*/
+func KeepRecent(n int) (stop func()) {
+	r := &recentRing{limit: max(n, 1)}
+	recent.Store(r)
+	remove := OnCatch(func(ev Event) {
+		r.keep(ev.Err)
+	})
+	return func() {
+		remove()
+		recent.CompareAndSwap(r, nil)
+	}
+}
+
+// this records an occurrence of the error among the recent ones
+func (r *recentRing) keep(err error) {
+	fingerprint := errstack.Fingerprint(err)
+	now := time.Now()
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	entry := RecentError{Fingerprint: fingerprint, First: now}
+	for i, kept := range r.entries {
+		if kept.Fingerprint == fingerprint {
+			entry = kept
+			r.entries = append(r.entries[:i], r.entries[i+1:]...)
+			break
+		}
+	}
+	entry.Err, entry.Last = err, now
+	entry.Count++
+	if len(r.entries) >= r.limit {
+		r.entries = r.entries[len(r.entries)-r.limit+1:]
+	}
+	r.entries = append(r.entries, entry)
+}
+
+/*
+Recent() returns the failures kept by KeepRecent(), the most recent
+first, or nil if none are kept.
+*/
+func Recent() []RecentError {
+	r := recent.Load()
+	if r == nil {
+		return nil
+	}
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	if len(r.entries) == 0 {
+		return nil
+	}
+	result := make([]RecentError, len(r.entries))
+	for i, entry := range r.entries {
+		result[len(r.entries)-1-i] = entry
+	}
+	return result
+}
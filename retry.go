@@ -0,0 +1,190 @@
+package errhandling
+
+import (
+	"context"
+	"fmt"
+	"math/rand"
+	"time"
+
+	errstack "github.com/the-zucc/errhandling/err-stack"
+)
+
+/*
+Backoff computes the delay to wait before a retry. attempt is the
+number of the attempt that just failed, starting at 1.
+*/
+type Backoff interface {
+	Delay(attempt int) time.Duration
+}
+
+// ConstantBackoff waits the same delay before every retry.
+type ConstantBackoff time.Duration
+
+func (b ConstantBackoff) Delay(attempt int) time.Duration {
+	return time.Duration(b)
+}
+
+/*
+ExponentialBackoff multiplies the delay by Multiplier (2 if unset) after
+every attempt, up to Max if set. Jitter is the fraction of the delay
+(between 0 and 1) that is randomized, to avoid synchronized retries.
+*/
+type ExponentialBackoff struct {
+	Initial    time.Duration
+	Max        time.Duration
+	Multiplier float64
+	Jitter     float64
+}
+
+func (b ExponentialBackoff) Delay(attempt int) time.Duration {
+	multiplier := b.Multiplier
+	if multiplier == 0 {
+		multiplier = 2
+	}
+	delay := float64(b.Initial)
+	for i := 1; i < attempt; i++ {
+		delay *= multiplier
+		if b.Max > 0 && delay > float64(b.Max) {
+			delay = float64(b.Max)
+			break
+		}
+	}
+	if b.Jitter > 0 {
+		delay -= delay * b.Jitter * rand.Float64()
+	}
+	return time.Duration(delay)
+}
+
+/*
+RetryPolicy configures Retry(). MaxAttempts is the total number of
+attempts, including the first one (1 if unset). Backoff may be nil to
+retry immediately. RetryIf, if set, decides whether a failure may be
+retried, errstack.IsRetryable being the usual choice.
+*/
+type RetryPolicy struct {
+	MaxAttempts int
+	Backoff     Backoff
+	RetryIf     func(error) bool
+}
+
+/*
+Retry() calls the provided function until it succeeds, the attempts are
+exhausted, or the context is done. The function may fail by returning
+an error, or with Throw()/Return().
+
+Every failed attempt is recorded in the returned error's chain, the
+first failure being the root cause:
+
+	gave up after 3 attempts
+	caused by: attempt 3 failed: connection refused
+	caused by: attempt 2 failed: connection refused
+	caused by: attempt 1 failed
+	caused by: connection refused
+
+If the context is done first, its error (context.Canceled or
+context.DeadlineExceeded) is the second cause of the returned error,
+followed by the cause of its cancellation, if one was given.
+
+Example:
+
+	policy := RetryPolicy{
+		MaxAttempts: 5,
+		Backoff:     ExponentialBackoff{Initial: 100 * time.Millisecond, Jitter: 0.2},
+	}
+	body, err := Retry(ctx, policy, func() ([]byte, error) {
+		return fetch(ctx, url)
+	})
+*/
+func Retry[T any](ctx context.Context, policy RetryPolicy, f func() (T, error)) (T, error) {
+	maxAttempts := policy.MaxAttempts
+	if maxAttempts < 1 {
+		maxAttempts = 1
+	}
+	var attempts error
+	for attempt := 1; ; attempt++ {
+		val, err := attemptRetry(f)
+		if err == nil {
+			return val, nil
+		}
+		attempts = recordAttempt(attempts, attempt, err)
+		if attempt >= maxAttempts || (policy.RetryIf != nil && !policy.RetryIf(err)) {
+			return val, errstack.New(fmt.Sprintf("gave up after %d attempts", attempt), attempts)
+		}
+		var delay time.Duration
+		if policy.Backoff != nil {
+			delay = policy.Backoff.Delay(attempt)
+		}
+		if sleep(ctx, delay) != nil {
+			return val, errstack.New(
+				fmt.Sprintf("gave up after %d attempts", attempt),
+				append([]error{attempts}, doneCauses(ctx)...)...,
+			)
+		}
+	}
+}
+
+/*
+Retry_() behaves like Retry(), for functions that only return an error.
+*/
+func Retry_(ctx context.Context, policy RetryPolicy, f func() error) error {
+	_, err := Retry(ctx, policy, func() (struct{}, error) {
+		return struct{}{}, f()
+	})
+	return err
+}
+
+/*
+RetryThrow() behaves like Retry(), but throws the final error up the
+call stack, so it needs to be paired with a deferred call to Catch().
+
+errlint:throws
+*/
+func RetryThrow[T any](ctx context.Context, policy RetryPolicy, f func() (T, error)) T {
+	return Throw(Retry(ctx, policy, f))
+}
+
+// attemptRetry() calls f, intercepting Throw() and Return().
+func attemptRetry[T any](f func() (T, error)) (val T, e error) {
+	defer Catch(&val, &e)
+	return f()
+}
+
+/*
+recordAttempt() adds the failure of an attempt to the chain. Only the
+first failure is kept as an actual cause, so the classification of the
+later ones is carried over to their record.
+*/
+func recordAttempt(attempts error, attempt int, err error) error {
+	if attempts == nil {
+		return errstack.New(fmt.Sprintf("attempt %d failed", attempt), err)
+	}
+	record := errstack.New(fmt.Sprintf("attempt %d failed: %s", attempt, err), attempts)
+	if errstack.IsRetryable(err) {
+		record = record.Retryable()
+	}
+	if errstack.IsTimeout(err) {
+		record = record.TimedOut()
+	}
+	return record
+}
+
+/*
+sleep() waits for the delay, or returns the error of the context if it
+is done first.
+*/
+func sleep(ctx context.Context, delay time.Duration) error {
+	if ctx.Err() != nil {
+		return ctx.Err()
+	}
+	if delay <= 0 {
+		return nil
+	}
+	timer := time.NewTimer(delay)
+	defer timer.Stop()
+	select {
+	case <-ctx.Done():
+		return ctx.Err()
+	case <-timer.C:
+		return nil
+	}
+}
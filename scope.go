@@ -0,0 +1,122 @@
+package errhandling
+
+import (
+	"context"
+	"sync"
+)
+
+/*
+Scope provides structured concurrency on top of Throw() and Catch():
+the children started with Go() are guaranteed to have returned by the
+time the scope's Catch_() (or CatchScope()) returns, and the first child
+error is what the enclosing function returns.
+*/
+type Scope struct {
+	ctx    context.Context
+	cancel context.CancelFunc
+	wg     sync.WaitGroup
+	once   sync.Once
+	err    error
+}
+
+/*
+NewScope() returns a new Scope, whose children receive a context derived
+from ctx. The context is cancelled as soon as a child fails, or when the
+enclosing function returns.
+
+Example:
+
+	func Index(ctx context.Context, docs []Doc) (e error) {
+		scope := NewScope(ctx)
+		defer scope.Catch_(&e)
+		for _, doc := range docs {
+			doc := doc
+			scope.Go(func(ctx context.Context) {
+				Throw_(index(ctx, doc))
+			})
+		}
+		return nil
+	}
+*/
+func NewScope(ctx context.Context) *Scope {
+	ctx, cancel := context.WithCancel(ctx)
+	return &Scope{ctx: ctx, cancel: cancel}
+}
+
+// Context() returns the context passed to the children of the scope.
+func (s *Scope) Context() context.Context {
+	return s.ctx
+}
+
+/*
+Go() runs the provided function in a new child goroutine. Errors passed
+up with Throw() or Return(), as well as any other panic, are recorded by
+the scope; the first one cancels the scope's context.
+*/
+func (s *Scope) Go(f func(ctx context.Context)) {
+	s.wg.Add(1)
+	go func() {
+		defer s.wg.Done()
+		var err error
+		func() {
+			defer CatchPanic_(&err)
+			f(s.ctx)
+		}()
+		if err != nil {
+			s.once.Do(func() {
+				s.err = err
+				s.cancel()
+			})
+		}
+	}()
+}
+
+/*
+Catch_() behaves like the package-level Catch_(), but first waits for
+all the children of the scope. If the enclosing function did not fail
+itself, the first child error is returned.
+*/
+func (s *Scope) Catch_(errAddr *error) {
+	if errAddr == nil {
+		panic(ERROR_IN_CATCH)
+	}
+	panicInfo := recover()
+	s.join(panicInfo != nil)
+	if panicInfo != nil {
+		recovered[any](panicInfo, nil, errAddr)
+	}
+	if *errAddr == nil {
+		*errAddr = s.err
+	}
+}
+
+/*
+CatchScope() behaves like Catch(), but first waits for all the children
+of the provided scope. If the enclosing function did not fail itself,
+the first child error is returned.
+*/
+func CatchScope[T any](s *Scope, valAddr *T, errAddr *error) {
+	if errAddr == nil {
+		panic(ERROR_IN_CATCH)
+	}
+	panicInfo := recover()
+	s.join(panicInfo != nil)
+	if panicInfo != nil {
+		recovered(panicInfo, valAddr, errAddr)
+	}
+	if *errAddr == nil {
+		*errAddr = s.err
+	}
+}
+
+/*
+join() waits for the children, cancelling them first if the enclosing
+function failed.
+*/
+func (s *Scope) join(failed bool) {
+	if failed {
+		s.cancel()
+	}
+	s.wg.Wait()
+	s.cancel()
+}
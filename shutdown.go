@@ -0,0 +1,57 @@
+package errhandling
+
+import "sync"
+
+var (
+	shutdownMu    sync.Mutex
+	shutdownHooks []func() error
+)
+
+/*
+OnShutdown() registers a hook to run on Shutdown(), e.g. to flush
+buffers or close connections when the application stops. The hooks run
+when main() returns or fails if it defers HandleMain(). To run them on
+signals, main() returns once the context of NotifySignals() is done, so
+that its deferred calls run as well.
+
+Example:
+
+	func main() {
+		defer HandleMain()
+		ctx, stop := NotifySignals(context.Background(), syscall.SIGINT, syscall.SIGTERM)
+		defer stop()
+		db := Must(sql.Open("postgres", dsn))
+		OnShutdown(db.Close)
+		Must_(serve(ctx, db))
+	}
+*/
+func OnShutdown(hook func() error) {
+	shutdownMu.Lock()
+	defer shutdownMu.Unlock()
+	shutdownHooks = append(shutdownHooks, hook)
+}
+
+/*
+Shutdown() runs the registered hooks in the reverse order of their
+registration, and unregisters them. The hooks may Throw or panic, which
+does not prevent the other ones from running. The failures are joined
+with errstack.Join() into the returned error, which is also passed to
+the reporters registered with OnReport().
+*/
+func Shutdown() (e error) {
+	shutdownMu.Lock()
+	hooks := shutdownHooks
+	shutdownHooks = nil
+	shutdownMu.Unlock()
+	for i := len(hooks) - 1; i >= 0; i-- {
+		AppendInto(&e, runHook(hooks[i]))
+	}
+	Report(e)
+	return e
+}
+
+// runHook() runs a shutdown hook, converting its panics into errors.
+func runHook(hook func() error) (e error) {
+	defer CatchPanic_(&e)
+	return hook()
+}
@@ -0,0 +1,57 @@
+package errhandling
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"os/signal"
+
+	errstack "github.com/the-zucc/errhandling/err-stack"
+)
+
+/*
+SignalError is the root cause of the cancellation of the contexts
+returned by NotifySignals(), naming the signal that was received.
+*/
+type SignalError struct {
+	Signal os.Signal
+}
+
+func (e SignalError) Error() string {
+	return fmt.Sprintf("received signal %s", e.Signal)
+}
+
+/*
+NotifySignals() behaves like signal.NotifyContext(), except that the
+cause of the cancellation of the returned context is an errstack.Error
+caused by a SignalError. ThrowIfDone() and Retry() keep that cause in
+their errors, so that operator-initiated shutdowns can be told apart
+from failures with errors.As(). Calling stop() releases the resources
+and stops relaying the signals.
+
+Example:
+
+	ctx, stop := NotifySignals(context.Background(), syscall.SIGINT, syscall.SIGTERM)
+	defer stop()
+	err := Retry_(ctx, policy, sync)
+	var sig SignalError
+	if errors.As(err, &sig) {
+		log.Printf("sync interrupted by %s", sig.Signal)
+	}
+*/
+func NotifySignals(parent context.Context, sigs ...os.Signal) (ctx context.Context, stop func()) {
+	ctx, cancel := context.WithCancelCause(parent)
+	c := make(chan os.Signal, 1)
+	signal.Notify(c, sigs...)
+	go func() {
+		select {
+		case sig := <-c:
+			cancel(errstack.New("shutdown requested", SignalError{Signal: sig}))
+		case <-ctx.Done():
+		}
+	}()
+	return ctx, func() {
+		signal.Stop(c)
+		cancel(context.Canceled)
+	}
+}
@@ -0,0 +1,168 @@
+package errhandling
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sync"
+	"time"
+
+	errstack "github.com/the-zucc/errhandling/err-stack"
+)
+
+// RestartMode determines when a supervised service is restarted.
+type RestartMode int
+
+const (
+	// Never lets the service stop, and fails the supervisor on error.
+	Never RestartMode = iota
+	// OnError restarts the service only when it fails.
+	OnError
+	// Always restarts the service whenever it returns.
+	Always
+)
+
+/*
+RestartPolicy configures how a Supervisor restarts one of its services.
+Unlimited restarts are never immediate: without a Backoff, they are
+delayed by DefaultRestartBackoff, so that a service returning right away
+does not spin.
+*/
+type RestartPolicy struct {
+	Mode       RestartMode
+	MaxRetries int           // restarts allowed before giving up, 0 for unlimited
+	Backoff    time.Duration // delay before the first restart
+	MaxBackoff time.Duration // if set, the delay doubles after each restart up to this value
+}
+
+// DefaultRestartBackoff is the delay of the unlimited restarts of the policies without a Backoff.
+var DefaultRestartBackoff = time.Second
+
+/*
+Supervisor runs long-running services that may fail with Throw() or
+Return(), and restarts them according to their RestartPolicy. When a
+service exhausts its restarts, the supervisor gives up: all services
+are cancelled, and Wait() reports what happened.
+*/
+type Supervisor struct {
+	ctx    context.Context
+	cancel context.CancelFunc
+	wg     sync.WaitGroup
+	mu     sync.Mutex
+	total  int
+	errs   []error
+}
+
+/*
+NewSupervisor() returns a new Supervisor, whose services receive a
+context derived from ctx.
+
+Example:
+
+	func Serve(ctx context.Context) error {
+		s := NewSupervisor(ctx)
+		s.Add("http", RestartPolicy{Mode: OnError, MaxRetries: 5, Backoff: time.Second}, serveHTTP)
+		s.Add("metrics", RestartPolicy{Mode: Always}, pushMetrics)
+		return s.Wait()
+	}
+*/
+func NewSupervisor(ctx context.Context) *Supervisor {
+	ctx, cancel := context.WithCancel(ctx)
+	return &Supervisor{ctx: ctx, cancel: cancel}
+}
+
+// Add() starts the provided service under the supervisor.
+func (s *Supervisor) Add(name string, policy RestartPolicy, f func(ctx context.Context)) {
+	s.mu.Lock()
+	s.total++
+	s.mu.Unlock()
+	s.wg.Add(1)
+	go func() {
+		defer s.wg.Done()
+		if err := s.supervise(name, policy, f); err != nil {
+			s.mu.Lock()
+			s.errs = append(s.errs, err)
+			s.mu.Unlock()
+			s.cancel()
+		}
+	}()
+}
+
+/*
+supervise() runs the service until it stops for good, and returns an
+error if the supervisor should give up.
+*/
+func (s *Supervisor) supervise(name string, policy RestartPolicy, f func(ctx context.Context)) error {
+	delay := policy.Backoff
+	if delay <= 0 && policy.MaxRetries <= 0 {
+		delay = DefaultRestartBackoff
+	}
+	for restarts := 0; ; restarts++ {
+		var err error
+		func() {
+			defer CatchPanic_(&err)
+			f(s.ctx)
+		}()
+		if s.ctx.Err() != nil {
+			return s.interrupted(name, err)
+		}
+		if err == nil && policy.Mode != Always {
+			return nil
+		}
+		if err != nil && policy.Mode == Never {
+			return errstack.New(fmt.Sprintf("service %q failed", name), err)
+		}
+		if policy.MaxRetries > 0 && restarts >= policy.MaxRetries {
+			msg := fmt.Sprintf("service %q gave up after %d restarts", name, restarts)
+			if err == nil {
+				return errstack.New(msg)
+			}
+			return errstack.New(msg, err)
+		}
+		if delay > 0 {
+			timer := time.NewTimer(delay)
+			select {
+			case <-s.ctx.Done():
+				timer.Stop()
+				return s.interrupted(name, err)
+			case <-timer.C:
+			}
+			if policy.MaxBackoff > 0 {
+				delay *= 2
+				if delay > policy.MaxBackoff {
+					delay = policy.MaxBackoff
+				}
+			}
+		}
+	}
+}
+
+/*
+interrupted() returns the last failure of a service stopped by the
+cancellation of the supervisor, with the error of the context, or nil
+if the service did not fail, or only failed because of the cancellation.
+*/
+func (s *Supervisor) interrupted(name string, err error) error {
+	if err == nil || errors.Is(err, s.ctx.Err()) {
+		return nil
+	}
+	return errstack.New(fmt.Sprintf("service %q failed", name), append([]error{err}, doneCauses(s.ctx)...)...)
+}
+
+// Stop() cancels all the services of the supervisor.
+func (s *Supervisor) Stop() {
+	s.cancel()
+}
+
+/*
+Wait() blocks until all services have stopped. If the supervisor gave
+up, or if services were failing when it was stopped, it returns an error
+reporting how many services failed, with every failure as a cause.
+*/
+func (s *Supervisor) Wait() error {
+	s.wg.Wait()
+	s.cancel()
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return failures(s.errs, s.total)
+}
@@ -0,0 +1,45 @@
+package errhandling
+
+import errstack "github.com/the-zucc/errhandling/err-stack"
+
+/*
+Throttle() returns a hook calling f only with the errors sampled by s,
+the key being the fingerprint of the error (see errstack.Fingerprint()),
+so that a storm of errors does not flood the systems the hook feeds.
+It suits the hooks of OnThrow(), OnCatch() and OnReport(); Report()
+itself can be sampled with SampleReports().
+
+Example:
+
+	// the first occurrence of each failure, then one out of 100
+	errhandling.OnCatch(errhandling.Throttle(errstack.Dedup(100), func(ev errhandling.Event) {
+		alert(ev.Err)
+	}))
+*/
+func Throttle(s errstack.Sampler, f func(Event)) func(Event) {
+	return func(ev Event) {
+		if s(errstack.Fingerprint(ev.Err)) {
+			f(ev)
+		}
+	}
+}
+
+/*
+ThrottleErr() returns a callback calling f only with the errors sampled
+by s, as Throttle() does, for the callbacks of OnErr() and OnErr_().
+
+Example:
+
+	var logLimit = errstack.RateLimit(1)
+
+	OnErr_(conn.Ping())(ThrottleErr(logLimit, func(err error) {
+		log.Println("ping failed:", err)
+	}))
+*/
+func ThrottleErr(s errstack.Sampler, f func(error)) func(error) {
+	return func(err error) {
+		if s(errstack.Fingerprint(err)) {
+			f(err)
+		}
+	}
+}
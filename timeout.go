@@ -0,0 +1,70 @@
+package errhandling
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"time"
+
+	errstack "github.com/the-zucc/errhandling/err-stack"
+)
+
+/*
+WithTimeout() runs the provided function with a context derived from
+ctx, that expires after the provided duration, and returns its value.
+If the function fails, or if the deadline expires before it returns,
+the error is thrown up the call stack, so WithTimeout() needs to be
+paired with a deferred call to Catch(). On expiry, the thrown error is
+marked as a timeout and caused by context.DeadlineExceeded. If ctx is
+cancelled first, the cancellation is thrown instead, as ThrowIfDone()
+does, with the cause of the cancellation.
+
+Example:
+
+	func Profile(ctx context.Context, id string) (p Profile, e error) {
+		defer Catch(&p, &e)
+		return WithTimeout(ctx, time.Second, func(ctx context.Context) (Profile, error) {
+			return fetchProfile(ctx, id)
+		}), nil
+	}
+
+errlint:throws
+*/
+func WithTimeout[T any](ctx context.Context, d time.Duration, f func(ctx context.Context) (T, error)) T {
+	ctx, cancel := context.WithTimeout(ctx, d)
+	defer cancel()
+	fut := Async(func() (T, error) {
+		return f(ctx)
+	})
+	select {
+	case <-fut.Done():
+		val, err := fut.Result()
+		if err != nil && errors.Is(err, context.DeadlineExceeded) {
+			return Throw(val, timedOut(d, err))
+		}
+		return Throw(val, err)
+	case <-ctx.Done():
+		var zero T
+		if ctx.Err() != context.DeadlineExceeded {
+			return Throw(zero, errstack.New("context cancelled", doneCauses(ctx)...))
+		}
+		return Throw(zero, timedOut(d, ctx.Err()))
+	}
+}
+
+/*
+WithTimeout_() behaves like WithTimeout(), for functions that only
+return an error.
+
+errlint:throws
+*/
+func WithTimeout_(ctx context.Context, d time.Duration, f func(ctx context.Context) error) {
+	WithTimeout(ctx, d, func(ctx context.Context) (struct{}, error) {
+		return struct{}{}, f(ctx)
+	})
+}
+
+// timedOut() decorates the error of an expired context.
+func timedOut(d time.Duration, err error) error {
+	return errstack.New(fmt.Sprintf("timed out after %s", d), err).TimedOut()
+}
@@ -0,0 +1,81 @@
+package errhandling
+
+/*
+TryBlock holds the outcome of a function run through Try(). It is
+meant to be used fluently, for call sites where the enclosing function
+cannot be rewritten with named returns and a deferred Catch() (e.g.
+inside closures passed to third-party code).
+*/
+type TryBlock struct {
+	err      error
+	panicked bool // whether the block panicked with something else than a thrown error
+	panicVal any  // the value of that panic, re-panicked by Finally() and Err()
+}
+
+/*
+Try() runs the provided function immediately, intercepting any error
+passed up the call stack with Throw()/Return(). The outcome can then
+be handled with Catch() and Finally().
+
+Any other panic is held until the end of the chain: Finally() runs its
+function, then re-panics it, as does Err(). The blocks that may panic
+must therefore end with one of them.
+
+Example:
+
+	Try(func() {
+		cfg := Throw(loadConfig(path))
+		apply(cfg)
+	}).Catch(func(err error) {
+		log.Println("could not apply config:", err)
+	}).Finally(func() {
+		wg.Done()
+	})
+*/
+func Try(f func()) *TryBlock {
+	t := &TryBlock{}
+	func() {
+		defer func() {
+			if panicInfo := recover(); panicInfo != nil {
+				t.panicked, t.panicVal = true, panicInfo
+			}
+		}()
+		func() {
+			defer Catch_(&t.err)
+			f()
+		}()
+	}()
+	return t
+}
+
+/*
+Catch() runs the provided function with the error thrown in the Try()
+block, if any. It does not run for the other panics.
+*/
+func (t *TryBlock) Catch(f func(err error)) *TryBlock {
+	if t.err != nil {
+		f(t.err)
+	}
+	return t
+}
+
+/*
+Finally() runs the provided function whether or not an error was thrown
+in the Try() block, and returns that error. If the block panicked with
+something else, the panic goes on once the function has run.
+*/
+func (t *TryBlock) Finally(f func()) error {
+	f()
+	return t.Err()
+}
+
+/*
+Err() returns the error thrown in the Try() block, if any, and
+re-panics the other panics of the block.
+*/
+func (t *TryBlock) Err() error {
+	if t.panicked {
+		panic(t.panicVal)
+	}
+	return t.err
+}